@@ -2,32 +2,107 @@ package main
 
 import (
 	"context"
+	"errors"
+	"io"
+	"io/fs"
 	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"blog-api/internal/config"
+	"blog-api/internal/events"
+	"blog-api/internal/http-server/handlers/admin"
 	"blog-api/internal/http-server/handlers/article"
+	"blog-api/internal/http-server/handlers/attachment"
+	"blog-api/internal/http-server/handlers/collection"
+	"blog-api/internal/http-server/handlers/fallback"
+	"blog-api/internal/http-server/handlers/newsletter"
+	"blog-api/internal/http-server/handlers/notification"
+	"blog-api/internal/http-server/handlers/session"
+	"blog-api/internal/http-server/handlers/sitemap"
+	"blog-api/internal/http-server/handlers/static"
+	"blog-api/internal/http-server/handlers/stats"
 	"blog-api/internal/http-server/handlers/user"
+	"blog-api/internal/http-server/handlers/webhook"
+	mw "blog-api/internal/http-server/middleware"
+	"blog-api/internal/lib/jwt"
 	"blog-api/internal/lib/logger"
+	"blog-api/internal/lib/logger/rotate"
 	"blog-api/internal/lib/logger/sl"
+	email "blog-api/internal/notification"
 	articleservice "blog-api/internal/service/article"
+	attachmentservice "blog-api/internal/service/attachment"
+	backupservice "blog-api/internal/service/backup"
+	collectionservice "blog-api/internal/service/collection"
+	newsletterservice "blog-api/internal/service/newsletter"
+	notificationservice "blog-api/internal/service/notification"
+	sessionservice "blog-api/internal/service/session"
+	sitemapservice "blog-api/internal/service/sitemap"
+	statsservice "blog-api/internal/service/stats"
 	userservice "blog-api/internal/service/user"
+	webhookservice "blog-api/internal/service/webhook"
 	"blog-api/internal/storage/sqlite"
+	"blog-api/internal/worker"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	"github.com/oschwald/maxminddb-golang"
 )
 
 func main() {
 	cfg := config.MustLoad()
 
-	log := logger.New(cfg.Env)
+	logOut := io.Writer(os.Stdout)
+	if cfg.Logging.File != "" {
+		logFile, err := rotate.New(cfg.Logging.File, cfg.Logging.MaxSizeMB, cfg.Logging.MaxBackups)
+		if err != nil {
+			panic(err)
+		}
+		defer logFile.Close()
+		logOut = logFile
+	}
+
+	log, logLevel, err := logger.New(cfg.Logging.Level, cfg.Logging.Format, logOut)
+	if err != nil {
+		panic(err)
+	}
+
+	log.Info("logger initialized", slog.String("log_level", cfg.Logging.Level), slog.String("log_format", cfg.Logging.Format))
 
 	log.Debug("initializing server...", slog.String("addr", cfg.Address))
 
+	if cfg.SecretFromFile {
+		log.Warn("reading jwt secret from config file is deprecated, use JWT_SECRET env variable instead")
+	}
+
+	// Init geo database
+	var geoDB *maxminddb.Reader
+	if cfg.GeoDBPath != "" {
+		geoDB, err = maxminddb.Open(cfg.GeoDBPath)
+		if err != nil {
+			log.Error("error opening geo database", sl.Error(err))
+			return
+		}
+		defer geoDB.Close()
+	}
+
+	// Init token keys
+	keys, err := jwt.LoadKeys(cfg.JWT.Algorithm, cfg.Secret, cfg.PreviousSecrets, cfg.JWT.PrivateKeyPath, cfg.JWT.PublicKeyPath)
+	if err != nil {
+		log.Error("error loading jwt keys", sl.Error(err))
+		return
+	}
+
+	// During a secret rotation tokens signed with the previous secrets still pass
+	if keys.Retired() {
+		log.Info("accepting tokens signed with previous jwt secrets", slog.Int("count", len(cfg.PreviousSecrets)))
+	}
+	// Tokens are verified the same way on every protected route
+	verifier := mw.Verifier(keys)
+
 	// Init storage
 	storage, err := sqlite.New(cfg.StoragePath)
 	if err != nil {
@@ -35,24 +110,118 @@ func main() {
 		return
 	}
 
+	// Live events for streaming clients
+	bus := events.New()
+
+	// Email notifications
+	var mailer email.Notifier = email.NullNotifier{}
+	if cfg.NotificationEnabled {
+		mailer = email.NewSMTP(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPFrom)
+	}
+
 	// Init service layer
-	usrService := userservice.New(log, storage, cfg.TokenTTL)
-	artService := articleservice.New(log, storage)
+	usrService := userservice.New(log, storage, cfg.TokenTTL, cfg.TokenTTLRemember, keys, cfg.SessionLimit, cfg.BcryptCost, userservice.Lockout{
+		MaxFailures: cfg.LoginMaxFailures,
+		Duration:    cfg.LoginLockout,
+	}, mailer)
+	ntfService := notificationservice.New(log, storage)
+	whkService := webhookservice.New(log, storage)
+	colService := collectionservice.New(log, storage)
+	attService := attachmentservice.New(log, storage, cfg.AttachmentDir)
+	artService := articleservice.New(log, storage, ntfService, bus, whkService, mailer, cfg.RequireArticleVersion)
+	bkpService := backupservice.New(log, storage, cfg.BackupDir)
+	smpService := sitemapservice.New(log, storage)
+	sesService := sessionservice.New(log, storage)
+	stsService := statsservice.New(log, storage)
+	nwsService := newsletterservice.New(log, storage)
 
 	// Handlers and middleware
 	r := chi.NewRouter()
 
+	// Expensive reads get longer, route_timeouts may override them
+	routeTimeouts := map[string]time.Duration{
+		"/sitemap.xml": cfg.HeavyReadTimeout,
+	}
+	for prefix, d := range cfg.RouteTimeouts {
+		routeTimeouts[prefix] = d
+	}
+
 	r.Use(middleware.RequestID)
 	r.Use(middleware.RealIP)
+	r.Use(mw.Geo(geoDB))
 	r.Use(middleware.Logger)
-	r.Use(middleware.Recoverer)
+	r.Use(mw.Recoverer(log))
+	r.Use(mw.SecurityHeaders(cfg.Env != config.EnvLocal))
+	if cfg.CompressLevel > 0 {
+		r.Use(mw.Compress(cfg.CompressLevel, cfg.CompressMinSize))
+	}
+	r.Use(middleware.StripSlashes)
+	r.Use(mw.ReadOnlyWhile(bkpService.Restoring))
+	r.Use(mw.RouteTimeout(mw.Timeouts{
+		Read:     cfg.RequestTimeout,
+		Write:    cfg.WriteTimeout,
+		Routes:   routeTimeouts,
+		Streams:  []string{"/articles/stream"},
+		BasePath: cfg.BasePath,
+	}))
+	r.Use(verifier)
+	r.Use(mw.ActiveSession(sesService.Active))
 
 	// Init handlers
-	usr := user.New(log, usrService, cfg.Secret)
-	art := article.New(log, artService, cfg.Secret)
+	usr := user.New(log, usrService, verifier)
+	art := article.New(log, artService, verifier, bus)
+	adm := admin.New(log, bkpService, logLevel, verifier)
+	ntf := notification.New(log, ntfService, verifier)
+	smp := sitemap.New(log, smpService, cfg.BaseURL, cfg.BasePath)
+	ses := session.New(log, sesService, verifier)
+	whk := webhook.New(log, whkService, verifier)
+	col := collection.New(log, colService, verifier)
+	att := attachment.New(log, attService, verifier)
+	sts := stats.New(log, stsService, verifier)
+	nws := newsletter.New(log, nwsService, verifier, cfg.NewsletterToken)
+
+	// Set before mounting so that subrouters inherit them
+	r.NotFound(fallback.NotFound)
+	r.MethodNotAllowed(fallback.MethodNotAllowed)
+
+	// The API is served under base_path, the sitemap stays at the root for crawlers
+	api := chi.NewRouter()
+	api.NotFound(fallback.NotFound)
+	api.MethodNotAllowed(fallback.MethodNotAllowed)
+	api.Use(mw.BasePath(cfg.BasePath))
+
+	api.Route("/users", usr.Register())
+	api.Route("/users/{id}/notifications", ntf.Register())
+	api.Route("/users/me/sessions", ses.Register())
+	api.Route("/users/{id}/articles", art.RegisterByAuthor())
+	api.Route("/articles", art.Register())
+	api.Route("/articles/{id}/attachments", att.RegisterByArticle())
+	api.Route("/articles/{id}/stats", sts.RegisterByArticle())
+	api.Route("/webhooks", whk.Register())
+	api.Route("/attachments", att.Register())
+	api.Route("/collections", col.Register())
+	api.Route("/admin", adm.Register())
+	api.Route("/newsletter", nws.Register())
+
+	if cfg.BasePath == "" {
+		r.Mount("/", api)
+	} else {
+		r.Mount(cfg.BasePath, api)
+	}
+	r.Get("/sitemap.xml", smp.Get)
+
+	// The frontend gets every path no other route takes
+	if cfg.Static.Dir != "" {
+		frontend := os.DirFS(cfg.Static.Dir)
+		if _, err := fs.Stat(frontend, "index.html"); err != nil {
+			log.Error("error opening frontend", sl.Error(err))
+			return
+		}
 
-	r.Route("/users", usr.Register())
-	r.Route("/articles", art.Register())
+		stc := static.New(log, frontend, cfg.Static.MaxAge, cfg.Env != config.EnvLocal)
+		r.Get("/*", stc.Serve)
+		r.Head("/*", stc.Serve)
+	}
 
 	srv := http.Server{
 		Handler:      r,
@@ -62,6 +231,48 @@ func main() {
 		IdleTimeout:  cfg.IdleTimeout,
 	}
 
+	// Streams never go idle, end them so that Shutdown doesn't wait for them
+	srv.RegisterOnShutdown(bus.Close)
+
+	// Background tasks
+	scheduler := worker.New(log)
+	scheduler.Add(worker.Task{
+		Name:     "sqlite-optimize",
+		Interval: time.Hour,
+		Run:      storage.Optimize,
+	})
+	scheduler.Add(worker.Task{
+		Name:     "article-stats",
+		Interval: 5 * time.Minute,
+		Run:      artService.AggregateStats,
+	})
+	scheduler.Add(worker.Task{
+		Name:     "recovered-panics",
+		Interval: time.Hour,
+		Run: func(ctx context.Context) error {
+			if n := mw.RecoveredPanics(); n > 0 {
+				log.Warn("handlers panicked", slog.Int64("panics", n))
+			}
+			return nil
+		},
+	})
+	if keys.Retired() {
+		// Tells when the previous secrets can be dropped
+		scheduler.Add(worker.Task{
+			Name:     "jwt-retired-keys",
+			Interval: time.Hour,
+			Run: func(ctx context.Context) error {
+				for kid, n := range keys.RetiredUse() {
+					log.Warn("requests still use tokens signed with a previous jwt secret", slog.String("kid", kid), slog.Int64("requests", n))
+				}
+				return nil
+			},
+		})
+	}
+	scheduler.Start(context.Background())
+	ntfService.Start()
+	whkService.Start()
+
 	log.Debug("server initialized")
 	log.Info("server is running...")
 
@@ -70,7 +281,15 @@ func main() {
 	signal.Notify(done, syscall.SIGTERM, syscall.SIGINT, os.Interrupt)
 
 	go func() {
-		if err := srv.ListenAndServe(); err != nil {
+		var err error
+		if cfg.TLSCertFile != "" {
+			log.Info("serving over TLS")
+			err = srv.ListenAndServeTLS(cfg.TLSCertFile, cfg.TLSKeyFile)
+		} else {
+			err = srv.ListenAndServe()
+		}
+		// Shutdown makes both return ErrServerClosed
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
 			log.Error("error starting sever", sl.Error(err))
 		}
 	}()
@@ -82,5 +301,15 @@ func main() {
 
 	srv.Shutdown(ctx)
 
+	if err := scheduler.Stop(ctx); err != nil {
+		log.Error("error stopping background tasks", sl.Error(err))
+	}
+	if err := ntfService.Stop(ctx); err != nil {
+		log.Error("error writing pending notifications", sl.Error(err))
+	}
+	if err := whkService.Stop(ctx); err != nil {
+		log.Error("error delivering pending webhooks", sl.Error(err))
+	}
+
 	log.Info("server stopped")
 }
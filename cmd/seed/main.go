@@ -0,0 +1,185 @@
+// Seed fills a database with users and articles for local development.
+//
+// Every user gets the password "password". Data goes through the service layer,
+// so hashes and validations are the same as for real requests.
+// Running it again only adds what is missing, pass --wipe to start from scratch.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"strings"
+	"time"
+
+	"blog-api/internal/domain/models"
+	"blog-api/internal/lib/logger/handlers/slogDiscard"
+	articleservice "blog-api/internal/service/article"
+	userservice "blog-api/internal/service/user"
+	"blog-api/internal/storage/sqlite"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const password = "password"
+
+var (
+	names = []string{
+		"alice", "bob", "carol", "dave", "erin", "frank", "grace", "heidi",
+		"ivan", "judy", "mallory", "nina", "oscar", "peggy", "rupert", "sybil",
+		"trent", "victor", "walter", "yana",
+	}
+
+	topics = []string{
+		"Go", "SQLite", "REST APIs", "JWT", "Concurrency", "Testing", "Docker",
+		"Linux", "Databases", "Clean Architecture", "Microservices", "Caching",
+	}
+
+	titleTemplates = []string{
+		"Getting started with %s",
+		"%s in production: lessons learned",
+		"Why I stopped worrying about %s",
+		"A practical guide to %s",
+		"%s tips and tricks",
+		"Common %s mistakes",
+	}
+
+	sentences = []string{
+		"Lorem ipsum dolor sit amet, consectetur adipiscing elit.",
+		"Sed do eiusmod tempor incididunt ut labore et dolore magna aliqua.",
+		"Ut enim ad minim veniam, quis nostrud exercitation ullamco laboris.",
+		"Duis aute irure dolor in reprehenderit in voluptate velit esse.",
+		"Excepteur sint occaecat cupidatat non proident, sunt in culpa qui officia.",
+		"Nemo enim ipsam voluptatem quia voluptas sit aspernatur aut odit aut fugit.",
+	}
+)
+
+func main() {
+	var (
+		dbPath   string
+		users    int
+		articles int
+		wipe     bool
+		seed     int64
+	)
+	flag.StringVar(&dbPath, "db", "", "path to sqlite database")
+	flag.IntVar(&users, "users", 10, "number of users")
+	flag.IntVar(&articles, "articles", 50, "number of articles")
+	flag.BoolVar(&wipe, "wipe", false, "remove the database before seeding")
+	flag.Int64Var(&seed, "seed", 1, "random seed")
+	flag.Parse()
+
+	if dbPath == "" || users < 1 || articles < 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := run(dbPath, users, articles, wipe, seed); err != nil {
+		fmt.Fprintln(os.Stderr, "seed:", err)
+		os.Exit(1)
+	}
+}
+
+func run(dbPath string, users, articles int, wipe bool, seed int64) error {
+	start := time.Now()
+	ctx := context.Background()
+
+	if wipe {
+		if err := os.Remove(dbPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+
+	storage, err := sqlite.New(dbPath)
+	if err != nil {
+		return err
+	}
+
+	log := slogDiscard.NewDiscardLogger()
+	usrService := userservice.New(log, storage, 0, 0, nil, 0, bcrypt.DefaultCost, userservice.Lockout{}, nil)
+	artService := articleservice.New(log, storage, nil, nil, nil, nil, false)
+
+	rnd := rand.New(rand.NewSource(seed))
+
+	// Users
+	var created, skipped int
+	for i := 0; i < users; i++ {
+		name := fmt.Sprintf("%s_%d", names[i%len(names)], i+1)
+
+		_, err := usrService.Register(ctx, name, "", password)
+		if errors.Is(err, userservice.ErrUserExists) {
+			skipped++
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		created++
+	}
+
+	authors, err := usrService.GetAll(ctx, users, 0)
+	if err != nil {
+		return err
+	}
+
+	// Articles, only the missing ones
+	existing, err := artService.GetAll(ctx, "", "", models.PopularityFilter{}, false, 0, -1, 0)
+	if err != nil {
+		return err
+	}
+
+	var createdArts int
+	for i := len(existing); i < articles; i++ {
+		author := authors[rnd.Intn(len(authors))]
+
+		art := models.Article{
+			Title:    fmt.Sprintf(titleTemplates[rnd.Intn(len(titleTemplates))], topics[rnd.Intn(len(topics))]),
+			Content:  content(rnd),
+			AuthorID: int(author.ID),
+		}
+		if rnd.Intn(5) == 0 {
+			art.Status = models.ArticleDraft
+		}
+
+		id, err := artService.Create(ctx, &art)
+		if err != nil {
+			return err
+		}
+
+		// Spread publish dates over the last year
+		if art.Status != models.ArticleDraft {
+			publishDate := time.Now().UTC().Add(-time.Duration(rnd.Int63n(int64(365 * 24 * time.Hour))))
+			if err := storage.UpdateArticlePublishDate(ctx, id, publishDate); err != nil {
+				return err
+			}
+		}
+		createdArts++
+	}
+
+	fmt.Printf("users:    %d created, %d already existed\n", created, skipped)
+	fmt.Printf("articles: %d created, %d already existed\n", createdArts, len(existing))
+	fmt.Printf("password: %q\n", password)
+	fmt.Printf("done in %s\n", time.Since(start).Round(time.Millisecond))
+
+	return nil
+}
+
+// content returns from one to twenty paragraphs of filler text
+func content(rnd *rand.Rand) string {
+	paragraphs := make([]string, 1+rnd.Intn(20))
+	for i := range paragraphs {
+		var b strings.Builder
+		for j := 0; j < 2+rnd.Intn(6); j++ {
+			if j > 0 {
+				b.WriteString(" ")
+			}
+			b.WriteString(sentences[rnd.Intn(len(sentences))])
+		}
+		paragraphs[i] = b.String()
+	}
+
+	return strings.Join(paragraphs, "\n\n")
+}
@@ -8,8 +8,11 @@ require (
 	github.com/go-chi/render v1.0.3
 	github.com/golang-jwt/jwt/v5 v5.2.0
 	github.com/ilyakaznacheev/cleanenv v1.5.0
+	github.com/lestrrat-go/jwx/v2 v2.0.19
 	github.com/mattn/go-sqlite3 v1.14.20
+	github.com/oschwald/maxminddb-golang v1.12.0
 	golang.org/x/crypto v0.18.0
+	golang.org/x/sync v0.6.0
 )
 
 require (
@@ -22,7 +25,6 @@ require (
 	github.com/lestrrat-go/httpcc v1.0.1 // indirect
 	github.com/lestrrat-go/httprc v1.0.4 // indirect
 	github.com/lestrrat-go/iter v1.0.2 // indirect
-	github.com/lestrrat-go/jwx/v2 v2.0.19 // indirect
 	github.com/lestrrat-go/option v1.0.1 // indirect
 	github.com/segmentio/asm v1.2.0 // indirect
 	golang.org/x/sys v0.16.0 // indirect
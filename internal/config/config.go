@@ -1,19 +1,93 @@
 package config
 
 import (
+	"compress/gzip"
 	"flag"
 	"log"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/ilyakaznacheev/cleanenv"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	secretEnv         = "JWT_SECRET"
+	previousSecretEnv = "JWT_PREVIOUS_SECRET"
+	newsletterEnv     = "NEWSLETTER_TOKEN"
+	minSecretLen      = 32
+
+	// EnvLocal is the env of a development machine, where browser hardening such as HSTS gets in the way
+	EnvLocal = "local"
 )
 
 type Config struct {
 	Env         string `yaml:"env" env-default:"dev"`
 	StoragePath string `yaml:"storage_path" env-requires:"true"`
-	Secret      string `yaml:"secret" env-required:"true"`
-	HTTPServer  `yaml:"http_server"`
+	BackupDir   string `yaml:"backup_dir" env-default:"./storage/backups"`
+	// AttachmentDir holds the content of article attachments, one file per SHA256
+	AttachmentDir string `yaml:"attachment_dir" env-default:"./storage/attachments"`
+	BaseURL       string `yaml:"base_url" env-default:"http://localhost:8080"`
+	// GeoDBPath points to a MaxMind GeoLite2-City database, when set request logs include the client's country and city
+	GeoDBPath string `yaml:"geo_db_path"`
+	// SessionLimit is how many active login sessions a user may have, 0 means no limit
+	SessionLimit int `yaml:"session_limit" env-default:"5"`
+	// BcryptCost is used for new password hashes, older hashes with a lower cost are upgraded on login
+	BcryptCost int `yaml:"bcrypt_cost" env-default:"10"`
+	// LoginMaxFailures wrong passwords in a row lock the account for LoginLockout, 0 turns it off
+	LoginMaxFailures int           `yaml:"login_max_failures" env-default:"10"`
+	LoginLockout     time.Duration `yaml:"login_lockout" env-default:"15m"`
+	// RequireArticleVersion makes article updates without a version fail instead of skipping the conflict check
+	RequireArticleVersion bool `yaml:"require_article_version" env-default:"false"`
+	// NotificationEnabled sends email notifications from SMTPFrom through the SMTP relay at SMTPHost:SMTPPort
+	NotificationEnabled bool   `yaml:"notification_enabled" env-default:"false"`
+	SMTPHost            string `yaml:"smtp_host"`
+	SMTPPort            int    `yaml:"smtp_port" env-default:"25"`
+	SMTPFrom            string `yaml:"smtp_from"`
+	// Secret is read from JWT_SECRET env variable.
+	// Setting it in the config file is deprecated and kept for backward compatibility
+	Secret         string `yaml:"secret"`
+	SecretFromFile bool   `yaml:"-"`
+	// PreviousSecrets still verify HS256 tokens signed before the secret was rotated.
+	// JWT_PREVIOUS_SECRET env variable replaces them with a comma separated list
+	PreviousSecrets []string `yaml:"previous_secrets"`
+	// PreviousSecret is the single secret form of PreviousSecrets, kept for backward compatibility
+	PreviousSecret string `yaml:"previous_secret"`
+	JWT            `yaml:"jwt"`
+	HTTPServer     `yaml:"http_server"`
+	Logging        Logging `yaml:"logging"`
+	Static         Static  `yaml:"static"`
+
+	// NewsletterToken, read from NEWSLETTER_TOKEN env variable or the config file, is a static API key
+	// machine clients send instead of an admin token to read the newsletter digest. Empty disables it
+	NewsletterToken string `yaml:"newsletter_token"`
+}
+
+// Logging configures the logger. Logs go to stdout unless File is set,
+// the file is rotated once it grows past MaxSizeMB keeping MaxBackups old files
+type Logging struct {
+	Level      string `yaml:"level" env-default:"info"`
+	Format     string `yaml:"format" env-default:"text"`
+	File       string `yaml:"file"`
+	MaxSizeMB  int    `yaml:"max_size_mb" env-default:"100"`
+	MaxBackups int    `yaml:"max_backups" env-default:"3"`
+}
+
+// Static serves the frontend build in Dir at the root, next to the API under base_path.
+// Assets may be cached by browsers for MaxAge
+type Static struct {
+	Dir    string        `yaml:"dir"`
+	MaxAge time.Duration `yaml:"max_age" env-default:"24h"`
+}
+
+// JWT selects how tokens are signed. HS256 uses Secret,
+// RS256 signs with the private key and verifies with the public one.
+// A service with only the public key can verify tokens but not issue them
+type JWT struct {
+	Algorithm      string `yaml:"algorithm" env-default:"HS256"`
+	PrivateKeyPath string `yaml:"private_key_path"`
+	PublicKeyPath  string `yaml:"public_key_path"`
 }
 
 type HTTPServer struct {
@@ -22,6 +96,27 @@ type HTTPServer struct {
 	IdleTimeout     time.Duration `yaml:"idle_timeout" env-default:"60s"`
 	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" env-default:"10s"`
 	TokenTTL        time.Duration `yaml:"tokenTTL" env-default:"1h"`
+	// TokenTTLRemember replaces TokenTTL for logins that ask to be remembered
+	TokenTTLRemember time.Duration `yaml:"token_ttl_remember" env-default:"720h"`
+	// RequestTimeout limits handlers of API reads, except streams
+	RequestTimeout time.Duration `yaml:"request_timeout" env-default:"5s"`
+	// WriteTimeout limits handlers of requests that modify data
+	WriteTimeout time.Duration `yaml:"write_timeout" env-default:"5s"`
+	// HeavyReadTimeout limits expensive read endpoints such as the sitemap
+	HeavyReadTimeout time.Duration `yaml:"heavy_read_timeout" env-default:"30s"`
+	// RouteTimeouts overrides the handler timeout for paths under a prefix such as "/articles/trending",
+	// relative to base_path. 0 lifts the limit, the response then isn't bound by Timeout either.
+	// The request itself must still be read within Timeout
+	RouteTimeouts map[string]time.Duration `yaml:"route_timeouts"`
+	// CompressLevel is the gzip level of responses, from 1 to 9, 0 turns compression off
+	CompressLevel int `yaml:"compress_level" env-default:"5"`
+	// CompressMinSize is how long a response must be in bytes to be compressed
+	CompressMinSize int `yaml:"compress_min_size" env-default:"1024"`
+	// BasePath is a prefix such as "/api/v1" the API is served under, empty serves it at the root
+	BasePath string `yaml:"base_path"`
+	// TLSCertFile and TLSKeyFile are PEM files to serve HTTPS with, plain HTTP is served without them
+	TLSCertFile string `yaml:"tls_cert_file"`
+	TLSKeyFile  string `yaml:"tls_key_file"`
 }
 
 func MustLoad() *Config {
@@ -31,13 +126,98 @@ func MustLoad() *Config {
 	}
 
 	if _, err := os.Stat(path); err != nil {
-		log.Panicf("error opening config file: %w:", err)
+		log.Panicf("error opening config file: %v", err)
 	}
 
 	var cfg Config
 
 	if err := cleanenv.ReadConfig(path, &cfg); err != nil {
-		log.Panicf("error reading config file: %w", err)
+		log.Panicf("error reading config file: %v", err)
+	}
+
+	if secret, ok := os.LookupEnv(secretEnv); ok {
+		cfg.Secret = secret
+	} else if cfg.Secret != "" {
+		cfg.SecretFromFile = true
+	}
+
+	if cfg.PreviousSecret != "" {
+		cfg.PreviousSecrets = append([]string{cfg.PreviousSecret}, cfg.PreviousSecrets...)
+	}
+	if secrets, ok := os.LookupEnv(previousSecretEnv); ok {
+		cfg.PreviousSecrets = nil
+		for _, secret := range strings.Split(secrets, ",") {
+			if secret = strings.TrimSpace(secret); secret != "" {
+				cfg.PreviousSecrets = append(cfg.PreviousSecrets, secret)
+			}
+		}
+	}
+
+	if token, ok := os.LookupEnv(newsletterEnv); ok {
+		cfg.NewsletterToken = token
+	}
+	// A short key can be guessed
+	if cfg.NewsletterToken != "" && len(cfg.NewsletterToken) < minSecretLen {
+		log.Panicf("newsletter token must be at least %d bytes long", minSecretLen)
+	}
+
+	if cfg.LoginMaxFailures < 0 || (cfg.LoginMaxFailures > 0 && cfg.LoginLockout <= 0) {
+		log.Panicf("login_max_failures can't be negative and login_lockout must be positive")
+	}
+
+	if cfg.BcryptCost < bcrypt.MinCost || cfg.BcryptCost > bcrypt.MaxCost {
+		log.Panicf("bcrypt_cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+	}
+
+	cfg.BasePath = strings.TrimSuffix(cfg.BasePath, "/")
+	if cfg.BasePath != "" && !strings.HasPrefix(cfg.BasePath, "/") {
+		log.Panicf("http_server.base_path must start with \"/\"")
+	}
+
+	if cfg.CompressLevel < gzip.NoCompression || cfg.CompressLevel > gzip.BestCompression || cfg.CompressMinSize < 0 {
+		log.Panicf("http_server.compress_level must be between 0 and 9 and http_server.compress_min_size can't be negative")
+	}
+
+	// At the root the API would take every path the frontend needs
+	if cfg.Static.Dir != "" && cfg.BasePath == "" {
+		log.Panicf("static.dir requires http_server.base_path")
+	}
+
+	if (cfg.TLSCertFile == "") != (cfg.TLSKeyFile == "") {
+		log.Panicf("http_server.tls_cert_file and http_server.tls_key_file must be set together")
+	}
+
+	for prefix, d := range cfg.RouteTimeouts {
+		if !strings.HasPrefix(prefix, "/") || d < 0 {
+			log.Panicf("http_server.route_timeouts keys must start with \"/\" and values can't be negative")
+		}
+	}
+
+	if cfg.Logging.File != "" && (cfg.Logging.MaxSizeMB <= 0 || cfg.Logging.MaxBackups < 0) {
+		log.Panicf("logging.max_size_mb must be positive and logging.max_backups can't be negative")
+	}
+
+	if cfg.NotificationEnabled && (cfg.SMTPHost == "" || cfg.SMTPFrom == "") {
+		log.Panicf("notification_enabled requires smtp_host and smtp_from")
+	}
+
+	switch cfg.JWT.Algorithm {
+	case "HS256":
+		// HS256 with a short key can be brute-forced
+		if len(cfg.Secret) < minSecretLen {
+			log.Panicf("jwt secret must be at least %d bytes long, set it via %s env variable", minSecretLen, secretEnv)
+		}
+		for _, secret := range cfg.PreviousSecrets {
+			if len(secret) < minSecretLen {
+				log.Panicf("previous jwt secrets must be at least %d bytes long", minSecretLen)
+			}
+		}
+	case "RS256":
+		if cfg.JWT.PrivateKeyPath == "" && cfg.JWT.PublicKeyPath == "" {
+			log.Panicf("RS256 requires jwt.private_key_path or jwt.public_key_path")
+		}
+	default:
+		log.Panicf("unsupported jwt algorithm: %q", cfg.JWT.Algorithm)
 	}
 
 	return &cfg
@@ -2,10 +2,54 @@ package models
 
 import "time"
 
+const (
+	ArticleDraft     = "draft"
+	ArticlePublished = "published"
+
+	ReactionLike    = "like"
+	ReactionDislike = "dislike"
+
+	// DefaultLanguage is set on articles created without a language
+	DefaultLanguage = "en"
+
+	// ArticleSortUpdated lists recently changed articles first
+	ArticleSortUpdated = "updated"
+	// ArticleSortLikes and ArticleSortViews list the most popular articles first
+	ArticleSortLikes = "likes"
+	ArticleSortViews = "views"
+
+	RemovalDeleted   = "deleted"
+	RemovalNotFound  = "not_found"
+	RemovalForbidden = "forbidden"
+)
+
+// PopularityFilter leaves out articles with fewer likes or views, zeros don't filter
+type PopularityFilter struct {
+	MinLikes int
+	MinViews int
+}
+
+// RemovalResult is the outcome for one article of a bulk removal
+type RemovalResult struct {
+	ID     int    `json:"id"`
+	Result string `json:"result"`
+}
+
 type Article struct {
-	ID          int        `json:"id,omitempty"`
-	Title       string     `json:"title,omitempty"`
-	Content     string     `json:"content,omitempty"`
-	PublishDate *time.Time `json:"publish_date,omitempty"`
-	AuthorID    int        `json:"author_id,omitempty"`
+	ID           int        `json:"id,omitempty"`
+	Title        string     `json:"title,omitempty"`
+	Content      string     `json:"content,omitempty"`
+	Language     string     `json:"language,omitempty"`
+	CanonicalURL string     `json:"canonical_url,omitempty"`
+	PublishDate  *time.Time `json:"publish_date,omitempty"`
+	CreatedAt    *time.Time `json:"created_at,omitempty"`
+	UpdatedAt    *time.Time `json:"updated_at,omitempty"`
+	Status       string     `json:"status,omitempty"`
+	AuthorID     int        `json:"author_id,omitempty"`
+	Pinned       bool       `json:"pinned,omitempty"`
+	Version      int        `json:"version,omitempty"`
+	Views        int        `json:"views,omitempty"`
+	Likes        int        `json:"likes,omitempty"`
+	Dislikes     int        `json:"dislikes,omitempty"`
+	Score        int        `json:"score,omitempty"`
 }
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// Attachment is a file uploaded for an article. The content is stored once per SHA256,
+// however many articles it's attached to
+type Attachment struct {
+	ID        int64     `json:"id"`
+	ArticleID int       `json:"article_id"`
+	SHA256    string    `json:"sha256"`
+	MimeType  string    `json:"mime_type"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"created_at"`
+	// URL is the path the content is served at, to embed in the article
+	URL string `json:"url,omitempty"`
+}
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// Collection is a reading list of articles by any authors.
+// Private collections are only visible to their owner
+type Collection struct {
+	ID          int64     `json:"id"`
+	OwnerID     int       `json:"owner_id"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	IsPublic    bool      `json:"is_public"`
+	CreatedAt   time.Time `json:"created_at"`
+}
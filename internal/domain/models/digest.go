@@ -0,0 +1,14 @@
+package models
+
+// Digest collects the articles published over a period for a newsletter, grouped by author
+type Digest struct {
+	// Period is the range of publish dates, e.g. "2024-01-01/2024-01-07"
+	Period           string          `json:"period"`
+	ArticlesByAuthor []AuthorSection `json:"articles_by_author"`
+}
+
+// AuthorSection holds an author's articles of a digest, oldest first
+type AuthorSection struct {
+	Author   User      `json:"author"`
+	Articles []Article `json:"articles"`
+}
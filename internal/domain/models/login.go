@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+type LoginEvent struct {
+	ID         int64     `json:"id"`
+	UserID     int64     `json:"user_id"`
+	IP         string    `json:"ip"`
+	UserAgent  string    `json:"user_agent"`
+	LoggedInAt time.Time `json:"logged_in_at"`
+	Success    bool      `json:"success"`
+}
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// ArticleStats is the breakdown of one article's audience for its author
+type ArticleStats struct {
+	ArticleID int `json:"article_id"`
+	// Views has a point per day, oldest first. Days without views are zeros
+	Views []DayViews `json:"views"`
+	Likes int        `json:"likes"`
+	// FirstLikeAfter is the number of seconds from publication to the first like,
+	// nil while the article has no likes or isn't published
+	FirstLikeAfter *int64 `json:"first_like_after_seconds"`
+}
+
+// DayViews counts the views of a day (UTC), formatted as 2006-01-02
+type DayViews struct {
+	Date  string `json:"date"`
+	Views int    `json:"views"`
+}
+
+// UserStats sums up what a user published, for profile pages.
+// Only published articles and their views and likes count
+type UserStats struct {
+	UserID   int64      `json:"user_id"`
+	Articles int        `json:"articles"`
+	Likes    int        `json:"likes"`
+	Views    int        `json:"views"`
+	JoinedAt *time.Time `json:"joined_at"`
+}
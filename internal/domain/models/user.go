@@ -2,12 +2,28 @@ package models
 
 import "time"
 
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
 type User struct {
 	ID               int64      `json:"id,omitempty"`
 	RegistrationDate *time.Time `json:"registration_date,omitempty"`
+	UpdatedAt        *time.Time `json:"updated_at,omitempty"`
 	Status           string     `json:"status,omitempty"`
+	Role             string     `json:"role,omitempty"`
 	ArticlesID       []int64    `json:"articles_id,omitempty"`
 	Credentials      `json:"credentials,omitempty"`
+
+	// TotalLikesReceived counts likes on all of the user's articles, it's only set on profiles
+	TotalLikesReceived int `json:"total_likes_received,omitempty"`
+	// CurrentStreak and LongestStreak count consecutive days (UTC) with a published article, they are only set on profiles
+	CurrentStreak int `json:"current_streak,omitempty"`
+	LongestStreak int `json:"longest_streak,omitempty"`
+
+	// LockedUntil is set while logins are refused after too many wrong passwords, it's never sent
+	LockedUntil *time.Time `json:"-"`
 }
 
 type Credentials struct {
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// Webhook events
+const (
+	WebhookArticlePublished = "article.published"
+)
+
+// Webhook is an HTTP callback called on the events it's subscribed to.
+// Secret signs the deliveries and is never returned to clients
+type Webhook struct {
+	ID        int64     `json:"id"`
+	OwnerID   int       `json:"owner_id"`
+	URL       string    `json:"url"`
+	Secret    string    `json:"-"`
+	Events    []string  `json:"events"`
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"created_at"`
+}
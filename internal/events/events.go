@@ -0,0 +1,78 @@
+package events
+
+import "sync"
+
+const (
+	ArticleCreated = "article.created"
+)
+
+// subscriberBuffer is how many events a slow subscriber may lag behind before it misses some
+const subscriberBuffer = 16
+
+type Event struct {
+	Type string
+	Data any
+}
+
+// Bus is an in-process publish/subscribe hub
+type Bus struct {
+	mu     sync.Mutex
+	subs   map[chan Event]struct{}
+	closed bool
+}
+
+func New() *Bus {
+	return &Bus{
+		subs: make(map[chan Event]struct{}),
+	}
+}
+
+// Subscribe returns a channel receiving published events and a function to unsubscribe.
+// The channel is closed on unsubscribe or when the bus is closed
+func (b *Bus) Subscribe() (<-chan Event, func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ch := make(chan Event, subscriberBuffer)
+	if b.closed {
+		close(ch)
+		return ch, func() {}
+	}
+	b.subs[ch] = struct{}{}
+
+	return ch, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		if _, ok := b.subs[ch]; ok {
+			delete(b.subs, ch)
+			close(ch)
+		}
+	}
+}
+
+// Publish sends the event to every subscriber without blocking,
+// subscribers with a full buffer miss it
+func (b *Bus) Publish(e Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for ch := range b.subs {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
+
+// Close closes every subscription, later subscriptions are closed right away
+func (b *Bus) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for ch := range b.subs {
+		delete(b.subs, ch)
+		close(ch)
+	}
+}
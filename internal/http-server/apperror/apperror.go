@@ -0,0 +1,159 @@
+package apperror
+
+import (
+	"errors"
+	"net/http"
+
+	resp "blog-api/internal/lib/api/response"
+	"blog-api/internal/service/article"
+	"blog-api/internal/service/attachment"
+	"blog-api/internal/service/backup"
+	"blog-api/internal/service/collection"
+	"blog-api/internal/service/notification"
+	"blog-api/internal/service/session"
+	"blog-api/internal/service/sitemap"
+	"blog-api/internal/service/stats"
+	"blog-api/internal/service/user"
+	"blog-api/internal/service/webhook"
+
+	"github.com/go-chi/render"
+)
+
+// AppError is an error together with the response it's answered with.
+// Message is sent to the client, translated by Code when possible
+type AppError struct {
+	Code       string
+	Message    string
+	HTTPStatus int
+	Err        error
+}
+
+func (e *AppError) Error() string {
+	if e.Err != nil {
+		return e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *AppError) Unwrap() error {
+	return e.Err
+}
+
+// known are the service errors clients are told about, any other error is internal.
+// Without a Message the error's own text is sent
+var known = []*AppError{
+	// Article
+	{Err: article.ErrArticleNotFound, HTTPStatus: http.StatusNotFound, Code: resp.CodeNotFound},
+	{Err: article.ErrForbidden, HTTPStatus: http.StatusForbidden, Code: resp.CodeForbidden},
+	{Err: article.ErrArticleExists, HTTPStatus: http.StatusConflict, Code: resp.CodeArticleExists, Message: "article title already taken"},
+	{Err: article.ErrVersionConflict, HTTPStatus: http.StatusConflict, Code: resp.CodeVersionConflict},
+	{Err: article.ErrVersionRequired, HTTPStatus: http.StatusPreconditionRequired, Code: resp.CodeVersionRequired},
+	{Err: article.ErrTooManyPinned, HTTPStatus: http.StatusConflict, Code: resp.CodeConflict},
+	{Err: article.ErrEmptyTitle, HTTPStatus: http.StatusBadRequest, Code: resp.CodeValidationFailed},
+	{Err: article.ErrTitleTooLong, HTTPStatus: http.StatusBadRequest, Code: resp.CodeValidationFailed},
+	{Err: article.ErrContentTooLong, HTTPStatus: http.StatusBadRequest, Code: resp.CodeValidationFailed},
+	{Err: article.ErrInvalidStatus, HTTPStatus: http.StatusBadRequest, Code: resp.CodeValidationFailed},
+	{Err: article.ErrInvalidReaction, HTTPStatus: http.StatusBadRequest, Code: resp.CodeValidationFailed},
+	{Err: article.ErrInvalidSort, HTTPStatus: http.StatusBadRequest, Code: resp.CodeValidationFailed},
+	{Err: article.ErrInvalidPeriod, HTTPStatus: http.StatusBadRequest, Code: resp.CodeValidationFailed},
+	{Err: article.ErrInvalidHours, HTTPStatus: http.StatusBadRequest, Code: resp.CodeValidationFailed},
+	{Err: article.ErrPeriodAndHours, HTTPStatus: http.StatusBadRequest, Code: resp.CodeValidationFailed},
+	{Err: article.ErrInvalidLanguage, HTTPStatus: http.StatusBadRequest, Code: resp.CodeValidationFailed},
+	{Err: article.ErrInvalidCanonicalURL, HTTPStatus: http.StatusBadRequest, Code: resp.CodeValidationFailed},
+	{Err: article.ErrNoIDs, HTTPStatus: http.StatusBadRequest, Code: resp.CodeValidationFailed},
+	{Err: article.ErrTooManyIDs, HTTPStatus: http.StatusBadRequest, Code: resp.CodeValidationFailed},
+
+	// Attachment
+	{Err: attachment.ErrArticleNotFound, HTTPStatus: http.StatusNotFound, Code: resp.CodeNotFound},
+	{Err: attachment.ErrForbidden, HTTPStatus: http.StatusForbidden, Code: resp.CodeForbidden},
+	{Err: attachment.ErrAttachmentNotFound, HTTPStatus: http.StatusNotFound, Code: resp.CodeNotFound},
+	{Err: attachment.ErrTooLarge, HTTPStatus: http.StatusRequestEntityTooLarge, Code: resp.CodePayloadTooLarge},
+	{Err: attachment.ErrUnsupportedType, HTTPStatus: http.StatusUnsupportedMediaType, Code: resp.CodeUnsupportedMedia},
+
+	// Backup
+	{Err: backup.ErrBackupNotFound, HTTPStatus: http.StatusNotFound, Code: resp.CodeNotFound},
+	{Err: backup.ErrInvalidName, HTTPStatus: http.StatusBadRequest, Code: resp.CodeValidationFailed},
+	{Err: backup.ErrCorrupted, HTTPStatus: http.StatusUnprocessableEntity, Code: resp.CodeBackupCorrupted},
+	{Err: backup.ErrBusy, HTTPStatus: http.StatusServiceUnavailable, Code: resp.CodeUnavailable},
+	{Err: backup.ErrBackupExists, HTTPStatus: http.StatusConflict, Code: resp.CodeConflict},
+
+	// Collection
+	{Err: collection.ErrCollectionNotFound, HTTPStatus: http.StatusNotFound, Code: resp.CodeNotFound},
+	{Err: collection.ErrForbidden, HTTPStatus: http.StatusForbidden, Code: resp.CodeForbidden},
+	{Err: collection.ErrArticleNotFound, HTTPStatus: http.StatusNotFound, Code: resp.CodeNotFound},
+	{Err: collection.ErrArticleNotInCollection, HTTPStatus: http.StatusNotFound, Code: resp.CodeNotFound},
+	{Err: collection.ErrArticleInCollection, HTTPStatus: http.StatusConflict, Code: resp.CodeConflict},
+	{Err: collection.ErrInvalidTitle, HTTPStatus: http.StatusBadRequest, Code: resp.CodeValidationFailed},
+	{Err: collection.ErrDescriptionTooLong, HTTPStatus: http.StatusBadRequest, Code: resp.CodeValidationFailed},
+
+	// Notification
+	{Err: notification.ErrTooManyIDs, HTTPStatus: http.StatusBadRequest, Code: resp.CodeValidationFailed},
+
+	// Session
+	{Err: session.ErrSessionNotFound, HTTPStatus: http.StatusNotFound, Code: resp.CodeNotFound},
+
+	// Sitemap
+	{Err: sitemap.ErrPageNotFound, HTTPStatus: http.StatusNotFound, Code: resp.CodeNotFound},
+
+	// Stats
+	{Err: stats.ErrArticleNotFound, HTTPStatus: http.StatusNotFound, Code: resp.CodeNotFound},
+	{Err: stats.ErrForbidden, HTTPStatus: http.StatusForbidden, Code: resp.CodeForbidden},
+
+	// User
+	{Err: user.ErrUserNotFound, HTTPStatus: http.StatusNotFound, Code: resp.CodeNotFound},
+	{Err: user.ErrInvalidCredentials, HTTPStatus: http.StatusUnauthorized, Code: resp.CodeInvalidCredentials},
+	{Err: user.ErrUserExists, HTTPStatus: http.StatusConflict, Code: resp.CodeUserExists, Message: "user already exists"},
+	{Err: user.ErrUserNameTaken, HTTPStatus: http.StatusConflict, Code: resp.CodeUserExists},
+	{Err: user.ErrEmailTaken, HTTPStatus: http.StatusConflict, Code: resp.CodeEmailTaken},
+	{Err: user.ErrTitleTaken, HTTPStatus: http.StatusConflict, Code: resp.CodeArticleExists},
+	{Err: user.ErrInvalidEmail, HTTPStatus: http.StatusBadRequest, Code: resp.CodeValidationFailed},
+	{Err: user.ErrInvalidUserName, HTTPStatus: http.StatusBadRequest, Code: resp.CodeValidationFailed},
+	{Err: user.ErrNothingToCheck, HTTPStatus: http.StatusBadRequest, Code: resp.CodeValidationFailed},
+	{Err: user.ErrStatusTooLong, HTTPStatus: http.StatusBadRequest, Code: resp.CodeValidationFailed},
+	{Err: user.ErrInvalidStatus, HTTPStatus: http.StatusBadRequest, Code: resp.CodeValidationFailed},
+	{Err: user.ErrEmptyPassword, HTTPStatus: http.StatusBadRequest, Code: resp.CodeValidationFailed},
+	{Err: user.ErrInvalidResetToken, HTTPStatus: http.StatusBadRequest, Code: resp.CodeValidationFailed},
+	{Err: user.ErrAccountLocked, HTTPStatus: http.StatusLocked, Code: resp.CodeAccountLocked},
+
+	// Webhook
+	{Err: webhook.ErrWebhookNotFound, HTTPStatus: http.StatusNotFound, Code: resp.CodeNotFound},
+	{Err: webhook.ErrForbidden, HTTPStatus: http.StatusForbidden, Code: resp.CodeForbidden},
+	{Err: webhook.ErrInvalidURL, HTTPStatus: http.StatusBadRequest, Code: resp.CodeValidationFailed},
+	{Err: webhook.ErrInvalidSecret, HTTPStatus: http.StatusBadRequest, Code: resp.CodeValidationFailed},
+	{Err: webhook.ErrInvalidEvents, HTTPStatus: http.StatusBadRequest, Code: resp.CodeValidationFailed},
+}
+
+// internal answers errors clients aren't told about
+var internal = &AppError{HTTPStatus: http.StatusInternalServerError, Code: resp.CodeInternal, Message: "internal error"}
+
+// From returns the AppError err is or wraps, or maps a known service error to one.
+// Any other error is internal and reported as not ok
+func From(err error) (appErr *AppError, ok bool) {
+	if errors.As(err, &appErr) {
+		return appErr, true
+	}
+
+	for _, known := range known {
+		if errors.Is(err, known.Err) {
+			return known, true
+		}
+	}
+
+	return internal, false
+}
+
+// Render answers with the response err maps to and reports whether err was expected.
+// Unexpected errors get 500, logging them is up to the caller
+func Render(w http.ResponseWriter, r *http.Request, err error) bool {
+	appErr, ok := From(err)
+
+	msg := appErr.Message
+	if msg == "" {
+		msg = appErr.Error()
+	}
+
+	render.Status(r, appErr.HTTPStatus)
+	render.JSON(w, r, resp.Err(r, appErr.Code, msg))
+
+	return ok
+}
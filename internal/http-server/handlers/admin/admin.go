@@ -0,0 +1,164 @@
+package admin
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"blog-api/internal/domain/models"
+	"blog-api/internal/http-server/apperror"
+	mw "blog-api/internal/http-server/middleware"
+	req "blog-api/internal/lib/api/request"
+	resp "blog-api/internal/lib/api/response"
+	"blog-api/internal/lib/logger"
+	"blog-api/internal/lib/logger/sl"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+type BackupService interface {
+	Create(ctx context.Context) (models.Backup, error)
+	List() ([]models.Backup, error)
+	Restore(ctx context.Context, name string) error
+}
+
+type Admin struct {
+	log      *slog.Logger
+	backups  BackupService
+	logLevel *slog.LevelVar
+	verifier func(http.Handler) http.Handler
+}
+
+// New takes the LevelVar of the logger so that its level can be changed while running
+func New(log *slog.Logger, backups BackupService, logLevel *slog.LevelVar, verifier func(http.Handler) http.Handler) *Admin {
+	return &Admin{
+		log:      log,
+		backups:  backups,
+		logLevel: logLevel,
+		verifier: verifier,
+	}
+}
+
+func (a *Admin) Register() func(r chi.Router) {
+	return func(r chi.Router) {
+		// Require admin
+		r.Use(a.verifier)
+		r.Use(mw.Authenticator)
+		r.Use(mw.RequireAdmin)
+
+		r.Post("/backup", a.createBackup)
+		r.Get("/backups", a.listBackups)
+		r.Post("/restore/{name}", a.restore)
+		r.Get("/loglevel", a.getLogLevel)
+		r.Post("/loglevel", a.setLogLevel)
+	}
+}
+
+func (a *Admin) createBackup(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.admin.createBackup"
+
+	log := logger.FromContext(r.Context(), a.log).With(slog.String("op", op))
+
+	// Send to service layer
+	b, err := a.backups.Create(r.Context())
+	if err != nil {
+		if !apperror.Render(w, r, err) {
+			log.Error("failed to create backup", sl.Error(err))
+		}
+		return
+	}
+
+	// Write to response
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, resp.Response{
+		Status:  resp.StatusOk,
+		Backups: &[]models.Backup{b},
+	})
+}
+
+func (a *Admin) listBackups(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.admin.listBackups"
+
+	log := logger.FromContext(r.Context(), a.log).With(slog.String("op", op))
+
+	// Send to service layer
+	backups, err := a.backups.List()
+	if err != nil {
+		log.Error("failed to list backups", sl.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, resp.Err(r, resp.CodeInternal, "internal error"))
+		return
+	}
+
+	// Write to response
+	render.JSON(w, r, resp.Response{
+		Status:  resp.StatusOk,
+		Backups: &backups,
+	})
+}
+
+func (a *Admin) restore(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.admin.restore"
+
+	name := chi.URLParam(r, "name")
+
+	log := logger.FromContext(r.Context(), a.log).With(slog.String("op", op), slog.String("name", name))
+
+	// Send to service layer
+	err := a.backups.Restore(r.Context(), name)
+	if err != nil {
+		// Restores are rare, every failure is worth a look
+		log.Error("failed to restore backup", sl.Error(err))
+		apperror.Render(w, r, err)
+		return
+	}
+
+	// Write to response
+	render.JSON(w, r, resp.Response{
+		Status: resp.StatusOk,
+	})
+}
+
+func (a *Admin) getLogLevel(w http.ResponseWriter, r *http.Request) {
+	// Write to response
+	render.JSON(w, r, resp.Response{
+		Status:   resp.StatusOk,
+		LogLevel: strings.ToLower(a.logLevel.Level().String()),
+	})
+}
+
+func (a *Admin) setLogLevel(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.admin.setLogLevel"
+
+	log := logger.FromContext(r.Context(), a.log).With(slog.String("op", op))
+
+	var body req.LogLevel
+	err := render.DecodeJSON(r.Body, &body)
+	if err != nil {
+		log.Debug("failed to decode request", sl.Error(err))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, resp.Err(r, resp.CodeInvalidBody, "invalid request body"))
+		return
+	}
+
+	level, err := logger.ParseLevel(body.Level)
+	if err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, resp.Err(r, resp.CodeValidationFailed, "level must be one of debug, info, warn, error"))
+		return
+	}
+
+	previous := a.logLevel.Level()
+	a.logLevel.Set(level)
+
+	// Logged at warn so that the change shows up whatever the new level is
+	log.Warn("log level changed", slog.String("from", previous.String()), slog.String("to", level.String()))
+
+	// Write to response
+	render.JSON(w, r, resp.Response{
+		Status:   resp.StatusOk,
+		LogLevel: strings.ToLower(level.String()),
+	})
+}
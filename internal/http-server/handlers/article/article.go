@@ -1,74 +1,377 @@
 package article
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"blog-api/internal/domain/models"
+	"blog-api/internal/http-server/apperror"
+	mw "blog-api/internal/http-server/middleware"
+	req "blog-api/internal/lib/api/request"
 	resp "blog-api/internal/lib/api/response"
 	"blog-api/internal/lib/jwt"
+	"blog-api/internal/lib/logger"
 	"blog-api/internal/lib/logger/sl"
 	"blog-api/internal/service/article"
-	"blog-api/internal/storage"
 
 	"github.com/go-chi/chi/v5"
-	"github.com/go-chi/jwtauth/v5"
 	"github.com/go-chi/render"
 )
 
+// includeAuthor adds the author to GET /articles/{id} when passed in the "include" query param
+const includeAuthor = "author"
+
 type Service interface {
-	GetAll() ([]models.Article, error)
-	GetByID(id int) (*models.Article, error)
-	Create(art *models.Article) error
-	Update(art *models.Article) error
-	Remove(id int) error
+	GetAll(ctx context.Context, language, sort string, popular models.PopularityFilter, visibleOnly bool, viewerID, limit, offset int) ([]models.Article, error)
+	GetInRange(ctx context.Context, from, to time.Time, language, sort string, visibleOnly bool, viewerID, limit, offset int) ([]models.Article, error)
+	GetByAuthor(ctx context.Context, authorID int, visibleOnly bool, viewerID, limit, offset int) ([]models.Article, error)
+	Trending(ctx context.Context, period string, hours, limit int) ([]models.Article, error)
+	GetByID(ctx context.Context, id int, visibleOnly bool, viewerID int) (*models.Article, error)
+	GetByIDs(ctx context.Context, ids []int, visibleOnly bool, viewerID int) ([]models.Article, []int, error)
+	LastModified(ctx context.Context) (time.Time, error)
+	GetByIDWithAuthor(ctx context.Context, id int, visibleOnly bool, viewerID int) (*models.Article, *models.User, error)
+	View(ctx context.Context, id int, fingerprint string) error
+	React(ctx context.Context, userID, id int, reaction string) error
+	Create(ctx context.Context, art *models.Article) (int64, error)
+	Duplicate(ctx context.Context, id, requesterID int, role string) (int64, error)
+	Update(ctx context.Context, art *models.Article, requesterID int, role string) (int, error)
+	Pin(ctx context.Context, id, requesterID int, role string) error
+	Unpin(ctx context.Context, id, requesterID int, role string) error
+	Remove(ctx context.Context, id, requesterID int, role string) error
+	RemoveMany(ctx context.Context, requesterID int, role string, ids []int) ([]models.RemovalResult, error)
 }
 
 type Article struct {
-	log     *slog.Logger
-	service Service
-	secret  string
+	log      *slog.Logger
+	service  Service
+	verifier func(http.Handler) http.Handler
+	events   Subscriber
 }
 
-func New(log *slog.Logger, service Service, secret string) *Article {
+func New(log *slog.Logger, service Service, verifier func(http.Handler) http.Handler, events Subscriber) *Article {
 	return &Article{
-		log:     log,
-		service: service,
-		secret:  secret,
+		log:      log,
+		service:  service,
+		verifier: verifier,
+		events:   events,
 	}
 }
 
 func (a *Article) Register() func(r chi.Router) {
 	return func(r chi.Router) {
+		// Write endpoints only accept JSON bodies
+		r.Use(mw.RequireJSON)
+
 		// Public routes
 		r.Get("/", a.getAll)
+		r.Get("/stream", a.stream)
+		r.Get("/trending", a.trending)
 		r.Get("/{id}", a.getByID)
 
 		// Require auth
 		r.Group(func(r chi.Router) {
-			tokenAuth := jwtauth.New("HS256", []byte(a.secret), nil)
-			r.Use(jwtauth.Verifier(tokenAuth))
-			r.Use(jwtauth.Authenticator(tokenAuth))
+			r.Use(a.verifier)
+			r.Use(mw.Authenticator)
 
 			r.Post("/", a.create)
+			r.Delete("/", a.removeMany)
+			r.Post("/{id}/duplicate", a.duplicate)
+			r.Post("/{id}/like", a.react(models.ReactionLike))
+			r.Post("/{id}/dislike", a.react(models.ReactionDislike))
+			r.Delete("/{id}/reaction", a.react(""))
 			r.Put("/{id}", a.update)
+			r.Put("/{id}/pin", a.pin)
+			r.Delete("/{id}/pin", a.unpin)
 			r.Delete("/{id}", a.remove)
 		})
 	}
 }
 
+// RegisterByAuthor serves the articles of one author,
+// it expects to be mounted under a route with the user "id" param
+func (a *Article) RegisterByAuthor() func(r chi.Router) {
+	return func(r chi.Router) {
+		// Public routes
+		r.Get("/", a.getByAuthor)
+
+		// Require auth
+		r.Group(func(r chi.Router) {
+			r.Use(a.verifier)
+			r.Use(mw.Authenticator)
+
+			r.Delete("/", a.removeBulk)
+		})
+	}
+}
+
 func (a *Article) getAll(w http.ResponseWriter, r *http.Request) {
 	const op = "handlers.article.getAll"
 
-	log := a.log.With(slog.String("op", op))
+	log := logger.FromContext(r.Context(), a.log).With(slog.String("op", op))
+
+	// Send to service layer
+	lastMod, err := a.service.LastModified(r.Context())
+	if err != nil {
+		// The list can still be served, just without caching
+		log.Error("failed to get last modification time", sl.Error(err))
+	} else if notModified(w, r, lastMod) {
+		return
+	}
+
+	q := r.URL.Query()
+	if q.Has("ids") {
+		a.getByIDs(w, r)
+		return
+	}
+	if q.Has("from") || q.Has("to") {
+		a.getInRange(w, r)
+		return
+	}
+
+	popular, err := req.Popularity(r)
+	if err != nil {
+		log.Debug("invalid popularity params", sl.Error(err))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, resp.Err(r, resp.CodeValidationFailed, err.Error()))
+		return
+	}
+
+	// The full list stays unpaginated for older clients, popular posts are paged
+	limit, offset := -1, 0
+	if popular != (models.PopularityFilter{}) || q.Has("limit") || q.Has("offset") {
+		limit, offset, err = req.Pagination(r)
+		if err != nil {
+			log.Debug("invalid pagination params", sl.Error(err))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Err(r, resp.CodeValidationFailed, err.Error()))
+			return
+		}
+	}
+
+	fields, ok := pickFields(w, r, log)
+	if !ok {
+		return
+	}
+
+	// Drafts are private to their author, anonymous callers only see published articles
+	viewerID, _ := jwt.UserID(r.Context())
+
+	// Send to service layer
+	articles, err := a.service.GetAll(r.Context(), q.Get("language"), q.Get("sort"), popular, true, viewerID, limit, offset)
+	if err != nil {
+		if !apperror.Render(w, r, err) {
+			log.Error("failed to get all articles", sl.Error(err))
+		}
+		return
+	}
+
+	// Write to response
+	render.JSON(w, r, resp.SelectFields(resp.Response{
+		Status:   resp.StatusOk,
+		Articles: &articles,
+	}, fields))
+}
+
+// pickFields parses the "fields" query param, nil means all fields. It answers 400 on unknown fields
+func pickFields(w http.ResponseWriter, r *http.Request, log *slog.Logger) ([]string, bool) {
+	fields, err := resp.ArticleFields(r.URL.Query().Get("fields"))
+	if err != nil {
+		log.Debug("invalid fields param", sl.Error(err))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, resp.Err(r, resp.CodeValidationFailed, err.Error()))
+		return nil, false
+	}
+
+	return fields, true
+}
+
+// notModified sets Last-Modified and answers 304 if the client's copy from If-Modified-Since is still current.
+// HTTP dates have second precision, so lastMod is compared in whole seconds
+func notModified(w http.ResponseWriter, r *http.Request, lastMod time.Time) bool {
+	if lastMod.IsZero() {
+		return false
+	}
+
+	lastMod = lastMod.UTC().Truncate(time.Second)
+	w.Header().Set("Last-Modified", lastMod.Format(http.TimeFormat))
+
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil || lastMod.After(since) {
+		return false
+	}
+
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+func (a *Article) getInRange(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.article.getInRange"
+
+	log := logger.FromContext(r.Context(), a.log).With(slog.String("op", op))
+
+	from, to, err := req.DateRange(r)
+	if err != nil {
+		log.Debug("invalid date range", sl.Error(err))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, resp.Err(r, resp.CodeValidationFailed, err.Error()))
+		return
+	}
+
+	limit, offset, err := req.Pagination(r)
+	if err != nil {
+		log.Debug("invalid pagination params", sl.Error(err))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, resp.Err(r, resp.CodeValidationFailed, err.Error()))
+		return
+	}
+
+	fields, ok := pickFields(w, r, log)
+	if !ok {
+		return
+	}
+
+	// Drafts are private to their author, anonymous callers only see published articles
+	viewerID, _ := jwt.UserID(r.Context())
+
+	// Send to service layer
+	articles, err := a.service.GetInRange(r.Context(), from, to, r.URL.Query().Get("language"), r.URL.Query().Get("sort"), true, viewerID, limit, offset)
+	if err != nil {
+		if !apperror.Render(w, r, err) {
+			log.Error("failed to get articles in range", sl.Error(err))
+		}
+		return
+	}
+
+	// Write to response
+	render.JSON(w, r, resp.SelectFields(resp.Response{
+		Status:   resp.StatusOk,
+		Articles: &articles,
+	}, fields))
+}
+
+// trending lists the top articles over the "period" or "hours" query param, "limit" sets how many
+func (a *Article) trending(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.article.trending"
+
+	log := logger.FromContext(r.Context(), a.log).With(slog.String("op", op))
+
+	limit, _, err := req.Pagination(r)
+	if err != nil {
+		log.Debug("invalid pagination params", sl.Error(err))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, resp.Err(r, resp.CodeValidationFailed, err.Error()))
+		return
+	}
+
+	var hours int
+	if h := r.URL.Query().Get("hours"); h != "" {
+		hours, err = strconv.Atoi(h)
+		if err != nil || hours < 1 {
+			log.Debug("invalid hours param", slog.String("hours", h))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Err(r, resp.CodeValidationFailed, article.ErrInvalidHours.Error()))
+			return
+		}
+	}
 
 	// Send to service layer
-	articles, err := a.service.GetAll()
+	articles, err := a.service.Trending(r.Context(), r.URL.Query().Get("period"), hours, limit)
 	if err != nil {
-		log.Error("failed to get all articles", sl.Error(err))
-		render.JSON(w, r, resp.Err("internal error"))
+		if !apperror.Render(w, r, err) {
+			log.Error("failed to get trending articles", sl.Error(err))
+		}
+		return
+	}
+
+	// Write to response
+	render.JSON(w, r, resp.Response{
+		Status:   resp.StatusOk,
+		Articles: &articles,
+	})
+}
+
+// getByIDs serves GET /articles?ids=1,5,9 keeping the order of the ids
+func (a *Article) getByIDs(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.article.getByIDs"
+
+	log := logger.FromContext(r.Context(), a.log).With(slog.String("op", op))
+
+	var ids []int
+	for _, s := range strings.Split(r.URL.Query().Get("ids"), ",") {
+		id, err := strconv.Atoi(strings.TrimSpace(s))
+		if err != nil {
+			log.Debug("invalid ids param", sl.Error(err))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Err(r, resp.CodeValidationFailed, "invalid ids, expected comma separated article ids"))
+			return
+		}
+		ids = append(ids, id)
+	}
+
+	fields, ok := pickFields(w, r, log)
+	if !ok {
+		return
+	}
+
+	// Drafts are private to their author, other callers get their ids as missing
+	viewerID, _ := jwt.UserID(r.Context())
+
+	// Send to service layer
+	articles, missing, err := a.service.GetByIDs(r.Context(), ids, true, viewerID)
+	if err != nil {
+		if !apperror.Render(w, r, err) {
+			log.Error("failed to get articles by ids", sl.Error(err))
+		}
+		return
+	}
+
+	// Write to response
+	render.JSON(w, r, resp.SelectFields(resp.Response{
+		Status:     resp.StatusOk,
+		Articles:   &articles,
+		MissingIDs: &missing,
+	}, fields))
+}
+
+// getByAuthor lists the articles of the user from the "id" url param, pinned ones first
+func (a *Article) getByAuthor(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.article.getByAuthor"
+
+	log := logger.FromContext(r.Context(), a.log).With(slog.String("op", op))
+
+	authorID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		log.Debug("failed to get \"id\" url param", sl.Error(err))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, resp.Err(r, resp.CodeValidationFailed, "invalid user id"))
+		return
+	}
+
+	limit, offset, err := req.Pagination(r)
+	if err != nil {
+		log.Debug("invalid pagination params", sl.Error(err))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, resp.Err(r, resp.CodeValidationFailed, err.Error()))
+		return
+	}
+
+	// Drafts are private to their author, anonymous callers only see published articles
+	viewerID, _ := jwt.UserID(r.Context())
+
+	// Send to service layer
+	articles, err := a.service.GetByAuthor(r.Context(), authorID, true, viewerID, limit, offset)
+	if err != nil {
+		log.Error("failed to get articles by author", sl.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, resp.Err(r, resp.CodeInternal, "internal error"))
 		return
 	}
 
@@ -82,145 +385,387 @@ func (a *Article) getAll(w http.ResponseWriter, r *http.Request) {
 func (a *Article) create(w http.ResponseWriter, r *http.Request) {
 	const op = "handlers.article.create"
 
-	log := a.log.With(slog.String("op", op))
+	log := logger.FromContext(r.Context(), a.log).With(slog.String("op", op))
 
-	var art models.Article
-	err := render.DecodeJSON(r.Body, &art)
+	var body req.Article
+	err := render.DecodeJSON(r.Body, &body)
 	if err != nil {
-		log.Error("failed to decode request", sl.Error(err))
-		render.JSON(w, r, resp.Err("internal error"))
+		decodeErr(w, r, log, err)
 		return
 	}
+	art := body.Model()
 
-	satisfied, err := jwt.CheckClaim(r.Context(), "uid", strconv.Itoa(art.AuthorID))
+	userID, err := jwt.UserID(r.Context())
 	if err != nil {
-		log.Error("failed to check permission", slog.String("user_id", strconv.Itoa(art.AuthorID)), sl.Error(err))
-		render.JSON(w, r, resp.Err("internal error"))
+		log.Error("failed to get user id from token", sl.Error(err))
+		render.Status(r, http.StatusUnauthorized)
+		render.JSON(w, r, resp.Err(r, resp.CodeUnauthorized, "unauthorized"))
 		return
 	}
-	if !satisfied {
-		log.Debug("user doesn't have permission", slog.Int("user_id", art.AuthorID))
-		render.JSON(w, r, resp.Err("not enough rights"))
+
+	// The author is always the token's user. Older clients still send author_id,
+	// which is fine as long as it's theirs
+	if art.AuthorID != 0 && art.AuthorID != userID {
+		log.Debug("author_id doesn't match token", slog.Int("author_id", art.AuthorID))
+		render.Status(r, http.StatusUnprocessableEntity)
+		render.JSON(w, r, resp.Err(r, resp.CodeValidationFailed, "author_id is no longer accepted, articles are created by the token's user"))
 		return
 	}
+	art.AuthorID = userID
 
 	// Validation
 	if art.Title == "" {
 		log.Debug("failed to create article: title is empty")
-		render.JSON(w, r, resp.Err("title is empty"))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, resp.Err(r, resp.CodeValidationFailed, "title is empty"))
 		return
 	}
 	if art.Content == "" {
 		log.Debug("failed to create article: content is empty")
-		render.JSON(w, r, resp.Err("content is empty"))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, resp.Err(r, resp.CodeValidationFailed, "content is empty"))
 		return
 	}
 
 	// Send to service layer
-	err = a.service.Create(&art)
+	id, err := a.service.Create(r.Context(), &art)
 	if err != nil {
-		log.Error("failed to create article", sl.Error(err))
-		if errors.Is(err, article.ErrArticleExists) {
-			render.JSON(w, r, resp.Err("article title already taken"))
-			return
+		if !apperror.Render(w, r, err) {
+			log.Error("failed to create article", sl.Error(err))
 		}
-		render.JSON(w, r, resp.Err("internal error"))
 		return
 	}
 
 	// Write to response
-	render.JSON(w, r, resp.Response{
+	a.created(w, r, id)
+}
+
+func (a *Article) duplicate(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.article.duplicate"
+
+	log := logger.FromContext(r.Context(), a.log).With(slog.String("op", op))
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		log.Error("failed to get \"id\" url param", sl.Error(err))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, resp.Err(r, resp.CodeValidationFailed, "invalid article id"))
+		return
+	}
+
+	// Any user may use an article as a template, the copy belongs to them
+	userID, role, err := requester(r)
+	if err != nil {
+		log.Error("failed to get requester from token", sl.Error(err))
+		render.Status(r, http.StatusUnauthorized)
+		render.JSON(w, r, resp.Err(r, resp.CodeUnauthorized, "unauthorized"))
+		return
+	}
+
+	// Send to service layer
+	newID, err := a.service.Duplicate(r.Context(), id, userID, role)
+	if err != nil {
+		if !apperror.Render(w, r, err) {
+			log.Error("failed to duplicate article", sl.Error(err))
+		}
+		return
+	}
+
+	// Write to response
+	a.created(w, r, newID)
+}
+
+// created writes a 201 response with the location and body of the new article
+func (a *Article) created(w http.ResponseWriter, r *http.Request, id int64) {
+	const op = "handlers.article.created"
+
+	log := logger.FromContext(r.Context(), a.log).With(slog.String("op", op))
+
+	response := resp.Response{
 		Status: resp.StatusOk,
-	})
+		ID:     id,
+	}
+
+	// Send to service layer, the requester just created the article
+	art, err := a.service.GetByID(r.Context(), int(id), false, 0)
+	if err != nil {
+		// The article is already created, the client can still fetch it by id
+		log.Error("failed to get created article", sl.Error(err))
+	} else {
+		response.Article = resp.NewArticleDTO(art)
+		w.Header().Set("Content-Language", art.Language)
+	}
+
+	w.Header().Set("Location", mw.Path(r, fmt.Sprintf("/articles/%d", id)))
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, response)
+}
+
+// viewerFingerprint identifies a viewer by client IP and User-Agent without storing them
+func viewerFingerprint(r *http.Request) string {
+	sum := sha256.Sum256([]byte(req.ClientIP(r) + r.UserAgent()))
+	return hex.EncodeToString(sum[:])
+}
+
+// decodeErr answers 422 naming the field if a value in the body has the wrong type, 400 otherwise
+func decodeErr(w http.ResponseWriter, r *http.Request, log *slog.Logger, err error) {
+	log.Debug("failed to decode request", sl.Error(err))
+
+	if field, ok := req.InvalidField(err); ok {
+		render.Status(r, http.StatusUnprocessableEntity)
+		render.JSON(w, r, resp.Err(r, resp.CodeValidationFailed, fmt.Sprintf("invalid value of %s", field)))
+		return
+	}
+
+	render.Status(r, http.StatusBadRequest)
+	render.JSON(w, r, resp.Err(r, resp.CodeInvalidBody, "invalid request body"))
 }
 
 func (a *Article) getByID(w http.ResponseWriter, r *http.Request) {
 	const op = "handlers.article.getByID"
 
-	log := a.log.With(slog.String("op", op))
+	log := logger.FromContext(r.Context(), a.log).With(slog.String("op", op))
 
 	id, err := strconv.Atoi(chi.URLParam(r, "id"))
 	if err != nil {
 		log.Error("failed to get \"id\" url param", sl.Error(err))
-		render.JSON(w, r, resp.Err("internal error"))
+		render.JSON(w, r, resp.Err(r, resp.CodeInternal, "internal error"))
+		return
+	}
+
+	withAuthor := false
+	if include := r.URL.Query().Get("include"); include != "" {
+		for _, rel := range strings.Split(include, ",") {
+			switch strings.TrimSpace(rel) {
+			case includeAuthor:
+				withAuthor = true
+			default:
+				log.Debug("unknown include", slog.String("include", rel))
+				render.Status(r, http.StatusBadRequest)
+				render.JSON(w, r, resp.Err(r, resp.CodeValidationFailed, fmt.Sprintf("unknown include %q, supported: %s", rel, includeAuthor)))
+				return
+			}
+		}
+	}
+
+	fields, ok := pickFields(w, r, log)
+	if !ok {
 		return
 	}
 
+	// Drafts are private to their author, to anyone else they don't exist
+	viewerID, _ := jwt.UserID(r.Context())
+
 	// Send to service layer
-	artcl, err := a.service.GetByID(id)
+	var artcl *models.Article
+	var author *models.User
+	if withAuthor {
+		artcl, author, err = a.service.GetByIDWithAuthor(r.Context(), id, true, viewerID)
+	} else {
+		artcl, err = a.service.GetByID(r.Context(), id, true, viewerID)
+	}
 	if err != nil {
-		log.Error("failed to get article by id", sl.Error(err))
-		if errors.Is(err, article.ErrArticleNotFound) {
-			render.JSON(w, r, resp.Err("article not found"))
-			return
+		if !apperror.Render(w, r, err) {
+			log.Error("failed to get article by id", sl.Error(err))
 		}
-		render.JSON(w, r, resp.Err("internal error"))
 		return
 	}
 
-	var art []models.Article
-	art = append(art, *artcl)
+	// Send to service layer, the view only counts once the article is known to be visible
+	if err := a.service.View(r.Context(), id, viewerFingerprint(r)); err != nil {
+		// Failing to count a view shouldn't hide the article
+		log.Error("failed to count article view", sl.Error(err))
+	}
+
+	dto := resp.NewArticleDTO(artcl)
+	w.Header().Set("Content-Language", artcl.Language)
+	if artcl.CanonicalURL != "" {
+		w.Header().Set("Link", fmt.Sprintf("<%s>; rel=\"canonical\"", artcl.CanonicalURL))
+	}
+	if author != nil {
+		dto.Author = resp.NewUserDTO(*author)
+	}
 
 	// Write to response
-	render.JSON(w, r, resp.Response{
-		Status:   resp.StatusOk,
-		Articles: &art,
-	})
+	render.JSON(w, r, resp.SelectFields(resp.Response{
+		Status:  resp.StatusOk,
+		Article: dto,
+	}, fields))
+}
+
+// react sets the token user's reaction to the article and returns the updated counts,
+// an empty reaction removes it
+func (a *Article) react(reaction string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.article.react"
+
+		log := logger.FromContext(r.Context(), a.log).With(slog.String("op", op))
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			log.Error("failed to get \"id\" url param", sl.Error(err))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Err(r, resp.CodeValidationFailed, "invalid article id"))
+			return
+		}
+
+		userID, err := jwt.UserID(r.Context())
+		if err != nil {
+			log.Error("failed to get user id from token", sl.Error(err))
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, resp.Err(r, resp.CodeUnauthorized, "unauthorized"))
+			return
+		}
+
+		// Send to service layer
+		err = a.service.React(r.Context(), userID, id, reaction)
+		if err != nil {
+			if !apperror.Render(w, r, err) {
+				log.Error("failed to react to article", sl.Error(err))
+			}
+			return
+		}
+
+		// Send to service layer
+		art, err := a.service.GetByID(r.Context(), id, true, userID)
+		if err != nil {
+			log.Error("failed to get article by id", sl.Error(err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Err(r, resp.CodeInternal, "internal error"))
+			return
+		}
+
+		// Write to response
+		w.Header().Set("Content-Language", art.Language)
+		render.JSON(w, r, resp.Response{
+			Status:  resp.StatusOk,
+			Article: resp.NewArticleDTO(art),
+		})
+	}
 }
 
-// TODO: добавить проверку на уникальность заголовка статьи
 func (a *Article) update(w http.ResponseWriter, r *http.Request) {
 	const op = "handlers.article.update"
 
-	log := a.log.With(slog.String("op", op))
+	log := logger.FromContext(r.Context(), a.log).With(slog.String("op", op))
 
-	articleID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
 	if err != nil {
-		log.Error("failed to get \"id\" url param", sl.Error(err))
-		render.JSON(w, r, resp.Err("internal error"))
+		log.Debug("failed to get \"id\" url param", sl.Error(err))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, resp.Err(r, resp.CodeValidationFailed, "invalid article id"))
+		return
+	}
+
+	userID, role, err := requester(r)
+	if err != nil {
+		log.Error("failed to get requester from token", sl.Error(err))
+		render.Status(r, http.StatusUnauthorized)
+		render.JSON(w, r, resp.Err(r, resp.CodeUnauthorized, "unauthorized"))
 		return
 	}
 
-	var art models.Article
-	err = render.DecodeJSON(r.Body, &art)
+	var body req.Article
+	err = render.DecodeJSON(r.Body, &body)
 	if err != nil {
-		log.Error("failed to decode request", sl.Error(err))
-		render.JSON(w, r, resp.Err("internal error"))
+		decodeErr(w, r, log, err)
 		return
 	}
+	art := body.Model()
+
+	// Pass the id of the article by which it will be found in the database
+	art.ID = id
 
 	// Send to service layer
-	ar, err := a.service.GetByID(articleID)
+	version, err := a.service.Update(r.Context(), &art, userID, role)
 	if err != nil {
-		log.Error("failed to get article by id", sl.Error(err))
-		if errors.Is(err, storage.ErrArticleNotFound) {
-			render.JSON(w, r, resp.Err("article not found"))
+		// The current version lets the client merge and retry
+		if errors.Is(err, article.ErrVersionConflict) {
+			render.Status(r, http.StatusConflict)
+			render.JSON(w, r, resp.Response{
+				Status:  resp.StatusError,
+				Error:   resp.Message(r, resp.CodeVersionConflict, article.ErrVersionConflict.Error()),
+				Code:    resp.CodeVersionConflict,
+				Version: &version,
+			})
 			return
 		}
-		render.JSON(w, r, resp.Err("internal error"))
+		if !apperror.Render(w, r, err) {
+			log.Error("failed to update article", sl.Error(err))
+		}
 		return
 	}
 
-	satisfied, err := jwt.CheckClaim(r.Context(), "uid", strconv.Itoa(ar.AuthorID))
+	// Write to response
+	render.JSON(w, r, resp.Response{
+		Status:  resp.StatusOk,
+		Version: &version,
+	})
+}
+
+func (a *Article) pin(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.article.pin"
+
+	log := logger.FromContext(r.Context(), a.log).With(slog.String("op", op))
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
 	if err != nil {
-		log.Error("failed to check permission")
-		render.JSON(w, r, resp.Err("internal error"))
+		log.Debug("failed to get \"id\" url param", sl.Error(err))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, resp.Err(r, resp.CodeValidationFailed, "invalid article id"))
 		return
 	}
-	if !satisfied {
-		log.Error("user doesn't have permission")
-		render.JSON(w, r, resp.Err("not enough rights"))
+
+	userID, role, err := requester(r)
+	if err != nil {
+		log.Error("failed to get requester from token", sl.Error(err))
+		render.Status(r, http.StatusUnauthorized)
+		render.JSON(w, r, resp.Err(r, resp.CodeUnauthorized, "unauthorized"))
 		return
 	}
 
-	// Pass the id of the article by which it will be found in the database
-	art.ID = articleID
+	// Send to service layer
+	err = a.service.Pin(r.Context(), id, userID, role)
+	if err != nil {
+		if !apperror.Render(w, r, err) {
+			log.Error("failed to pin article", sl.Error(err))
+		}
+		return
+	}
+
+	// Write to response
+	render.JSON(w, r, resp.Response{
+		Status: resp.StatusOk,
+	})
+}
+
+func (a *Article) unpin(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.article.unpin"
+
+	log := logger.FromContext(r.Context(), a.log).With(slog.String("op", op))
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		log.Debug("failed to get \"id\" url param", sl.Error(err))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, resp.Err(r, resp.CodeValidationFailed, "invalid article id"))
+		return
+	}
+
+	userID, role, err := requester(r)
+	if err != nil {
+		log.Error("failed to get requester from token", sl.Error(err))
+		render.Status(r, http.StatusUnauthorized)
+		render.JSON(w, r, resp.Err(r, resp.CodeUnauthorized, "unauthorized"))
+		return
+	}
 
 	// Send to service layer
-	err = a.service.Update(&art)
+	err = a.service.Unpin(r.Context(), id, userID, role)
 	if err != nil {
-		log.Error("failed to update article", sl.Error(err))
-		render.JSON(w, r, resp.Err("internal error"))
+		if !apperror.Render(w, r, err) {
+			log.Error("failed to unpin article", sl.Error(err))
+		}
 		return
 	}
 
@@ -233,52 +778,145 @@ func (a *Article) update(w http.ResponseWriter, r *http.Request) {
 func (a *Article) remove(w http.ResponseWriter, r *http.Request) {
 	const op = "handlers.article.remove"
 
-	log := a.log.With(slog.String("op", op))
+	log := logger.FromContext(r.Context(), a.log).With(slog.String("op", op))
 
 	id, err := strconv.Atoi(chi.URLParam(r, "id"))
 	if err != nil {
-		log.Error("failed to get \"id\" url param", sl.Error(err))
-		render.JSON(w, r, resp.Err("internal error"))
+		log.Debug("failed to get \"id\" url param", sl.Error(err))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, resp.Err(r, resp.CodeValidationFailed, "invalid article id"))
+		return
+	}
+
+	userID, role, err := requester(r)
+	if err != nil {
+		log.Error("failed to get requester from token", sl.Error(err))
+		render.Status(r, http.StatusUnauthorized)
+		render.JSON(w, r, resp.Err(r, resp.CodeUnauthorized, "unauthorized"))
 		return
 	}
 
 	// Send to service layer
-	art, err := a.service.GetByID(id)
+	err = a.service.Remove(r.Context(), id, userID, role)
 	if err != nil {
-		log.Error("failed to get article by id", sl.Error(err))
-		if errors.Is(err, article.ErrArticleNotFound) {
-			render.JSON(w, r, resp.Err("article not found"))
+		if !apperror.Render(w, r, err) {
+			log.Error("failed to remove article", sl.Error(err))
 		}
-		render.JSON(w, r, resp.Err("internal error"))
 		return
 	}
 
-	satisfied, err := jwt.CheckClaim(r.Context(), "uid", strconv.Itoa(art.AuthorID))
+	// Write to response
+	render.JSON(w, r, resp.Response{
+		Status: resp.StatusOk,
+	})
+}
+
+// removeMany removes the token user's articles listed in the body and reports the result for each id
+func (a *Article) removeMany(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.article.removeMany"
+
+	log := logger.FromContext(r.Context(), a.log).With(slog.String("op", op))
+
+	userID, role, err := requester(r)
 	if err != nil {
-		log.Error("failed to check permission")
-		render.JSON(w, r, resp.Err("internal error"))
+		log.Error("failed to get requester from token", sl.Error(err))
+		render.Status(r, http.StatusUnauthorized)
+		render.JSON(w, r, resp.Err(r, resp.CodeUnauthorized, "unauthorized"))
 		return
 	}
-	if !satisfied {
-		log.Error("user doesn't have permission")
-		render.JSON(w, r, resp.Err("not enough rights"))
+
+	var bulk req.BulkDelete
+	err = render.DecodeJSON(r.Body, &bulk)
+	if err != nil {
+		log.Debug("failed to decode request", sl.Error(err))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, resp.Err(r, resp.CodeInvalidBody, "invalid request body"))
 		return
 	}
 
 	// Send to service layer
-	err = a.service.Remove(id)
+	results, err := a.service.RemoveMany(r.Context(), userID, role, bulk.IDs)
 	if err != nil {
-		log.Error("failed to remove article", sl.Error(err))
-		if errors.Is(err, article.ErrArticleNotFound) {
-			render.JSON(w, r, resp.Err("article not found"))
-			return
+		if !apperror.Render(w, r, err) {
+			log.Error("failed to remove articles", sl.Error(err))
 		}
-		render.JSON(w, r, resp.Err("internal error"))
 		return
 	}
 
+	deleted := countDeleted(results)
+
 	// Write to response
 	render.JSON(w, r, resp.Response{
-		Status: resp.StatusOk,
+		Status:  resp.StatusOk,
+		Deleted: &deleted,
+		Results: &results,
+	})
+}
+
+// countDeleted counts the articles a bulk removal deleted
+func countDeleted(results []models.RemovalResult) int {
+	deleted := 0
+	for _, res := range results {
+		if res.Result == models.RemovalDeleted {
+			deleted++
+		}
+	}
+	return deleted
+}
+
+func (a *Article) removeBulk(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.article.removeBulk"
+
+	log := logger.FromContext(r.Context(), a.log).With(slog.String("op", op))
+
+	id := chi.URLParam(r, "id")
+
+	userID, err := strconv.Atoi(id)
+	if err != nil {
+		log.Debug("failed to get \"id\" url param", sl.Error(err))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, resp.Err(r, resp.CodeValidationFailed, "invalid user id"))
+		return
+	}
+
+	// Checking user permission
+	satisfied, err := jwt.CheckClaim(r.Context(), "uid", id)
+	if err != nil {
+		log.Error("failed to check permission", sl.Error(err))
+		render.Status(r, http.StatusUnauthorized)
+		render.JSON(w, r, resp.Err(r, resp.CodeUnauthorized, "unauthorized"))
+		return
+	}
+	if !satisfied {
+		log.Debug("user doesn't have permission", slog.Int("user_id", userID))
+		render.Status(r, http.StatusForbidden)
+		render.JSON(w, r, resp.Err(r, resp.CodeForbidden, "not enough rights"))
+		return
+	}
+
+	var bulk req.BulkDelete
+	err = render.DecodeJSON(r.Body, &bulk)
+	if err != nil {
+		log.Debug("failed to decode request", sl.Error(err))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, resp.Err(r, resp.CodeInvalidBody, "invalid request body"))
+		return
+	}
+
+	// Send to service layer, only the user's own articles are removed even for an admin
+	results, err := a.service.RemoveMany(r.Context(), userID, models.RoleUser, bulk.IDs)
+	if err != nil {
+		if !apperror.Render(w, r, err) {
+			log.Error("failed to remove articles", sl.Error(err))
+		}
+		return
+	}
+
+	removed := countDeleted(results)
+
+	// Write to response
+	render.JSON(w, r, resp.Response{
+		Status:  resp.StatusOk,
+		Deleted: &removed,
 	})
 }
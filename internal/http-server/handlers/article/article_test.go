@@ -0,0 +1,383 @@
+package article_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"blog-api/internal/domain/models"
+	"blog-api/internal/events"
+	"blog-api/internal/http-server/handlers/article"
+	mw "blog-api/internal/http-server/middleware"
+	resp "blog-api/internal/lib/api/response"
+	"blog-api/internal/lib/jwt"
+	articleservice "blog-api/internal/service/article"
+	"blog-api/internal/testutil"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const userID = 5
+
+// newTestRouter serves the article routes with svc and returns a token of user userID
+func newTestRouter(t *testing.T, svc article.Service) (http.Handler, string) {
+	t.Helper()
+
+	keys, err := jwt.LoadKeys(jwt.HS256, "test secret", nil, "", "")
+	if err != nil {
+		t.Fatalf("failed to load keys: %v", err)
+	}
+	token, err := jwt.NewToken(models.User{ID: userID, Role: models.RoleUser}, 1, time.Hour, keys)
+	if err != nil {
+		t.Fatalf("failed to issue token: %v", err)
+	}
+
+	h := article.New(slog.New(slog.NewTextHandler(io.Discard, nil)), svc, mw.Verifier(keys), events.New())
+
+	r := chi.NewRouter()
+	r.Route("/articles", h.Register())
+
+	return r, token
+}
+
+// stored is the article the mocks serve
+func stored(_ context.Context, id int, _ bool, _ int) (*models.Article, error) {
+	return &models.Article{ID: id, Title: "Title", Content: "Content", Language: "en", AuthorID: userID, Version: 1}, nil
+}
+
+func TestHandlers(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		noToken    bool
+		svc        *testutil.ArticleService
+		wantStatus int
+		wantCode   string
+		// check asserts the body of a successful response
+		check func(t *testing.T, res resp.Response)
+	}{
+		{
+			name:   "create",
+			method: http.MethodPost,
+			path:   "/articles",
+			body:   `{"title":"Title","content":"Content"}`,
+			svc: &testutil.ArticleService{
+				CreateFunc: func(_ context.Context, art *models.Article) (int64, error) {
+					if art.AuthorID != userID {
+						return 0, fmt.Errorf("author = %d, want the token's user %d", art.AuthorID, userID)
+					}
+					return 1, nil
+				},
+				GetByIDFunc: stored,
+			},
+			wantStatus: http.StatusCreated,
+			check: func(t *testing.T, res resp.Response) {
+				if res.ID != 1 || res.Article == nil || res.Article.Title != "Title" {
+					t.Errorf("response = id %d, article %+v, want the created article 1", res.ID, res.Article)
+				}
+			},
+		},
+		{
+			name:       "create with broken JSON",
+			method:     http.MethodPost,
+			path:       "/articles",
+			body:       `{"title":`,
+			svc:        &testutil.ArticleService{},
+			wantStatus: http.StatusBadRequest,
+			wantCode:   resp.CodeInvalidBody,
+		},
+		{
+			name:       "create without title",
+			method:     http.MethodPost,
+			path:       "/articles",
+			body:       `{"content":"Content"}`,
+			svc:        &testutil.ArticleService{},
+			wantStatus: http.StatusBadRequest,
+			wantCode:   resp.CodeValidationFailed,
+		},
+		{
+			name:       "create with a non-numeric version",
+			method:     http.MethodPost,
+			path:       "/articles",
+			body:       `{"title":"Title","content":"Content","version":"abc"}`,
+			svc:        &testutil.ArticleService{},
+			wantStatus: http.StatusUnprocessableEntity,
+			wantCode:   resp.CodeValidationFailed,
+		},
+		{
+			name:   "create with a taken title",
+			method: http.MethodPost,
+			path:   "/articles",
+			body:   `{"title":"Title","content":"Content"}`,
+			svc: &testutil.ArticleService{
+				CreateFunc: func(context.Context, *models.Article) (int64, error) {
+					return 0, fmt.Errorf("service.article.Create: %w", articleservice.ErrArticleExists)
+				},
+			},
+			wantStatus: http.StatusConflict,
+			wantCode:   resp.CodeArticleExists,
+		},
+		{
+			name:       "create without token",
+			method:     http.MethodPost,
+			path:       "/articles",
+			body:       `{"title":"Title","content":"Content"}`,
+			noToken:    true,
+			svc:        &testutil.ArticleService{},
+			wantStatus: http.StatusUnauthorized,
+			wantCode:   resp.CodeUnauthorized,
+		},
+		{
+			name:   "get",
+			method: http.MethodGet,
+			path:   "/articles/1",
+			svc: &testutil.ArticleService{
+				ViewFunc:    func(context.Context, int, string) error { return nil },
+				GetByIDFunc: stored,
+			},
+			wantStatus: http.StatusOK,
+			check: func(t *testing.T, res resp.Response) {
+				if res.Article == nil || res.Article.ID != 1 || res.Article.Content != "Content" {
+					t.Errorf("article = %+v, want article 1", res.Article)
+				}
+			},
+		},
+		{
+			name:   "get missing",
+			method: http.MethodGet,
+			path:   "/articles/1",
+			svc: &testutil.ArticleService{
+				// Drafts of others are missing too, their views must not grow
+				GetByIDFunc: func(context.Context, int, bool, int) (*models.Article, error) {
+					return nil, fmt.Errorf("service.article.GetByID: %w", articleservice.ErrArticleNotFound)
+				},
+			},
+			wantStatus: http.StatusNotFound,
+			wantCode:   resp.CodeNotFound,
+		},
+		{
+			name:   "update",
+			method: http.MethodPut,
+			path:   "/articles/1",
+			body:   `{"title":"New title","version":1}`,
+			svc: &testutil.ArticleService{
+				UpdateFunc: func(_ context.Context, art *models.Article, requesterID int, _ string) (int, error) {
+					if art.ID != 1 || requesterID != userID {
+						return 0, fmt.Errorf("updated article %d for %d, want 1 for %d", art.ID, requesterID, userID)
+					}
+					return 2, nil
+				},
+			},
+			wantStatus: http.StatusOK,
+			check: func(t *testing.T, res resp.Response) {
+				if res.Version == nil || *res.Version != 2 {
+					t.Errorf("version = %v, want 2", res.Version)
+				}
+			},
+		},
+		{
+			name:       "update with broken JSON",
+			method:     http.MethodPut,
+			path:       "/articles/1",
+			body:       `not json`,
+			svc:        &testutil.ArticleService{},
+			wantStatus: http.StatusBadRequest,
+			wantCode:   resp.CodeInvalidBody,
+		},
+		{
+			name:   "update someone else's article",
+			method: http.MethodPut,
+			path:   "/articles/1",
+			body:   `{"title":"New title"}`,
+			svc: &testutil.ArticleService{
+				UpdateFunc: func(context.Context, *models.Article, int, string) (int, error) {
+					return 0, fmt.Errorf("service.article.Update: %w", articleservice.ErrForbidden)
+				},
+			},
+			wantStatus: http.StatusForbidden,
+			wantCode:   resp.CodeForbidden,
+		},
+		{
+			name:   "update with a stale version",
+			method: http.MethodPut,
+			path:   "/articles/1",
+			body:   `{"title":"New title","version":1}`,
+			svc: &testutil.ArticleService{
+				UpdateFunc: func(context.Context, *models.Article, int, string) (int, error) {
+					return 3, fmt.Errorf("service.article.Update: %w", articleservice.ErrVersionConflict)
+				},
+			},
+			wantStatus: http.StatusConflict,
+			wantCode:   resp.CodeVersionConflict,
+		},
+		{
+			name:       "update without token",
+			method:     http.MethodPut,
+			path:       "/articles/1",
+			body:       `{"title":"New title"}`,
+			noToken:    true,
+			svc:        &testutil.ArticleService{},
+			wantStatus: http.StatusUnauthorized,
+			wantCode:   resp.CodeUnauthorized,
+		},
+		{
+			name:   "remove",
+			method: http.MethodDelete,
+			path:   "/articles/1",
+			svc: &testutil.ArticleService{
+				RemoveFunc: func(context.Context, int, int, string) error { return nil },
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:   "remove missing",
+			method: http.MethodDelete,
+			path:   "/articles/1",
+			svc: &testutil.ArticleService{
+				RemoveFunc: func(context.Context, int, int, string) error {
+					return fmt.Errorf("service.article.Remove: %w", articleservice.ErrArticleNotFound)
+				},
+			},
+			wantStatus: http.StatusNotFound,
+			wantCode:   resp.CodeNotFound,
+		},
+		{
+			name:       "remove with an invalid id",
+			method:     http.MethodDelete,
+			path:       "/articles/abc",
+			svc:        &testutil.ArticleService{},
+			wantStatus: http.StatusBadRequest,
+			wantCode:   resp.CodeValidationFailed,
+		},
+		{
+			name:       "remove without token",
+			method:     http.MethodDelete,
+			path:       "/articles/1",
+			noToken:    true,
+			svc:        &testutil.ArticleService{},
+			wantStatus: http.StatusUnauthorized,
+			wantCode:   resp.CodeUnauthorized,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router, token := newTestRouter(t, tt.svc)
+
+			var body io.Reader
+			if tt.body != "" {
+				body = strings.NewReader(tt.body)
+			}
+			r := httptest.NewRequest(tt.method, tt.path, body)
+			if tt.body != "" {
+				r.Header.Set("Content-Type", "application/json")
+			}
+			if !tt.noToken {
+				r.Header.Set("Authorization", "Bearer "+token)
+			}
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, r)
+
+			var res resp.Response
+			if err := json.NewDecoder(w.Body).Decode(&res); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d (%s), want %d", w.Code, res.Error, tt.wantStatus)
+			}
+			if res.Code != tt.wantCode {
+				t.Errorf("code = %q, want %q", res.Code, tt.wantCode)
+			}
+			if tt.wantCode != "" && (res.Status != resp.StatusError || res.Error == "") {
+				t.Errorf("response = %+v, want an error with a message", res)
+			}
+			if tt.check != nil {
+				tt.check(t, res)
+			}
+		})
+	}
+}
+
+func TestInvalidFieldValue(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		field  string
+	}{
+		{name: "create with author_id abc", method: http.MethodPost, path: "/articles", body: `{"title":"Title","content":"Content","author_id":"abc"}`, field: "author_id"},
+		{name: "update with version abc", method: http.MethodPut, path: "/articles/1", body: `{"title":"Title","version":"abc"}`, field: "version"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router, token := newTestRouter(t, &testutil.ArticleService{})
+
+			r := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			r.Header.Set("Content-Type", "application/json")
+			r.Header.Set("Authorization", "Bearer "+token)
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, r)
+
+			var res resp.Response
+			if err := json.NewDecoder(w.Body).Decode(&res); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if w.Code != http.StatusUnprocessableEntity {
+				t.Fatalf("status = %d, want %d", w.Code, http.StatusUnprocessableEntity)
+			}
+			if !strings.Contains(res.Error, tt.field) {
+				t.Errorf("error = %q, want it to name %s", res.Error, tt.field)
+			}
+		})
+	}
+}
+
+func TestViewCountsVisibleArticles(t *testing.T) {
+	tests := []struct {
+		name       string
+		getErr     error
+		wantStatus int
+		wantViews  int
+	}{
+		{name: "visible article", wantStatus: http.StatusOK, wantViews: 1},
+		{name: "someone else's draft", getErr: fmt.Errorf("service.article.GetByID: %w", articleservice.ErrArticleNotFound), wantStatus: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var views int
+			router, _ := newTestRouter(t, &testutil.ArticleService{
+				GetByIDFunc: func(ctx context.Context, id int, visibleOnly bool, viewerID int) (*models.Article, error) {
+					if tt.getErr != nil {
+						return nil, tt.getErr
+					}
+					return stored(ctx, id, visibleOnly, viewerID)
+				},
+				ViewFunc: func(context.Context, int, string) error {
+					views++
+					return nil
+				},
+			})
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/articles/1", nil))
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if views != tt.wantViews {
+				t.Errorf("views counted = %d, want %d", views, tt.wantViews)
+			}
+		})
+	}
+}
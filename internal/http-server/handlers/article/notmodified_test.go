@@ -0,0 +1,91 @@
+package article
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNotModified(t *testing.T) {
+	tokyo := time.FixedZone("JST", 9*60*60)
+	lastMod := time.Date(2024, time.March, 1, 12, 30, 15, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		lastMod    time.Time
+		since      string
+		want       bool
+		wantHeader string
+		wantStatus int
+	}{
+		{
+			name:       "no If-Modified-Since",
+			lastMod:    lastMod,
+			wantHeader: "Fri, 01 Mar 2024 12:30:15 GMT",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "same second",
+			lastMod:    lastMod,
+			since:      "Fri, 01 Mar 2024 12:30:15 GMT",
+			want:       true,
+			wantHeader: "Fri, 01 Mar 2024 12:30:15 GMT",
+			wantStatus: http.StatusNotModified,
+		},
+		{
+			name:       "later within the same second",
+			lastMod:    lastMod.Add(999 * time.Millisecond),
+			since:      "Fri, 01 Mar 2024 12:30:15 GMT",
+			want:       true,
+			wantHeader: "Fri, 01 Mar 2024 12:30:15 GMT",
+			wantStatus: http.StatusNotModified,
+		},
+		{
+			name:       "a second later",
+			lastMod:    lastMod.Add(time.Second),
+			since:      "Fri, 01 Mar 2024 12:30:15 GMT",
+			wantHeader: "Fri, 01 Mar 2024 12:30:16 GMT",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "other time zone",
+			lastMod:    lastMod.In(tokyo),
+			since:      "Fri, 01 Mar 2024 12:30:15 GMT",
+			want:       true,
+			wantHeader: "Fri, 01 Mar 2024 12:30:15 GMT",
+			wantStatus: http.StatusNotModified,
+		},
+		{
+			name:       "malformed If-Modified-Since",
+			lastMod:    lastMod,
+			since:      "yesterday",
+			wantHeader: "Fri, 01 Mar 2024 12:30:15 GMT",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "never modified",
+			since:      "Fri, 01 Mar 2024 12:30:15 GMT",
+			wantStatus: http.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/articles", nil)
+			if tt.since != "" {
+				r.Header.Set("If-Modified-Since", tt.since)
+			}
+			w := httptest.NewRecorder()
+
+			if got := notModified(w, r, tt.lastMod); got != tt.want {
+				t.Errorf("notModified() = %v, want %v", got, tt.want)
+			}
+			if got := w.Header().Get("Last-Modified"); got != tt.wantHeader {
+				t.Errorf("Last-Modified = %q, want %q", got, tt.wantHeader)
+			}
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+		})
+	}
+}
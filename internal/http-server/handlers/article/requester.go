@@ -0,0 +1,23 @@
+package article
+
+import (
+	"net/http"
+
+	"blog-api/internal/lib/jwt"
+)
+
+// requester returns the id and role of the user the request's token was issued to.
+// Whether they may change an article is up to the service
+func requester(r *http.Request) (int, string, error) {
+	userID, err := jwt.UserID(r.Context())
+	if err != nil {
+		return 0, "", err
+	}
+
+	role, err := jwt.Role(r.Context())
+	if err != nil {
+		return 0, "", err
+	}
+
+	return userID, role, nil
+}
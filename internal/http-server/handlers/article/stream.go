@@ -0,0 +1,118 @@
+package article
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"blog-api/internal/domain/models"
+	"blog-api/internal/events"
+	resp "blog-api/internal/lib/api/response"
+	"blog-api/internal/lib/logger"
+	"blog-api/internal/lib/logger/sl"
+
+	"github.com/go-chi/render"
+)
+
+const (
+	// keepAliveInterval keeps proxies from closing an idle stream
+	keepAliveInterval = 15 * time.Second
+	previewLen        = 200
+)
+
+// Subscriber gives access to live article events
+type Subscriber interface {
+	Subscribe() (<-chan events.Event, func())
+}
+
+type articlePreview struct {
+	ID          int        `json:"id"`
+	Title       string     `json:"title"`
+	Preview     string     `json:"preview"`
+	PublishDate *time.Time `json:"publish_date,omitempty"`
+	AuthorID    int        `json:"author_id"`
+}
+
+func newArticlePreview(art models.Article) articlePreview {
+	preview := []rune(art.Content)
+	if len(preview) > previewLen {
+		preview = preview[:previewLen]
+	}
+
+	return articlePreview{
+		ID:          art.ID,
+		Title:       art.Title,
+		Preview:     string(preview),
+		PublishDate: art.PublishDate,
+		AuthorID:    art.AuthorID,
+	}
+}
+
+// stream pushes newly published articles to the client as server-sent events.
+// It ends when the client disconnects or the event bus is closed on shutdown
+func (a *Article) stream(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.article.stream"
+
+	log := logger.FromContext(r.Context(), a.log).With(slog.String("op", op))
+
+	rc := http.NewResponseController(w)
+
+	// The stream outlives the server write timeout
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		log.Error("failed to reset write deadline", sl.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, resp.Err(r, resp.CodeInternal, "internal error"))
+		return
+	}
+
+	sub, unsubscribe := a.events.Subscribe()
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		log.Error("failed to flush stream", sl.Error(err))
+		return
+	}
+
+	keepAlive := time.NewTicker(keepAliveInterval)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		case e, ok := <-sub:
+			if !ok {
+				return
+			}
+
+			art, ok := e.Data.(models.Article)
+			if !ok {
+				continue
+			}
+
+			data, err := json.Marshal(newArticlePreview(art))
+			if err != nil {
+				log.Error("failed to encode event", sl.Error(err))
+				continue
+			}
+
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.Type, data); err != nil {
+				return
+			}
+		}
+
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package article_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"blog-api/internal/domain/models"
+	"blog-api/internal/events"
+	"blog-api/internal/http-server/handlers/article"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// readEvent reads the next event of the stream, skipping keep-alive comments
+func readEvent(t *testing.T, r *bufio.Reader) (string, string) {
+	t.Helper()
+
+	var typ, data string
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("failed to read the stream: %v", err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+
+		switch {
+		case line == "" && typ != "":
+			return typ, data
+		case strings.HasPrefix(line, "event: "):
+			typ = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+}
+
+func TestStream(t *testing.T) {
+	bus := events.New()
+	noAuth := func(next http.Handler) http.Handler { return next }
+	h := article.New(slog.New(slog.NewTextHandler(io.Discard, nil)), nil, noAuth, bus)
+
+	router := chi.NewRouter()
+	router.Route("/articles", h.Register())
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+
+	res, err := http.Get(srv.URL + "/articles/stream")
+	if err != nil {
+		t.Fatalf("GET /articles/stream error = %v", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want %d", res.StatusCode, http.StatusOK)
+	}
+	if ct := res.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Content-Type = %q, want text/event-stream", ct)
+	}
+
+	// The handler subscribes before sending the headers, so nothing published now is lost
+	published := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	bus.Publish(events.Event{Type: events.ArticleCreated, Data: "not an article"})
+	bus.Publish(events.Event{Type: events.ArticleCreated, Data: models.Article{
+		ID:          7,
+		Title:       "Hello",
+		Content:     strings.Repeat("a", 300),
+		PublishDate: &published,
+		AuthorID:    3,
+	}})
+
+	body := bufio.NewReader(res.Body)
+	typ, data := readEvent(t, body)
+	if typ != events.ArticleCreated {
+		t.Errorf("event = %q, want %q", typ, events.ArticleCreated)
+	}
+
+	var preview struct {
+		ID          int       `json:"id"`
+		Title       string    `json:"title"`
+		Preview     string    `json:"preview"`
+		PublishDate time.Time `json:"publish_date"`
+		AuthorID    int       `json:"author_id"`
+	}
+	if err := json.Unmarshal([]byte(data), &preview); err != nil {
+		t.Fatalf("failed to decode event data %q: %v", data, err)
+	}
+	if preview.ID != 7 || preview.Title != "Hello" || preview.AuthorID != 3 || !preview.PublishDate.Equal(published) {
+		t.Errorf("event data = %+v, want article 7 by 3", preview)
+	}
+	if len(preview.Preview) != 200 {
+		t.Errorf("preview length = %d, want 200", len(preview.Preview))
+	}
+
+	// Closing the bus on shutdown ends the stream
+	bus.Close()
+	if rest, err := io.ReadAll(body); err != nil || len(rest) != 0 {
+		t.Errorf("after Close read %q, %v, want the stream to end", rest, err)
+	}
+}
@@ -0,0 +1,194 @@
+package attachment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+
+	"blog-api/internal/domain/models"
+	"blog-api/internal/http-server/apperror"
+	mw "blog-api/internal/http-server/middleware"
+	resp "blog-api/internal/lib/api/response"
+	"blog-api/internal/lib/jwt"
+	"blog-api/internal/lib/logger"
+	"blog-api/internal/lib/logger/sl"
+	"blog-api/internal/service/attachment"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+const (
+	// fileField is the multipart form field holding the upload
+	fileField = "file"
+	// multipartOverhead is allowed on top of the file size for part headers and boundaries
+	multipartOverhead = 64 << 10
+)
+
+type Service interface {
+	Upload(ctx context.Context, requesterID int, role string, articleID int, content io.Reader) (models.Attachment, error)
+	Open(ctx context.Context, sum string) (models.Attachment, *os.File, error)
+}
+
+type Attachment struct {
+	log      *slog.Logger
+	service  Service
+	verifier func(http.Handler) http.Handler
+}
+
+func New(log *slog.Logger, service Service, verifier func(http.Handler) http.Handler) *Attachment {
+	return &Attachment{
+		log:      log,
+		service:  service,
+		verifier: verifier,
+	}
+}
+
+// Register serves attachment content by its SHA256
+func (a *Attachment) Register() func(r chi.Router) {
+	return func(r chi.Router) {
+		r.Get("/{sha256}", a.get)
+	}
+}
+
+// RegisterByArticle serves uploads to one article,
+// it expects to be mounted under a route with the article "id" param
+func (a *Attachment) RegisterByArticle() func(r chi.Router) {
+	return func(r chi.Router) {
+		// Require auth
+		r.Group(func(r chi.Router) {
+			r.Use(a.verifier)
+			r.Use(mw.Authenticator)
+
+			r.Post("/", a.upload)
+		})
+	}
+}
+
+// upload attaches the "file" field of a multipart form to the article
+func (a *Attachment) upload(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.attachment.upload"
+
+	log := logger.FromContext(r.Context(), a.log).With(slog.String("op", op))
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		log.Debug("failed to get \"id\" url param", sl.Error(err))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, resp.Err(r, resp.CodeValidationFailed, "invalid article id"))
+		return
+	}
+
+	userID, err := jwt.UserID(r.Context())
+	if err != nil {
+		log.Error("failed to get user id from token", sl.Error(err))
+		render.Status(r, http.StatusUnauthorized)
+		render.JSON(w, r, resp.Err(r, resp.CodeUnauthorized, "unauthorized"))
+		return
+	}
+	role, err := jwt.Role(r.Context())
+	if err != nil {
+		log.Error("failed to get role from token", sl.Error(err))
+		render.Status(r, http.StatusUnauthorized)
+		render.JSON(w, r, resp.Err(r, resp.CodeUnauthorized, "unauthorized"))
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, attachment.MaxSize+multipartOverhead)
+
+	mr, err := r.MultipartReader()
+	if err != nil {
+		log.Debug("failed to read multipart body", sl.Error(err))
+		render.Status(r, http.StatusUnsupportedMediaType)
+		render.JSON(w, r, resp.Err(r, resp.CodeUnsupportedMedia, "content type must be multipart/form-data"))
+		return
+	}
+
+	var file io.Reader
+	for {
+		part, err := mr.NextPart()
+		if err != nil {
+			if a.tooLarge(w, r, err) {
+				return
+			}
+			if !errors.Is(err, io.EOF) {
+				log.Debug("failed to read multipart body", sl.Error(err))
+				render.Status(r, http.StatusBadRequest)
+				render.JSON(w, r, resp.Err(r, resp.CodeInvalidBody, "invalid request body"))
+				return
+			}
+			break
+		}
+		if part.FormName() == fileField {
+			file = part
+			break
+		}
+	}
+	if file == nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, resp.Err(r, resp.CodeValidationFailed, fmt.Sprintf("%q field is required", fileField)))
+		return
+	}
+
+	// Send to service layer
+	att, err := a.service.Upload(r.Context(), userID, role, id, file)
+	if err != nil {
+		if a.tooLarge(w, r, err) {
+			return
+		}
+		if !apperror.Render(w, r, err) {
+			log.Error("failed to upload attachment", sl.Error(err))
+		}
+		return
+	}
+	att.URL = mw.Path(r, "/attachments/"+att.SHA256)
+
+	// Write to response
+	w.Header().Set("Location", att.URL)
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, resp.Response{
+		Status:     resp.StatusOk,
+		ID:         att.ID,
+		Attachment: &att,
+	})
+}
+
+// get serves the content. It never changes for a sum, so it may be cached for good
+func (a *Attachment) get(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.attachment.get"
+
+	log := logger.FromContext(r.Context(), a.log).With(slog.String("op", op))
+
+	// Send to service layer
+	att, f, err := a.service.Open(r.Context(), chi.URLParam(r, "sha256"))
+	if err != nil {
+		if !apperror.Render(w, r, err) {
+			log.Error("failed to open attachment", sl.Error(err))
+		}
+		return
+	}
+	defer f.Close()
+
+	// Write to response
+	w.Header().Set("Content-Type", att.MimeType)
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	w.Header().Set("ETag", strconv.Quote(att.SHA256))
+	http.ServeContent(w, r, "", att.CreatedAt, f)
+}
+
+// tooLarge answers 413 if err is about the body exceeding its size limit, and reports whether it did
+func (a *Attachment) tooLarge(w http.ResponseWriter, r *http.Request, err error) bool {
+	var maxBytesErr *http.MaxBytesError
+	if !errors.As(err, &maxBytesErr) {
+		return false
+	}
+
+	apperror.Render(w, r, attachment.ErrTooLarge)
+
+	return true
+}
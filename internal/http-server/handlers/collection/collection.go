@@ -0,0 +1,370 @@
+package collection
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"blog-api/internal/domain/models"
+	"blog-api/internal/http-server/apperror"
+	mw "blog-api/internal/http-server/middleware"
+	req "blog-api/internal/lib/api/request"
+	resp "blog-api/internal/lib/api/response"
+	"blog-api/internal/lib/jwt"
+	"blog-api/internal/lib/logger"
+	"blog-api/internal/lib/logger/sl"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+type Service interface {
+	List(ctx context.Context, ownerID, requesterID int) ([]models.Collection, error)
+	Create(ctx context.Context, c models.Collection) (models.Collection, error)
+	Update(ctx context.Context, requesterID int, id int64, title string, description *string, isPublic *bool) (models.Collection, error)
+	Remove(ctx context.Context, requesterID int, id int64) error
+	AddArticle(ctx context.Context, requesterID int, id int64, articleID int) error
+	RemoveArticle(ctx context.Context, requesterID int, id int64, articleID int) error
+	Articles(ctx context.Context, requesterID int, id int64) (models.Collection, []models.Article, error)
+}
+
+type Collection struct {
+	log      *slog.Logger
+	service  Service
+	verifier func(http.Handler) http.Handler
+}
+
+func New(log *slog.Logger, service Service, verifier func(http.Handler) http.Handler) *Collection {
+	return &Collection{
+		log:      log,
+		service:  service,
+		verifier: verifier,
+	}
+}
+
+// Register serves reading lists. Public collections are readable by anyone,
+// private ones and all changes only by the owner
+func (c *Collection) Register() func(r chi.Router) {
+	return func(r chi.Router) {
+		r.Use(mw.RequireJSON)
+
+		// Public routes
+		r.Get("/", c.list)
+		r.Get("/{id}/articles", c.articles)
+
+		// Require auth
+		r.Group(func(r chi.Router) {
+			r.Use(c.verifier)
+			r.Use(mw.Authenticator)
+
+			r.Post("/", c.create)
+			r.Put("/{id}", c.update)
+			r.Delete("/{id}", c.remove)
+			r.Post("/{id}/articles", c.addArticle)
+			r.Delete("/{id}/articles/{aid}", c.removeArticle)
+		})
+	}
+}
+
+// list returns the collections of the "owner_id" query param, or of the token's user without it
+func (c *Collection) list(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.collection.list"
+
+	log := logger.FromContext(r.Context(), c.log).With(slog.String("op", op))
+
+	// Anonymous requests only see public collections
+	requesterID, _ := jwt.UserID(r.Context())
+
+	ownerID := requesterID
+	if o := r.URL.Query().Get("owner_id"); o != "" {
+		id, err := strconv.Atoi(o)
+		if err != nil {
+			log.Debug("failed to parse \"owner_id\" query param", sl.Error(err))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Err(r, resp.CodeValidationFailed, "invalid owner id"))
+			return
+		}
+		ownerID = id
+	}
+	if ownerID == 0 {
+		render.Status(r, http.StatusUnauthorized)
+		render.JSON(w, r, resp.Err(r, resp.CodeUnauthorized, "unauthorized"))
+		return
+	}
+
+	// Send to service layer
+	collections, err := c.service.List(r.Context(), ownerID, requesterID)
+	if err != nil {
+		if !apperror.Render(w, r, err) {
+			log.Error("failed to get collections", sl.Error(err))
+		}
+		return
+	}
+
+	// Write to response
+	render.JSON(w, r, resp.Response{
+		Status:      resp.StatusOk,
+		Collections: &collections,
+	})
+}
+
+func (c *Collection) create(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.collection.create"
+
+	log := logger.FromContext(r.Context(), c.log).With(slog.String("op", op))
+
+	userID, err := jwt.UserID(r.Context())
+	if err != nil {
+		log.Error("failed to get user id from token", sl.Error(err))
+		render.Status(r, http.StatusUnauthorized)
+		render.JSON(w, r, resp.Err(r, resp.CodeUnauthorized, "unauthorized"))
+		return
+	}
+
+	var body req.Collection
+	err = render.DecodeJSON(r.Body, &body)
+	if err != nil {
+		log.Debug("failed to decode request", sl.Error(err))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, resp.Err(r, resp.CodeInvalidBody, "invalid request body"))
+		return
+	}
+
+	coll := models.Collection{
+		OwnerID:  userID,
+		Title:    body.Title,
+		IsPublic: true,
+	}
+	if body.Description != nil {
+		coll.Description = *body.Description
+	}
+	if body.IsPublic != nil {
+		coll.IsPublic = *body.IsPublic
+	}
+
+	// Send to service layer
+	coll, err = c.service.Create(r.Context(), coll)
+	if err != nil {
+		if !apperror.Render(w, r, err) {
+			log.Error("failed to create collection", sl.Error(err))
+		}
+		return
+	}
+
+	// Write to response
+	w.Header().Set("Location", mw.Path(r, fmt.Sprintf("/collections/%d/articles", coll.ID)))
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, resp.Response{
+		Status:     resp.StatusOk,
+		ID:         coll.ID,
+		Collection: &coll,
+	})
+}
+
+func (c *Collection) update(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.collection.update"
+
+	log := logger.FromContext(r.Context(), c.log).With(slog.String("op", op))
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		log.Debug("failed to get \"id\" url param", sl.Error(err))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, resp.Err(r, resp.CodeValidationFailed, "invalid collection id"))
+		return
+	}
+
+	userID, err := jwt.UserID(r.Context())
+	if err != nil {
+		log.Error("failed to get user id from token", sl.Error(err))
+		render.Status(r, http.StatusUnauthorized)
+		render.JSON(w, r, resp.Err(r, resp.CodeUnauthorized, "unauthorized"))
+		return
+	}
+
+	var body req.Collection
+	err = render.DecodeJSON(r.Body, &body)
+	if err != nil {
+		log.Debug("failed to decode request", sl.Error(err))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, resp.Err(r, resp.CodeInvalidBody, "invalid request body"))
+		return
+	}
+
+	// Send to service layer
+	coll, err := c.service.Update(r.Context(), userID, id, body.Title, body.Description, body.IsPublic)
+	if err != nil {
+		if !apperror.Render(w, r, err) {
+			log.Error("failed to update collection", sl.Error(err))
+		}
+		return
+	}
+
+	// Write to response
+	render.JSON(w, r, resp.Response{
+		Status:     resp.StatusOk,
+		Collection: &coll,
+	})
+}
+
+func (c *Collection) remove(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.collection.remove"
+
+	log := logger.FromContext(r.Context(), c.log).With(slog.String("op", op))
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		log.Debug("failed to get \"id\" url param", sl.Error(err))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, resp.Err(r, resp.CodeValidationFailed, "invalid collection id"))
+		return
+	}
+
+	userID, err := jwt.UserID(r.Context())
+	if err != nil {
+		log.Error("failed to get user id from token", sl.Error(err))
+		render.Status(r, http.StatusUnauthorized)
+		render.JSON(w, r, resp.Err(r, resp.CodeUnauthorized, "unauthorized"))
+		return
+	}
+
+	// Send to service layer
+	err = c.service.Remove(r.Context(), userID, id)
+	if err != nil {
+		if !apperror.Render(w, r, err) {
+			log.Error("failed to remove collection", sl.Error(err))
+		}
+		return
+	}
+
+	// Write to response
+	render.JSON(w, r, resp.Response{
+		Status: resp.StatusOk,
+	})
+}
+
+func (c *Collection) addArticle(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.collection.addArticle"
+
+	log := logger.FromContext(r.Context(), c.log).With(slog.String("op", op))
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		log.Debug("failed to get \"id\" url param", sl.Error(err))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, resp.Err(r, resp.CodeValidationFailed, "invalid collection id"))
+		return
+	}
+
+	userID, err := jwt.UserID(r.Context())
+	if err != nil {
+		log.Error("failed to get user id from token", sl.Error(err))
+		render.Status(r, http.StatusUnauthorized)
+		render.JSON(w, r, resp.Err(r, resp.CodeUnauthorized, "unauthorized"))
+		return
+	}
+
+	var body req.CollectionArticle
+	err = render.DecodeJSON(r.Body, &body)
+	if err != nil {
+		log.Debug("failed to decode request", sl.Error(err))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, resp.Err(r, resp.CodeInvalidBody, "invalid request body"))
+		return
+	}
+
+	// Send to service layer
+	err = c.service.AddArticle(r.Context(), userID, id, body.ArticleID)
+	if err != nil {
+		if !apperror.Render(w, r, err) {
+			log.Error("failed to add article to collection", sl.Error(err))
+		}
+		return
+	}
+
+	// Write to response
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, resp.Response{
+		Status: resp.StatusOk,
+	})
+}
+
+func (c *Collection) removeArticle(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.collection.removeArticle"
+
+	log := logger.FromContext(r.Context(), c.log).With(slog.String("op", op))
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		log.Debug("failed to get \"id\" url param", sl.Error(err))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, resp.Err(r, resp.CodeValidationFailed, "invalid collection id"))
+		return
+	}
+
+	articleID, err := strconv.Atoi(chi.URLParam(r, "aid"))
+	if err != nil {
+		log.Debug("failed to get \"aid\" url param", sl.Error(err))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, resp.Err(r, resp.CodeValidationFailed, "invalid article id"))
+		return
+	}
+
+	userID, err := jwt.UserID(r.Context())
+	if err != nil {
+		log.Error("failed to get user id from token", sl.Error(err))
+		render.Status(r, http.StatusUnauthorized)
+		render.JSON(w, r, resp.Err(r, resp.CodeUnauthorized, "unauthorized"))
+		return
+	}
+
+	// Send to service layer
+	err = c.service.RemoveArticle(r.Context(), userID, id, articleID)
+	if err != nil {
+		if !apperror.Render(w, r, err) {
+			log.Error("failed to remove article from collection", sl.Error(err))
+		}
+		return
+	}
+
+	// Write to response
+	render.JSON(w, r, resp.Response{
+		Status: resp.StatusOk,
+	})
+}
+
+// articles returns the collection and its articles in order
+func (c *Collection) articles(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.collection.articles"
+
+	log := logger.FromContext(r.Context(), c.log).With(slog.String("op", op))
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		log.Debug("failed to get \"id\" url param", sl.Error(err))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, resp.Err(r, resp.CodeValidationFailed, "invalid collection id"))
+		return
+	}
+
+	// Anonymous requests only see public collections
+	requesterID, _ := jwt.UserID(r.Context())
+
+	// Send to service layer
+	coll, articles, err := c.service.Articles(r.Context(), requesterID, id)
+	if err != nil {
+		if !apperror.Render(w, r, err) {
+			log.Error("failed to get collection articles", sl.Error(err))
+		}
+		return
+	}
+
+	// Write to response
+	render.JSON(w, r, resp.Response{
+		Status:     resp.StatusOk,
+		Collection: &coll,
+		Articles:   &articles,
+	})
+}
@@ -0,0 +1,21 @@
+package fallback
+
+import (
+	"net/http"
+
+	resp "blog-api/internal/lib/api/response"
+
+	"github.com/go-chi/render"
+)
+
+// NotFound answers requests to unknown routes with the usual error envelope
+func NotFound(w http.ResponseWriter, r *http.Request) {
+	render.Status(r, http.StatusNotFound)
+	render.JSON(w, r, resp.Err(r, resp.CodeNotFound, "route not found"))
+}
+
+// MethodNotAllowed answers requests to known routes with an unsupported method
+func MethodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	render.Status(r, http.StatusMethodNotAllowed)
+	render.JSON(w, r, resp.Err(r, resp.CodeMethodNotAllowed, "method not allowed"))
+}
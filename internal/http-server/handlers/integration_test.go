@@ -0,0 +1,298 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"blog-api/internal/events"
+	"blog-api/internal/http-server/handlers/article"
+	"blog-api/internal/http-server/handlers/user"
+	mw "blog-api/internal/http-server/middleware"
+	resp "blog-api/internal/lib/api/response"
+	"blog-api/internal/lib/jwt"
+	email "blog-api/internal/notification"
+	articleservice "blog-api/internal/service/article"
+	notificationservice "blog-api/internal/service/notification"
+	sessionservice "blog-api/internal/service/session"
+	userservice "blog-api/internal/service/user"
+	webhookservice "blog-api/internal/service/webhook"
+	"blog-api/internal/storage/sqlite/sqlitetest"
+
+	"github.com/go-chi/chi/v5"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// newTestServer serves the user and article routes the way main wires them,
+// on top of a fresh in-memory database
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	storage := sqlitetest.New(t)
+
+	keys, err := jwt.LoadKeys(jwt.HS256, "test secret", nil, "", "")
+	if err != nil {
+		t.Fatalf("failed to load keys: %v", err)
+	}
+	verifier := mw.Verifier(keys)
+
+	usrService := userservice.New(log, storage, time.Hour, time.Hour, keys, 0, bcrypt.MinCost, userservice.Lockout{
+		MaxFailures: 10,
+		Duration:    time.Minute,
+	}, email.NullNotifier{})
+	artService := articleservice.New(log, storage, notificationservice.New(log, storage), events.New(), webhookservice.New(log, storage), email.NullNotifier{}, false)
+	sesService := sessionservice.New(log, storage)
+
+	r := chi.NewRouter()
+	r.Use(verifier)
+	r.Use(mw.ActiveSession(sesService.Active))
+	r.Route("/users", user.New(log, usrService, verifier).Register())
+	r.Route("/articles", article.New(log, artService, verifier, events.New()).Register())
+
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+// call sends body as JSON with the token, if any, and decodes the response
+func call(t *testing.T, srv *httptest.Server, method, path, token string, body any) (int, resp.Response) {
+	t.Helper()
+
+	var reqBody io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			t.Fatalf("failed to encode body: %v", err)
+		}
+		reqBody = bytes.NewReader(data)
+	}
+
+	r, err := http.NewRequest(method, srv.URL+path, reqBody)
+	if err != nil {
+		t.Fatalf("failed to build request: %v", err)
+	}
+	if body != nil {
+		r.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	res, err := srv.Client().Do(r)
+	if err != nil {
+		t.Fatalf("%s %s error = %v", method, path, err)
+	}
+	defer res.Body.Close()
+
+	var response resp.Response
+	if err := json.NewDecoder(res.Body).Decode(&response); err != nil && err != io.EOF {
+		t.Fatalf("%s %s: failed to decode response: %v", method, path, err)
+	}
+
+	return res.StatusCode, response
+}
+
+// registerAndLogin registers a user and returns its id and a token
+func registerAndLogin(t *testing.T, srv *httptest.Server, name string) (int64, string) {
+	t.Helper()
+
+	creds := map[string]string{"user_name": name, "password": "password of " + name}
+
+	status, res := call(t, srv, http.MethodPost, "/users/register", "", creds)
+	if status != http.StatusCreated {
+		t.Fatalf("register %q: status = %d (%s), want %d", name, status, res.Error, http.StatusCreated)
+	}
+
+	status, login := call(t, srv, http.MethodPost, "/users/login", "", creds)
+	if status != http.StatusOK || login.Token == "" {
+		t.Fatalf("login %q: status = %d (%s), want %d with a token", name, status, login.Error, http.StatusOK)
+	}
+
+	return res.ID, login.Token
+}
+
+func TestUserFlow(t *testing.T) {
+	srv := newTestServer(t)
+
+	id, _ := registerAndLogin(t, srv, "alice")
+
+	status, res := call(t, srv, http.MethodGet, fmt.Sprintf("/users/%d", id), "", nil)
+	if status != http.StatusOK {
+		t.Fatalf("GET /users/%d status = %d, want %d", id, status, http.StatusOK)
+	}
+	if res.User == nil || res.User.ID != id || res.User.UserName != "alice" {
+		t.Errorf("GET /users/%d user = %+v, want alice", id, res.User)
+	}
+
+	status, res = call(t, srv, http.MethodPost, "/users/register", "", map[string]string{"user_name": "alice", "password": "another"})
+	if status != http.StatusConflict || res.Code != resp.CodeUserExists {
+		t.Errorf("register a taken name: status, code = %d, %q, want %d, %q", status, res.Code, http.StatusConflict, resp.CodeUserExists)
+	}
+
+	status, res = call(t, srv, http.MethodPost, "/users/login", "", map[string]string{"user_name": "alice", "password": "wrong"})
+	if status != http.StatusUnauthorized || res.Token != "" {
+		t.Errorf("login with a wrong password: status = %d, token %q, want %d and no token", status, res.Token, http.StatusUnauthorized)
+	}
+
+	status, _ = call(t, srv, http.MethodGet, "/users/999", "", nil)
+	if status != http.StatusNotFound {
+		t.Errorf("GET /users/999 status = %d, want %d", status, http.StatusNotFound)
+	}
+}
+
+func TestArticleFlow(t *testing.T) {
+	srv := newTestServer(t)
+
+	authorID, token := registerAndLogin(t, srv, "author")
+
+	// Create
+	status, res := call(t, srv, http.MethodPost, "/articles", token, map[string]string{
+		"title":   "First",
+		"content": "Hello",
+		"status":  "published",
+	})
+	if status != http.StatusCreated || res.ID == 0 {
+		t.Fatalf("create status = %d (%s), want %d with an id", status, res.Error, http.StatusCreated)
+	}
+	path := fmt.Sprintf("/articles/%d", res.ID)
+
+	// Get
+	status, res = call(t, srv, http.MethodGet, path, "", nil)
+	if status != http.StatusOK {
+		t.Fatalf("GET %s status = %d, want %d", path, status, http.StatusOK)
+	}
+	if res.Article == nil || res.Article.Title != "First" || res.Article.Content != "Hello" || int64(res.Article.AuthorID) != authorID {
+		t.Fatalf("GET %s article = %+v, want the created one", path, res.Article)
+	}
+
+	// Update
+	status, res = call(t, srv, http.MethodPut, path, token, map[string]any{"title": "Renamed", "version": res.Article.Version})
+	if status != http.StatusOK {
+		t.Fatalf("update status = %d (%s), want %d", status, res.Error, http.StatusOK)
+	}
+	_, res = call(t, srv, http.MethodGet, path, "", nil)
+	if res.Article == nil || res.Article.Title != "Renamed" || res.Article.Content != "Hello" {
+		t.Errorf("after update article = %+v, want the new title and the old content", res.Article)
+	}
+
+	// Delete
+	status, res = call(t, srv, http.MethodDelete, path, token, nil)
+	if status != http.StatusOK {
+		t.Fatalf("delete status = %d (%s), want %d", status, res.Error, http.StatusOK)
+	}
+	status, _ = call(t, srv, http.MethodGet, path, "", nil)
+	if status != http.StatusNotFound {
+		t.Errorf("GET %s after delete status = %d, want %d", path, status, http.StatusNotFound)
+	}
+}
+
+func TestArticleAccessDenied(t *testing.T) {
+	srv := newTestServer(t)
+
+	_, authorToken := registerAndLogin(t, srv, "author")
+	_, otherToken := registerAndLogin(t, srv, "other")
+
+	status, res := call(t, srv, http.MethodPost, "/articles", authorToken, map[string]string{"title": "Mine", "content": "Hello", "status": "published"})
+	if status != http.StatusCreated {
+		t.Fatalf("create status = %d (%s), want %d", status, res.Error, http.StatusCreated)
+	}
+	path := fmt.Sprintf("/articles/%d", res.ID)
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		token      string
+		body       any
+		wantStatus int
+	}{
+		{name: "create without token", method: http.MethodPost, path: "/articles", body: map[string]string{"title": "t", "content": "c"}, wantStatus: http.StatusUnauthorized},
+		{name: "create with a forged token", method: http.MethodPost, path: "/articles", token: "not.a.token", body: map[string]string{"title": "t", "content": "c"}, wantStatus: http.StatusUnauthorized},
+		{name: "update without token", method: http.MethodPut, path: path, body: map[string]string{"title": "t"}, wantStatus: http.StatusUnauthorized},
+		{name: "delete without token", method: http.MethodDelete, path: path, wantStatus: http.StatusUnauthorized},
+		{name: "update by another user", method: http.MethodPut, path: path, token: otherToken, body: map[string]string{"title": "Stolen"}, wantStatus: http.StatusForbidden},
+		{name: "delete by another user", method: http.MethodDelete, path: path, token: otherToken, wantStatus: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, _ := call(t, srv, tt.method, tt.path, tt.token, tt.body)
+			if status != tt.wantStatus {
+				t.Errorf("%s %s status = %d, want %d", tt.method, tt.path, status, tt.wantStatus)
+			}
+		})
+	}
+
+	// Nothing was changed by the denied requests
+	_, res = call(t, srv, http.MethodGet, path, "", nil)
+	if res.Article == nil || res.Article.Title != "Mine" {
+		t.Errorf("article after denied requests = %+v, want it unchanged", res.Article)
+	}
+}
+
+func TestRenameKeepsStatus(t *testing.T) {
+	srv := newTestServer(t)
+
+	id, token := registerAndLogin(t, srv, "alice")
+	path := fmt.Sprintf("/users/%d", id)
+
+	status, res := call(t, srv, http.MethodPut, path, token, map[string]string{"status": "writing"})
+	if status != http.StatusOK {
+		t.Fatalf("set status: status = %d (%s), want %d", status, res.Error, http.StatusOK)
+	}
+
+	// A body without status must leave it alone, not clear it
+	status, res = call(t, srv, http.MethodPut, path, token, map[string]string{"user_name": "alice2"})
+	if status != http.StatusOK {
+		t.Fatalf("rename: status = %d (%s), want %d", status, res.Error, http.StatusOK)
+	}
+
+	_, res = call(t, srv, http.MethodGet, path, "", nil)
+	if res.User == nil || res.User.UserName != "alice2" || res.User.Status != "writing" {
+		t.Errorf("after rename user = %+v, want alice2 with status %q", res.User, "writing")
+	}
+}
+
+func TestRenameToTakenName(t *testing.T) {
+	srv := newTestServer(t)
+
+	registerAndLogin(t, srv, "alice")
+	id, token := registerAndLogin(t, srv, "bob")
+	path := fmt.Sprintf("/users/%d", id)
+
+	for _, name := range []string{"alice", "ALICE"} {
+		status, res := call(t, srv, http.MethodPut, path, token, map[string]string{"user_name": name})
+		if status != http.StatusConflict || res.Code != resp.CodeUserExists {
+			t.Errorf("rename to %q: status, code = %d, %q, want %d, %q", name, status, res.Code, http.StatusConflict, resp.CodeUserExists)
+		}
+	}
+
+	_, res := call(t, srv, http.MethodGet, path, "", nil)
+	if res.User == nil || res.User.UserName != "bob" {
+		t.Errorf("after failed renames user = %+v, want bob", res.User)
+	}
+}
+
+func TestDeletedUserToken(t *testing.T) {
+	srv := newTestServer(t)
+
+	id, token := registerAndLogin(t, srv, "alice")
+
+	status, res := call(t, srv, http.MethodDelete, fmt.Sprintf("/users/%d", id), token, nil)
+	if status != http.StatusOK {
+		t.Fatalf("delete self: status = %d (%s), want %d", status, res.Error, http.StatusOK)
+	}
+
+	// The token is still signed and unexpired, but its session went with the user
+	status, res = call(t, srv, http.MethodPost, "/articles", token, map[string]string{"title": "Ghost", "content": "Hello"})
+	if status != http.StatusUnauthorized {
+		t.Errorf("create with the deleted user's token: status = %d (%s), want %d", status, res.Error, http.StatusUnauthorized)
+	}
+}
@@ -0,0 +1,91 @@
+package newsletter
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"blog-api/internal/domain/models"
+	"blog-api/internal/http-server/apperror"
+	mw "blog-api/internal/http-server/middleware"
+	req "blog-api/internal/lib/api/request"
+	resp "blog-api/internal/lib/api/response"
+	"blog-api/internal/lib/logger"
+	"blog-api/internal/lib/logger/sl"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+// TokenHeader carries the newsletter token of machine clients
+const TokenHeader = "X-Newsletter-Token"
+
+type Service interface {
+	Digest(ctx context.Context, from, to time.Time) (models.Digest, error)
+}
+
+type Newsletter struct {
+	log      *slog.Logger
+	service  Service
+	verifier func(http.Handler) http.Handler
+	token    string
+}
+
+// New takes the static token machine clients may send in TokenHeader instead of an admin token,
+// empty when they aren't served
+func New(log *slog.Logger, service Service, verifier func(http.Handler) http.Handler, token string) *Newsletter {
+	return &Newsletter{
+		log:      log,
+		service:  service,
+		verifier: verifier,
+		token:    token,
+	}
+}
+
+func (n *Newsletter) Register() func(r chi.Router) {
+	return func(r chi.Router) {
+		// Require admin or the newsletter token
+		r.Use(n.verifier)
+		r.Use(mw.RequireAdminOrKey(TokenHeader, n.token))
+
+		r.Get("/digest", n.digest)
+	}
+}
+
+// digest returns the articles published between the required "from" and "to" dates, grouped by author
+func (n *Newsletter) digest(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.newsletter.digest"
+
+	log := logger.FromContext(r.Context(), n.log).With(slog.String("op", op))
+
+	q := r.URL.Query()
+	if q.Get("from") == "" || q.Get("to") == "" {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, resp.Err(r, resp.CodeValidationFailed, "from and to dates are required"))
+		return
+	}
+
+	from, to, err := req.DateRange(r)
+	if err != nil {
+		log.Debug("invalid date range", sl.Error(err))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, resp.Err(r, resp.CodeValidationFailed, err.Error()))
+		return
+	}
+
+	// Send to service layer
+	digest, err := n.service.Digest(r.Context(), from, to)
+	if err != nil {
+		if !apperror.Render(w, r, err) {
+			log.Error("failed to get digest", sl.Error(err))
+		}
+		return
+	}
+
+	// Write to response
+	render.JSON(w, r, resp.Response{
+		Status: resp.StatusOk,
+		Digest: &digest,
+	})
+}
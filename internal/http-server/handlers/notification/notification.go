@@ -0,0 +1,209 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"blog-api/internal/domain/models"
+	"blog-api/internal/http-server/apperror"
+	mw "blog-api/internal/http-server/middleware"
+	req "blog-api/internal/lib/api/request"
+	resp "blog-api/internal/lib/api/response"
+	"blog-api/internal/lib/jwt"
+	"blog-api/internal/lib/logger"
+	"blog-api/internal/lib/logger/sl"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+// me in place of the user id stands for the token's user
+const me = "me"
+
+type Service interface {
+	List(ctx context.Context, userID int, unreadOnly bool, limit, offset int) ([]models.Notification, int, error)
+	MarkRead(ctx context.Context, userID int, ids []int64) (int64, error)
+	MarkAllRead(ctx context.Context, userID int) (int64, error)
+}
+
+type Notification struct {
+	log      *slog.Logger
+	service  Service
+	verifier func(http.Handler) http.Handler
+}
+
+func New(log *slog.Logger, service Service, verifier func(http.Handler) http.Handler) *Notification {
+	return &Notification{
+		log:      log,
+		service:  service,
+		verifier: verifier,
+	}
+}
+
+// Register expects to be mounted under a route with the user "id" param,
+// the param may be "me"
+func (n *Notification) Register() func(r chi.Router) {
+	return func(r chi.Router) {
+		// Require auth
+		r.Use(n.verifier)
+		r.Use(mw.Authenticator)
+
+		r.Get("/", n.list)
+		r.Post("/read", n.markRead)
+		r.Put("/read-all", n.markAllRead)
+	}
+}
+
+func (n *Notification) list(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.notification.list"
+
+	log := logger.FromContext(r.Context(), n.log).With(slog.String("op", op))
+
+	userID, ok := n.owner(w, r, log)
+	if !ok {
+		return
+	}
+
+	limit, offset, err := req.Pagination(r)
+	if err != nil {
+		log.Debug("invalid pagination params", sl.Error(err))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, resp.Err(r, resp.CodeValidationFailed, err.Error()))
+		return
+	}
+
+	unreadOnly := false
+	if u := r.URL.Query().Get("unread"); u != "" {
+		unreadOnly, err = strconv.ParseBool(u)
+		if err != nil {
+			log.Debug("invalid \"unread\" query param", sl.Error(err))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Err(r, resp.CodeValidationFailed, "invalid unread: must be true or false"))
+			return
+		}
+	}
+
+	// Send to service layer
+	notifications, unread, err := n.service.List(r.Context(), userID, unreadOnly, limit, offset)
+	if err != nil {
+		log.Error("failed to get notifications", sl.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, resp.Err(r, resp.CodeInternal, "internal error"))
+		return
+	}
+
+	// Write to response
+	render.JSON(w, r, resp.Response{
+		Status:        resp.StatusOk,
+		Notifications: &notifications,
+		Unread:        &unread,
+	})
+}
+
+func (n *Notification) markRead(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.notification.markRead"
+
+	log := logger.FromContext(r.Context(), n.log).With(slog.String("op", op))
+
+	userID, ok := n.owner(w, r, log)
+	if !ok {
+		return
+	}
+
+	// An empty body marks everything as read
+	var mark req.MarkRead
+	err := render.DecodeJSON(r.Body, &mark)
+	if err != nil && !errors.Is(err, io.EOF) {
+		log.Debug("failed to decode request", sl.Error(err))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, resp.Err(r, resp.CodeInvalidBody, "invalid request body"))
+		return
+	}
+
+	// Send to service layer
+	marked, err := n.service.MarkRead(r.Context(), userID, mark.IDs)
+	if err != nil {
+		if !apperror.Render(w, r, err) {
+			log.Error("failed to mark notifications as read", sl.Error(err))
+		}
+		return
+	}
+
+	// Write to response
+	render.JSON(w, r, resp.Response{
+		Status: resp.StatusOk,
+		Marked: &marked,
+	})
+}
+
+func (n *Notification) markAllRead(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.notification.markAllRead"
+
+	log := logger.FromContext(r.Context(), n.log).With(slog.String("op", op))
+
+	userID, ok := n.owner(w, r, log)
+	if !ok {
+		return
+	}
+
+	// Send to service layer
+	marked, err := n.service.MarkAllRead(r.Context(), userID)
+	if err != nil {
+		log.Error("failed to mark notifications as read", sl.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, resp.Err(r, resp.CodeInternal, "internal error"))
+		return
+	}
+
+	// Write to response
+	render.JSON(w, r, resp.Response{
+		Status: resp.StatusOk,
+		Marked: &marked,
+	})
+}
+
+// owner resolves the user "id" param and checks that it belongs to the token's user.
+// On failure the error response is already written
+func (n *Notification) owner(w http.ResponseWriter, r *http.Request, log *slog.Logger) (int, bool) {
+	id := chi.URLParam(r, "id")
+
+	if id == me {
+		userID, err := jwt.UserID(r.Context())
+		if err != nil {
+			log.Error("failed to get user id from token", sl.Error(err))
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, resp.Err(r, resp.CodeUnauthorized, "unauthorized"))
+			return 0, false
+		}
+		return userID, true
+	}
+
+	userID, err := strconv.Atoi(id)
+	if err != nil {
+		log.Debug("failed to get \"id\" url param", sl.Error(err))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, resp.Err(r, resp.CodeValidationFailed, "invalid user id"))
+		return 0, false
+	}
+
+	// Checking user permission
+	satisfied, err := jwt.CheckClaim(r.Context(), "uid", id)
+	if err != nil {
+		log.Error("failed to check permission", sl.Error(err))
+		render.Status(r, http.StatusUnauthorized)
+		render.JSON(w, r, resp.Err(r, resp.CodeUnauthorized, "unauthorized"))
+		return 0, false
+	}
+	if !satisfied {
+		log.Debug("user doesn't have permission", slog.Int("user_id", userID))
+		render.Status(r, http.StatusForbidden)
+		render.JSON(w, r, resp.Err(r, resp.CodeForbidden, "not enough rights"))
+		return 0, false
+	}
+
+	return userID, true
+}
@@ -0,0 +1,115 @@
+package session
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"blog-api/internal/domain/models"
+	"blog-api/internal/http-server/apperror"
+	mw "blog-api/internal/http-server/middleware"
+	resp "blog-api/internal/lib/api/response"
+	"blog-api/internal/lib/jwt"
+	"blog-api/internal/lib/logger"
+	"blog-api/internal/lib/logger/sl"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+type Service interface {
+	List(ctx context.Context, userID int) ([]models.Session, error)
+	Revoke(ctx context.Context, userID int, sid int64) error
+}
+
+type Session struct {
+	log      *slog.Logger
+	service  Service
+	verifier func(http.Handler) http.Handler
+}
+
+func New(log *slog.Logger, service Service, verifier func(http.Handler) http.Handler) *Session {
+	return &Session{
+		log:      log,
+		service:  service,
+		verifier: verifier,
+	}
+}
+
+// Register serves the sessions of the token's user
+func (s *Session) Register() func(r chi.Router) {
+	return func(r chi.Router) {
+		// Require auth
+		r.Use(s.verifier)
+		r.Use(mw.Authenticator)
+
+		r.Get("/", s.list)
+		r.Delete("/{id}", s.revoke)
+	}
+}
+
+func (s *Session) list(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.session.list"
+
+	log := logger.FromContext(r.Context(), s.log).With(slog.String("op", op))
+
+	userID, err := jwt.UserID(r.Context())
+	if err != nil {
+		log.Error("failed to get user id from token", sl.Error(err))
+		render.Status(r, http.StatusUnauthorized)
+		render.JSON(w, r, resp.Err(r, resp.CodeUnauthorized, "unauthorized"))
+		return
+	}
+
+	// Send to service layer
+	sessions, err := s.service.List(r.Context(), userID)
+	if err != nil {
+		log.Error("failed to get sessions", sl.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, resp.Err(r, resp.CodeInternal, "internal error"))
+		return
+	}
+
+	// Write to response
+	render.JSON(w, r, resp.Response{
+		Status:   resp.StatusOk,
+		Sessions: &sessions,
+	})
+}
+
+func (s *Session) revoke(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.session.revoke"
+
+	log := logger.FromContext(r.Context(), s.log).With(slog.String("op", op))
+
+	sid, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		log.Debug("failed to get \"id\" url param", sl.Error(err))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, resp.Err(r, resp.CodeValidationFailed, "invalid session id"))
+		return
+	}
+
+	userID, err := jwt.UserID(r.Context())
+	if err != nil {
+		log.Error("failed to get user id from token", sl.Error(err))
+		render.Status(r, http.StatusUnauthorized)
+		render.JSON(w, r, resp.Err(r, resp.CodeUnauthorized, "unauthorized"))
+		return
+	}
+
+	// Send to service layer
+	err = s.service.Revoke(r.Context(), userID, sid)
+	if err != nil {
+		if !apperror.Render(w, r, err) {
+			log.Error("failed to revoke session", sl.Error(err))
+		}
+		return
+	}
+
+	// Write to response
+	render.JSON(w, r, resp.Response{
+		Status: resp.StatusOk,
+	})
+}
@@ -0,0 +1,150 @@
+package sitemap
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"blog-api/internal/http-server/apperror"
+	resp "blog-api/internal/lib/api/response"
+	"blog-api/internal/lib/logger"
+	"blog-api/internal/lib/logger/sl"
+	"blog-api/internal/service/sitemap"
+
+	"github.com/go-chi/render"
+)
+
+const xmlns = "http://www.sitemaps.org/schemas/sitemap/0.9"
+
+type Service interface {
+	Pages(ctx context.Context) (int, error)
+	Page(ctx context.Context, n int) ([]sitemap.Entry, error)
+}
+
+type Sitemap struct {
+	log      *slog.Logger
+	service  Service
+	baseURL  string
+	basePath string
+}
+
+// New takes the public address of the API and the prefix it's served under,
+// the sitemap itself is expected at the root of baseURL
+func New(log *slog.Logger, service Service, baseURL, basePath string) *Sitemap {
+	return &Sitemap{
+		log:      log,
+		service:  service,
+		baseURL:  strings.TrimRight(baseURL, "/"),
+		basePath: basePath,
+	}
+}
+
+type urlSet struct {
+	XMLName xml.Name `xml:"urlset"`
+	Xmlns   string   `xml:"xmlns,attr"`
+	URLs    []url    `xml:"url"`
+}
+
+type url struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+type sitemapIndex struct {
+	XMLName  xml.Name     `xml:"sitemapindex"`
+	Xmlns    string       `xml:"xmlns,attr"`
+	Sitemaps []sitemapRef `xml:"sitemap"`
+}
+
+type sitemapRef struct {
+	Loc string `xml:"loc"`
+}
+
+// Get serves the sitemap. When the entries don't fit in one sitemap
+// it serves a sitemap index pointing to /sitemap.xml?page=N instead
+func (s *Sitemap) Get(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.sitemap.Get"
+
+	log := logger.FromContext(r.Context(), s.log).With(slog.String("op", op))
+
+	page := 1
+	if p := r.URL.Query().Get("page"); p != "" {
+		var err error
+		page, err = strconv.Atoi(p)
+		if err != nil {
+			log.Debug("invalid \"page\" query param", sl.Error(err))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Err(r, resp.CodeValidationFailed, "invalid page"))
+			return
+		}
+	} else {
+		// Send to service layer
+		pages, err := s.service.Pages(r.Context())
+		if err != nil {
+			log.Error("failed to count sitemap pages", sl.Error(err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Err(r, resp.CodeInternal, "internal error"))
+			return
+		}
+
+		if pages > 1 {
+			s.writeXML(w, log, s.index(pages))
+			return
+		}
+	}
+
+	// Send to service layer
+	entries, err := s.service.Page(r.Context(), page)
+	if err != nil {
+		if !apperror.Render(w, r, err) {
+			log.Error("failed to get sitemap entries", sl.Error(err))
+		}
+		return
+	}
+
+	set := urlSet{
+		Xmlns: xmlns,
+		URLs:  make([]url, 0, len(entries)),
+	}
+	for _, e := range entries {
+		u := url{Loc: s.baseURL + s.basePath + e.Path}
+		if e.LastMod != nil {
+			u.LastMod = e.LastMod.UTC().Format(time.DateOnly)
+		}
+		set.URLs = append(set.URLs, u)
+	}
+
+	s.writeXML(w, log, set)
+}
+
+func (s *Sitemap) index(pages int) sitemapIndex {
+	idx := sitemapIndex{
+		Xmlns:    xmlns,
+		Sitemaps: make([]sitemapRef, 0, pages),
+	}
+	for i := 1; i <= pages; i++ {
+		idx.Sitemaps = append(idx.Sitemaps, sitemapRef{
+			Loc: fmt.Sprintf("%s/sitemap.xml?page=%d", s.baseURL, i),
+		})
+	}
+
+	return idx
+}
+
+func (s *Sitemap) writeXML(w http.ResponseWriter, log *slog.Logger, v any) {
+	data, err := xml.Marshal(v)
+	if err != nil {
+		log.Error("failed to encode sitemap", sl.Error(err))
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.Write([]byte(xml.Header))
+	w.Write(data)
+}
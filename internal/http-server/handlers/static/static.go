@@ -0,0 +1,102 @@
+package static
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"blog-api/internal/http-server/handlers/fallback"
+	"blog-api/internal/lib/logger"
+	"blog-api/internal/lib/logger/sl"
+)
+
+const index = "index.html"
+
+// The frontend loads its own scripts and styles and calls the API on the same origin,
+// the API's policy would block all of it
+const contentSecurityPolicy = "default-src 'self'; frame-ancestors 'none'"
+
+type Static struct {
+	log    *slog.Logger
+	fsys   fs.FS
+	maxAge time.Duration
+	strict bool
+}
+
+// New serves the frontend build in fsys. Assets may be cached for maxAge,
+// index.html is revalidated on every load so that a new build is picked up right away.
+// strict must match SecurityHeaders, the frontend then gets its own Content-Security-Policy
+func New(log *slog.Logger, fsys fs.FS, maxAge time.Duration, strict bool) *Static {
+	return &Static{
+		log:    log,
+		fsys:   fsys,
+		maxAge: maxAge,
+		strict: strict,
+	}
+}
+
+// Serve answers with the file at the request path. Other paths get index.html, so that
+// the frontend's own routes survive a reload, except missing assets, which get a 404.
+// It's meant to be mounted at "/*" so that every other route takes precedence
+func (s *Static) Serve(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.static.Serve"
+
+	log := logger.FromContext(r.Context(), s.log).With(slog.String("op", op))
+
+	name := strings.TrimPrefix(path.Clean("/"+r.URL.Path), "/")
+	if name == "" {
+		name = index
+	}
+
+	info, err := fs.Stat(s.fsys, name)
+	if err != nil || info.IsDir() {
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			log.Error("failed to stat file", slog.String("name", name), sl.Error(err))
+		}
+		// A missing script must not turn into a page
+		if path.Ext(name) != "" {
+			fallback.NotFound(w, r)
+			return
+		}
+		name = index
+	}
+
+	f, err := s.fsys.Open(name)
+	if err != nil {
+		log.Error("failed to open file", slog.String("name", name), sl.Error(err))
+		fallback.NotFound(w, r)
+		return
+	}
+	defer f.Close()
+
+	info, err = f.Stat()
+	if err != nil {
+		log.Error("failed to stat file", slog.String("name", name), sl.Error(err))
+		fallback.NotFound(w, r)
+		return
+	}
+	content, ok := f.(io.ReadSeeker)
+	if !ok {
+		log.Error("file can't seek", slog.String("name", name))
+		fallback.NotFound(w, r)
+		return
+	}
+
+	h := w.Header()
+	if name == index {
+		h.Set("Cache-Control", "no-cache")
+	} else {
+		h.Set("Cache-Control", "public, max-age="+strconv.Itoa(int(s.maxAge.Seconds())))
+	}
+	if s.strict {
+		h.Set("Content-Security-Policy", contentSecurityPolicy)
+	}
+
+	http.ServeContent(w, r, name, info.ModTime(), content)
+}
@@ -0,0 +1,95 @@
+package static_test
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"blog-api/internal/http-server/handlers/fallback"
+	"blog-api/internal/http-server/handlers/static"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const basePath = "/api/v1"
+
+// text answers with body, standing in for a real handler
+func text(body string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(body))
+	}
+}
+
+// newTestRouter wires the frontend next to the API and the sitemap the way main does
+func newTestRouter() http.Handler {
+	frontend := fstest.MapFS{
+		"index.html":    {Data: []byte("index"), ModTime: time.Now()},
+		"assets/app.js": {Data: []byte("script"), ModTime: time.Now()},
+	}
+
+	r := chi.NewRouter()
+	r.NotFound(fallback.NotFound)
+	r.MethodNotAllowed(fallback.MethodNotAllowed)
+
+	api := chi.NewRouter()
+	api.NotFound(fallback.NotFound)
+	api.MethodNotAllowed(fallback.MethodNotAllowed)
+	api.Route("/articles", func(r chi.Router) {
+		r.Get("/", text("articles"))
+		r.Post("/", text("created"))
+	})
+
+	r.Mount(basePath, api)
+	r.Get("/sitemap.xml", text("sitemap"))
+
+	stc := static.New(slog.New(slog.NewTextHandler(io.Discard, nil)), frontend, time.Hour, true)
+	r.Get("/*", stc.Serve)
+	r.Head("/*", stc.Serve)
+
+	return r
+}
+
+func TestRoutePriority(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		wantStatus int
+		wantBody   string
+		wantJSON   bool
+	}{
+		{name: "API route", method: http.MethodGet, path: basePath + "/articles", wantStatus: http.StatusOK, wantBody: "articles"},
+		{name: "API route with another method", method: http.MethodPost, path: basePath + "/articles", wantStatus: http.StatusOK, wantBody: "created"},
+		{name: "unknown API route", method: http.MethodGet, path: basePath + "/unknown", wantStatus: http.StatusNotFound, wantJSON: true},
+		{name: "unsupported API method", method: http.MethodDelete, path: basePath + "/articles", wantStatus: http.StatusMethodNotAllowed, wantJSON: true},
+		{name: "sitemap", method: http.MethodGet, path: "/sitemap.xml", wantStatus: http.StatusOK, wantBody: "sitemap"},
+		{name: "root", method: http.MethodGet, path: "/", wantStatus: http.StatusOK, wantBody: "index"},
+		{name: "frontend route", method: http.MethodGet, path: "/articles/1", wantStatus: http.StatusOK, wantBody: "index"},
+		{name: "asset", method: http.MethodGet, path: "/assets/app.js", wantStatus: http.StatusOK, wantBody: "script"},
+		{name: "missing asset", method: http.MethodGet, path: "/assets/missing.js", wantStatus: http.StatusNotFound, wantJSON: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			newTestRouter().ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("%s %s status = %d, want %d", tt.method, tt.path, w.Code, tt.wantStatus)
+			}
+			if tt.wantJSON {
+				if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/json") {
+					t.Errorf("%s %s Content-Type = %q, want the JSON error", tt.method, tt.path, ct)
+				}
+				return
+			}
+			if w.Body.String() != tt.wantBody {
+				t.Errorf("%s %s body = %q, want %q", tt.method, tt.path, w.Body.String(), tt.wantBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package stats
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"blog-api/internal/domain/models"
+	"blog-api/internal/http-server/apperror"
+	mw "blog-api/internal/http-server/middleware"
+	resp "blog-api/internal/lib/api/response"
+	"blog-api/internal/lib/jwt"
+	"blog-api/internal/lib/logger"
+	"blog-api/internal/lib/logger/sl"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+type Service interface {
+	ArticleStats(ctx context.Context, requesterID int, role string, articleID int) (models.ArticleStats, error)
+}
+
+type Stats struct {
+	log      *slog.Logger
+	service  Service
+	verifier func(http.Handler) http.Handler
+}
+
+func New(log *slog.Logger, service Service, verifier func(http.Handler) http.Handler) *Stats {
+	return &Stats{
+		log:      log,
+		service:  service,
+		verifier: verifier,
+	}
+}
+
+// RegisterByArticle serves the stats of one article to its author and admins,
+// it expects to be mounted under a route with the article "id" param
+func (s *Stats) RegisterByArticle() func(r chi.Router) {
+	return func(r chi.Router) {
+		// Require auth
+		r.Group(func(r chi.Router) {
+			r.Use(s.verifier)
+			r.Use(mw.Authenticator)
+
+			r.Get("/", s.article)
+		})
+	}
+}
+
+func (s *Stats) article(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.stats.article"
+
+	log := logger.FromContext(r.Context(), s.log).With(slog.String("op", op))
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		log.Debug("failed to get \"id\" url param", sl.Error(err))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, resp.Err(r, resp.CodeValidationFailed, "invalid article id"))
+		return
+	}
+
+	userID, err := jwt.UserID(r.Context())
+	if err != nil {
+		log.Error("failed to get user id from token", sl.Error(err))
+		render.Status(r, http.StatusUnauthorized)
+		render.JSON(w, r, resp.Err(r, resp.CodeUnauthorized, "unauthorized"))
+		return
+	}
+	role, err := jwt.Role(r.Context())
+	if err != nil {
+		log.Error("failed to get role from token", sl.Error(err))
+		render.Status(r, http.StatusUnauthorized)
+		render.JSON(w, r, resp.Err(r, resp.CodeUnauthorized, "unauthorized"))
+		return
+	}
+
+	// Send to service layer
+	stats, err := s.service.ArticleStats(r.Context(), userID, role, id)
+	if err != nil {
+		if !apperror.Render(w, r, err) {
+			log.Error("failed to get article stats", sl.Error(err))
+		}
+		return
+	}
+
+	// Write to response
+	render.JSON(w, r, resp.Response{
+		Status: resp.StatusOk,
+		Stats:  &stats,
+	})
+}
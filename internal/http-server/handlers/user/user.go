@@ -1,61 +1,84 @@
 package user
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"strconv"
+	"time"
 
 	"blog-api/internal/domain/models"
+	"blog-api/internal/http-server/apperror"
+	mw "blog-api/internal/http-server/middleware"
 	req "blog-api/internal/lib/api/request"
 	resp "blog-api/internal/lib/api/response"
 	"blog-api/internal/lib/jwt"
+	"blog-api/internal/lib/logger"
 	"blog-api/internal/lib/logger/sl"
-	"blog-api/internal/service/user"
+	userservice "blog-api/internal/service/user"
 
 	"github.com/go-chi/chi/v5"
-	"github.com/go-chi/jwtauth/v5"
 	"github.com/go-chi/render"
 )
 
 type Service interface {
-	GetAll() ([]models.User, error)
-	Remove(id int) error
-	UserByID(id int) (models.User, error)
-	Register(userName, password string) error
-	Login(userName, password, secret string) (token string, err error)
-	UpdateUserName(id int, userName string) error
-	UpdateStatus(id int, status string) error
+	GetAll(ctx context.Context, limit, offset int) ([]models.User, error)
+	Remove(ctx context.Context, id int) error
+	UserByID(ctx context.Context, id int) (models.User, error)
+	Stats(ctx context.Context, id int) (models.UserStats, error)
+	Register(ctx context.Context, userName, email, password string) (int64, error)
+	Login(ctx context.Context, identifier, password string, rememberMe bool, ip, userAgent string) (token string, err error)
+	LoginHistory(ctx context.Context, userID, limit, offset int) ([]models.LoginEvent, error)
+	Available(ctx context.Context, userName, email string) (bool, error)
+	UserByName(ctx context.Context, userName string) (models.User, error)
+	UpdateUserName(ctx context.Context, id int, userName string) error
+	UpdateEmail(ctx context.Context, id int, email string) error
+	UpdateStatus(ctx context.Context, id int, status string) error
+	ForgotPassword(ctx context.Context, email string) error
+	ResetPassword(ctx context.Context, token, password string) error
 }
 
 type User struct {
-	log     *slog.Logger
-	service Service
-	secret  string
+	log      *slog.Logger
+	service  Service
+	verifier func(http.Handler) http.Handler
 }
 
-func New(log *slog.Logger, service Service, secret string) *User {
+func New(log *slog.Logger, service Service, verifier func(http.Handler) http.Handler) *User {
 	return &User{
-		log:     log,
-		service: service,
-		secret:  secret,
+		log:      log,
+		service:  service,
+		verifier: verifier,
 	}
 }
 
 func (u *User) Register() func(r chi.Router) {
 	return func(r chi.Router) {
+		// Write endpoints only accept JSON bodies
+		r.Use(mw.RequireJSON)
+
 		// Public routes
-		r.Get("/", u.getAll) // TODO: получение всех пользователей блога
+		r.Get("/", u.getAll)
+		// Both paths share one limit, /check is kept for the registration form
+		availabilityLimit := mw.RateLimit(availabilityChecksPerMinute, time.Minute)
+		r.With(availabilityLimit).Get("/available", u.available)
+		r.With(availabilityLimit).Get("/check", u.available)
+		r.Get("/@{username}", u.getByName)
 		r.Get("/{id}", u.getByID)
+		r.Get("/{id}/stats", u.stats)
 		r.Post("/login", u.login)
 		r.Post("/register", u.register)
+		r.With(mw.RateLimit(passwordResetsPerMinute, time.Minute)).Post("/forgot-password", u.forgotPassword)
+		r.Post("/reset-password", u.resetPassword)
 
 		// Require auth
 		r.Group(func(r chi.Router) {
-			tokenAuth := jwtauth.New("HS256", []byte(u.secret), nil)
-			r.Use(jwtauth.Verifier(tokenAuth))
-			r.Use(jwtauth.Authenticator(tokenAuth))
+			r.Use(u.verifier)
+			r.Use(mw.Authenticator)
 
+			r.Get("/{id}/login-history", u.loginHistory)
 			r.Put("/{id}", u.update)
 			r.Delete("/{id}", u.remove)
 		})
@@ -65,34 +88,54 @@ func (u *User) Register() func(r chi.Router) {
 func (u *User) login(w http.ResponseWriter, r *http.Request) {
 	const op = "handlers.user.login"
 
-	log := u.log.With(slog.String("op", op))
+	log := logger.FromContext(r.Context(), u.log).With(slog.String("op", op))
 
 	var cred req.Credentials
 	err := render.DecodeJSON(r.Body, &cred)
 	if err != nil {
-		log.Error("failed to decode request", sl.Error(err))
-		render.JSON(w, r, resp.Err("internal error"))
+		log.Debug("failed to decode request", sl.Error(err))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, resp.Err(r, resp.CodeInvalidBody, "invalid request body"))
 		return
 	}
 
+	// Either the user name or the email identifies the user
+	identifier := cred.UserName
+	if identifier == "" {
+		identifier = cred.Email
+	}
+
 	// Validate user creds
-	if cred.UserName == "" {
+	if identifier == "" {
 		u.log.Error("user name is empty")
-		render.JSON(w, r, resp.Err("invalid credentials: user name is empty"))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, resp.Err(r, resp.CodeValidationFailed, "invalid credentials: user name is empty"))
 		return
 	}
 
 	if cred.Password == "" {
 		u.log.Error("password is empty")
-		render.JSON(w, r, resp.Err("invalid credentials: password is empty"))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, resp.Err(r, resp.CodeValidationFailed, "invalid credentials: password is empty"))
 		return
 	}
 
 	// Send to service layer
-	token, err := u.service.Login(cred.UserName, cred.Password, u.secret)
+	token, err := u.service.Login(r.Context(), identifier, cred.Password, cred.RememberMe, req.ClientIP(r), r.UserAgent())
 	if err != nil {
-		u.log.Error("failed to create new token", sl.Error(err))
-		render.JSON(w, r, resp.Err("internal error"))
+		// The unlock time lets the client tell the user when to come back
+		var locked *userservice.LockedError
+		if errors.As(err, &locked) {
+			response := resp.Err(r, resp.CodeAccountLocked, userservice.ErrAccountLocked.Error())
+			response.LockedUntil = &locked.Until
+			w.Header().Set("Retry-After", strconv.Itoa(int(time.Until(locked.Until).Seconds())+1))
+			render.Status(r, http.StatusLocked)
+			render.JSON(w, r, response)
+			return
+		}
+		if !apperror.Render(w, r, err) {
+			log.Error("failed to create new token", sl.Error(err))
+		}
 		return
 	}
 
@@ -104,15 +147,24 @@ func (u *User) login(w http.ResponseWriter, r *http.Request) {
 }
 
 func (u *User) getAll(w http.ResponseWriter, r *http.Request) {
-	const op = "handlers.register.getAll"
+	const op = "handlers.user.getAll"
+
+	log := logger.FromContext(r.Context(), u.log).With(slog.String("op", op))
 
-	log := u.log.With(slog.String("op", op))
+	limit, offset, err := req.Pagination(r)
+	if err != nil {
+		log.Debug("invalid pagination params", sl.Error(err))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, resp.Err(r, resp.CodeValidationFailed, err.Error()))
+		return
+	}
 
 	// Send to service layer
-	users, err := u.service.GetAll()
+	users, err := u.service.GetAll(r.Context(), limit, offset)
 	if err != nil {
 		log.Error("failed to get all users", sl.Error(err))
-		render.JSON(w, r, resp.Err("internal error"))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, resp.Err(r, resp.CodeInternal, "internal error"))
 		return
 	}
 
@@ -126,81 +178,297 @@ func (u *User) getAll(w http.ResponseWriter, r *http.Request) {
 func (u *User) register(w http.ResponseWriter, r *http.Request) {
 	const op = "handlers.user.register"
 
-	log := u.log.With(slog.String("op", op))
+	log := logger.FromContext(r.Context(), u.log).With(slog.String("op", op))
 
 	var cred req.Credentials
 	err := render.DecodeJSON(r.Body, &cred)
 	if err != nil {
-		log.Error("failed to decode request", sl.Error(err))
-		render.JSON(w, r, resp.Err("internal error"))
+		log.Debug("failed to decode request", sl.Error(err))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, resp.Err(r, resp.CodeInvalidBody, "invalid request body"))
 		return
 	}
 
 	// Validate user creds
 	if cred.UserName == "" {
 		u.log.Error("user name is empty")
-		render.JSON(w, r, resp.Err("invalid credentials: user name is empty"))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, resp.Err(r, resp.CodeValidationFailed, "invalid credentials: user name is empty"))
 		return
 	}
 
 	if cred.Password == "" {
 		u.log.Error("password is empty")
-		render.JSON(w, r, resp.Err("password is empty"))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, resp.Err(r, resp.CodeValidationFailed, "password is empty"))
 		return
 	}
 
 	// Send to service layer
-	err = u.service.Register(cred.UserName, cred.Password)
+	id, err := u.service.Register(r.Context(), cred.UserName, cred.Email, cred.Password)
 	if err != nil {
-		if errors.Is(err, user.ErrUserExists) {
-			u.log.Error("failed to register user", sl.Error(err))
-			render.JSON(w, r, resp.Err("user already exists"))
-			return
+		if !apperror.Render(w, r, err) {
+			u.log.Error("failed to register new user", sl.Error(err))
 		}
-
-		u.log.Info("failed to register new user", sl.Error(err))
-		render.JSON(w, r, resp.Err("internal error"))
 		return
 	}
 
+	response := resp.Response{
+		Status: resp.StatusOk,
+		ID:     id,
+	}
+
+	// Send to service layer
+	usr, err := u.service.UserByID(r.Context(), int(id))
+	if err != nil {
+		// The user is already created, the client can still fetch it by id
+		log.Error("failed to get registered user", sl.Error(err))
+	} else {
+		response.User = resp.NewUserDTO(usr)
+	}
+
 	// Write response
+	w.Header().Set("Location", mw.Path(r, fmt.Sprintf("/users/%d", id)))
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, response)
+}
+
+// passwordResetsPerMinute caps reset emails asked for per client IP, so the endpoint can't be used to flood inboxes
+const passwordResetsPerMinute = 5
+
+// forgotPassword emails a reset token to the "email" of the body. It answers 202 whether
+// the email is registered or not
+func (u *User) forgotPassword(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.user.forgotPassword"
+
+	log := logger.FromContext(r.Context(), u.log).With(slog.String("op", op))
+
+	var body req.ForgotPassword
+	err := render.DecodeJSON(r.Body, &body)
+	if err != nil {
+		log.Debug("failed to decode request", sl.Error(err))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, resp.Err(r, resp.CodeInvalidBody, "invalid request body"))
+		return
+	}
+
+	// Send to service layer
+	err = u.service.ForgotPassword(r.Context(), body.Email)
+	if err != nil {
+		if !apperror.Render(w, r, err) {
+			log.Error("failed to start password reset", sl.Error(err))
+		}
+		return
+	}
+
+	// Write to response
+	render.Status(r, http.StatusAccepted)
 	render.JSON(w, r, resp.Response{
 		Status: resp.StatusOk,
 	})
 }
 
+// resetPassword sets the "new_password" of the body for the owner of its "token"
+func (u *User) resetPassword(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.user.resetPassword"
+
+	log := logger.FromContext(r.Context(), u.log).With(slog.String("op", op))
+
+	var body req.ResetPassword
+	err := render.DecodeJSON(r.Body, &body)
+	if err != nil {
+		log.Debug("failed to decode request", sl.Error(err))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, resp.Err(r, resp.CodeInvalidBody, "invalid request body"))
+		return
+	}
+
+	// Send to service layer
+	err = u.service.ResetPassword(r.Context(), body.Token, body.NewPassword)
+	if err != nil {
+		if !apperror.Render(w, r, err) {
+			log.Error("failed to reset password", sl.Error(err))
+		}
+		return
+	}
+
+	// Write to response
+	render.JSON(w, r, resp.Response{
+		Status: resp.StatusOk,
+	})
+}
+
+// availabilityChecksPerMinute caps availability checks per client IP to slow down user enumeration
+const availabilityChecksPerMinute = 20
+
+// available tells the registration form whether the "username" and "email" query params are free
+func (u *User) available(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.user.available"
+
+	log := logger.FromContext(r.Context(), u.log).With(slog.String("op", op))
+
+	q := r.URL.Query()
+
+	// Send to service layer
+	ok, err := u.service.Available(r.Context(), q.Get("username"), q.Get("email"))
+	if err != nil {
+		if !apperror.Render(w, r, err) {
+			log.Error("failed to check availability", sl.Error(err))
+		}
+		return
+	}
+
+	// Write to response
+	render.JSON(w, r, resp.Response{
+		Status:    resp.StatusOk,
+		Available: &ok,
+	})
+}
+
 func (u *User) getByID(w http.ResponseWriter, r *http.Request) {
 	const op = "handlers.user.get"
 
-	log := u.log.With(slog.String("op", op))
+	log := logger.FromContext(r.Context(), u.log).With(slog.String("op", op))
 
 	id, err := strconv.Atoi(chi.URLParam(r, "id"))
 	if err != nil {
 		log.Error("failed to get \"id\" url param", sl.Error(err))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, resp.Err(r, resp.CodeValidationFailed, "invalid user id"))
+		return
 	}
 
 	// Send to service layer
-	user, err := u.service.UserByID(id)
+	usr, err := u.service.UserByID(r.Context(), id)
 	if err != nil {
-		u.log.Error("failed to get user by id", sl.Error(err))
-		render.JSON(w, r, resp.Err("internal error"))
+		if !apperror.Render(w, r, err) {
+			log.Error("failed to get user by id", sl.Error(err))
+		}
 		return
 	}
 
-	var us []models.User
-	us = append(us, user)
+	// Write to response
+	render.JSON(w, r, resp.Response{
+		Status: resp.StatusOk,
+		User:   resp.NewUserDTO(usr),
+	})
+}
+
+// stats returns the public counts shown on the user's profile
+func (u *User) stats(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.user.stats"
+
+	log := logger.FromContext(r.Context(), u.log).With(slog.String("op", op))
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		log.Debug("failed to get \"id\" url param", sl.Error(err))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, resp.Err(r, resp.CodeValidationFailed, "invalid user id"))
+		return
+	}
+
+	// Send to service layer
+	stats, err := u.service.Stats(r.Context(), id)
+	if err != nil {
+		if !apperror.Render(w, r, err) {
+			log.Error("failed to get user stats", sl.Error(err))
+		}
+		return
+	}
+
+	// Write to response
+	render.JSON(w, r, resp.Response{
+		Status:    resp.StatusOk,
+		UserStats: &stats,
+	})
+}
+
+// getByName serves the profile at /users/@{username}, same shape as getByID
+func (u *User) getByName(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.user.getByName"
+
+	log := logger.FromContext(r.Context(), u.log).With(slog.String("op", op))
+
+	userName := chi.URLParam(r, "username")
+
+	// Send to service layer
+	usr, err := u.service.UserByName(r.Context(), userName)
+	if err != nil {
+		if !apperror.Render(w, r, err) {
+			log.Error("failed to get user by name", sl.Error(err))
+		}
+		return
+	}
 
 	// Write to response
 	render.JSON(w, r, resp.Response{
 		Status: resp.StatusOk,
-		Users:  &us,
+		User:   resp.NewUserDTO(usr),
+	})
+}
+
+// loginHistoryLimit is the default number of login events returned
+const loginHistoryLimit = 50
+
+func (u *User) loginHistory(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.user.loginHistory"
+
+	log := logger.FromContext(r.Context(), u.log).With(slog.String("op", op))
+
+	id := chi.URLParam(r, "id")
+
+	userID, err := strconv.Atoi(id)
+	if err != nil {
+		log.Debug("failed to get \"id\" url param", sl.Error(err))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, resp.Err(r, resp.CodeValidationFailed, "invalid user id"))
+		return
+	}
+
+	// Checking user permission
+	satisfied, err := jwt.CheckClaim(r.Context(), "uid", id)
+	if err != nil {
+		log.Error("failed to check permission", sl.Error(err))
+		render.Status(r, http.StatusUnauthorized)
+		render.JSON(w, r, resp.Err(r, resp.CodeUnauthorized, "unauthorized"))
+		return
+	}
+	if !satisfied {
+		log.Debug("user doesn't have permission", slog.Int("user_id", userID))
+		render.Status(r, http.StatusForbidden)
+		render.JSON(w, r, resp.Err(r, resp.CodeForbidden, "not enough rights"))
+		return
+	}
+
+	limit, offset, err := req.PaginationWithLimit(r, loginHistoryLimit)
+	if err != nil {
+		log.Debug("invalid pagination params", sl.Error(err))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, resp.Err(r, resp.CodeValidationFailed, err.Error()))
+		return
+	}
+
+	// Send to service layer
+	history, err := u.service.LoginHistory(r.Context(), userID, limit, offset)
+	if err != nil {
+		log.Error("failed to get login history", sl.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, resp.Err(r, resp.CodeInternal, "internal error"))
+		return
+	}
+
+	// Write to response
+	render.JSON(w, r, resp.Response{
+		Status:       resp.StatusOk,
+		LoginHistory: &history,
 	})
 }
 
 func (u *User) update(w http.ResponseWriter, r *http.Request) {
 	const op = "handlers.user.update"
 
-	log := u.log.With(slog.String("op", op))
+	log := logger.FromContext(r.Context(), u.log).With(slog.String("op", op))
 
 	// Getting id from url params
 	id := chi.URLParam(r, "id")
@@ -208,51 +476,66 @@ func (u *User) update(w http.ResponseWriter, r *http.Request) {
 	// Checking user permission
 	satisfied, err := jwt.CheckClaim(r.Context(), "uid", id)
 	if err != nil {
-		log.Error("failed to check permission", slog.String("user_id", id))
-		render.JSON(w, r, resp.Err("internal error"))
+		log.Error("failed to check permission", sl.Error(err))
+		render.Status(r, http.StatusUnauthorized)
+		render.JSON(w, r, resp.Err(r, resp.CodeUnauthorized, "unauthorized"))
 		return
 	}
 	if !satisfied {
 		log.Error("user doesn't have permission")
-		render.JSON(w, r, resp.Err("not enough rights"))
+		render.Status(r, http.StatusForbidden)
+		render.JSON(w, r, resp.Err(r, resp.CodeForbidden, "not enough rights"))
 		return
 	}
 
 	var upd req.Update
 	err = render.DecodeJSON(r.Body, &upd)
 	if err != nil {
-		render.JSON(w, r, resp.Err("internal error"))
+		log.Debug("failed to decode request", sl.Error(err))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, resp.Err(r, resp.CodeInvalidBody, "invalid request body"))
 		return
 	}
 
 	userID, err := strconv.Atoi(id)
 	if err != nil {
 		log.Error("failed to convert str to int", sl.Error(err))
-		render.JSON(w, r, resp.Err("internal error"))
+		render.JSON(w, r, resp.Err(r, resp.CodeInternal, "internal error"))
 		return
 	}
 
 	// Validation
 	if upd.UserName != "" {
 		// Send to service layer
-		err := u.service.UpdateUserName(userID, upd.UserName)
+		err := u.service.UpdateUserName(r.Context(), userID, upd.UserName)
 		if err != nil {
-			u.log.Error("failed to update user name", sl.Error(err))
-			if errors.As(err, &user.ErrUserNameTaken) {
-				render.JSON(w, r, resp.Err("user name already taken"))
-				return
+			if !apperror.Render(w, r, err) {
+				log.Error("failed to update user name", sl.Error(err))
 			}
-			render.JSON(w, r, resp.Err("internal error"))
 			return
 		}
 	}
 
-	// Send to service layer
-	err = u.service.UpdateStatus(userID, upd.Status)
-	if err != nil {
-		u.log.Error("failed to update user status", sl.Error(err))
-		render.JSON(w, r, resp.Err("internal error"))
-		return
+	if upd.Email != "" {
+		// Send to service layer
+		err := u.service.UpdateEmail(r.Context(), userID, upd.Email)
+		if err != nil {
+			if !apperror.Render(w, r, err) {
+				log.Error("failed to update email", sl.Error(err))
+			}
+			return
+		}
+	}
+
+	if upd.Status != nil {
+		// Send to service layer
+		err := u.service.UpdateStatus(r.Context(), userID, *upd.Status)
+		if err != nil {
+			if !apperror.Render(w, r, err) {
+				log.Error("failed to update user status", sl.Error(err))
+			}
+			return
+		}
 	}
 
 	// Write to response
@@ -262,37 +545,40 @@ func (u *User) update(w http.ResponseWriter, r *http.Request) {
 }
 
 func (u *User) remove(w http.ResponseWriter, r *http.Request) {
-	// TODO: реализовать систему ролей: пользватель, админ
-	// TODO: делать токен недействитеьным после удаления
 	const op = "handlers.user.remove"
 
-	log := u.log.With(slog.String("op", op))
+	log := logger.FromContext(r.Context(), u.log).With(slog.String("op", op))
 
 	// Getting id from url params
 	id, err := strconv.Atoi(chi.URLParam(r, "id"))
 	if err != nil {
 		log.Error("failed to get \"id\" url param", sl.Error(err))
-		render.JSON(w, r, resp.Err("internal error"))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, resp.Err(r, resp.CodeValidationFailed, "invalid user id"))
+		return
 	}
 
 	// Checking user permission
 	satisfied, err := jwt.CheckClaim(r.Context(), "uid", strconv.Itoa(id))
 	if err != nil {
-		log.Error("failed to check permission", slog.Int("user_id", id))
-		render.JSON(w, r, resp.Err("internal error"))
+		log.Error("failed to check permission", sl.Error(err))
+		render.Status(r, http.StatusUnauthorized)
+		render.JSON(w, r, resp.Err(r, resp.CodeUnauthorized, "unauthorized"))
 		return
 	}
 	if !satisfied {
 		log.Error("user doesn't have permission")
-		render.JSON(w, r, resp.Err("not enough rights"))
+		render.Status(r, http.StatusForbidden)
+		render.JSON(w, r, resp.Err(r, resp.CodeForbidden, "not enough rights"))
 		return
 	}
 
 	// Send to service layer
-	err = u.service.Remove(id)
+	err = u.service.Remove(r.Context(), id)
 	if err != nil {
-		u.log.Error("failed to remove user", sl.Error(err))
-		render.JSON(w, r, resp.Err("internal error"))
+		if !apperror.Render(w, r, err) {
+			log.Error("failed to remove user", sl.Error(err))
+		}
 		return
 	}
 
@@ -0,0 +1,349 @@
+package user_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"blog-api/internal/domain/models"
+	"blog-api/internal/http-server/handlers/user"
+	mw "blog-api/internal/http-server/middleware"
+	resp "blog-api/internal/lib/api/response"
+	"blog-api/internal/lib/jwt"
+	userservice "blog-api/internal/service/user"
+	"blog-api/internal/testutil"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const userID = 5
+
+// newTestRouter serves the user routes with svc and returns a token of user userID
+func newTestRouter(t *testing.T, svc user.Service) (http.Handler, string) {
+	t.Helper()
+
+	keys, err := jwt.LoadKeys(jwt.HS256, "test secret", nil, "", "")
+	if err != nil {
+		t.Fatalf("failed to load keys: %v", err)
+	}
+	token, err := jwt.NewToken(models.User{ID: userID, Role: models.RoleUser}, 1, time.Hour, keys)
+	if err != nil {
+		t.Fatalf("failed to issue token: %v", err)
+	}
+
+	h := user.New(slog.New(slog.NewTextHandler(io.Discard, nil)), svc, mw.Verifier(keys))
+
+	r := chi.NewRouter()
+	r.Route("/users", h.Register())
+
+	return r, token
+}
+
+// serve sends the request to router, with the token unless it's empty, and decodes the response
+func serve(t *testing.T, router http.Handler, method, path, body, token string) (int, resp.Response) {
+	t.Helper()
+
+	var reqBody io.Reader
+	if body != "" {
+		reqBody = strings.NewReader(body)
+	}
+	r := httptest.NewRequest(method, path, reqBody)
+	if body != "" {
+		r.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, r)
+
+	var res resp.Response
+	if err := json.NewDecoder(w.Body).Decode(&res); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	return w.Code, res
+}
+
+func TestHandlers(t *testing.T) {
+	registered := func(_ context.Context, id int) (models.User, error) {
+		return models.User{ID: int64(id), Credentials: models.Credentials{UserName: "alice"}}, nil
+	}
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		noToken    bool
+		svc        *testutil.UserService
+		wantStatus int
+		wantCode   string
+		// check asserts the body of a successful response
+		check func(t *testing.T, res resp.Response)
+	}{
+		{
+			name:   "register",
+			method: http.MethodPost,
+			path:   "/users/register",
+			body:   `{"user_name":"alice","password":"secret"}`,
+			svc: &testutil.UserService{
+				RegisterFunc: func(_ context.Context, userName, _, password string) (int64, error) {
+					if userName != "alice" || password != "secret" {
+						return 0, fmt.Errorf("registered %q with %q", userName, password)
+					}
+					return 1, nil
+				},
+				UserByIDFunc: registered,
+			},
+			wantStatus: http.StatusCreated,
+			check: func(t *testing.T, res resp.Response) {
+				if res.ID != 1 || res.User == nil || res.User.UserName != "alice" {
+					t.Errorf("response = id %d, user %+v, want alice with id 1", res.ID, res.User)
+				}
+			},
+		},
+		{
+			name:       "register with broken JSON",
+			method:     http.MethodPost,
+			path:       "/users/register",
+			body:       `{"user_name":`,
+			svc:        &testutil.UserService{},
+			wantStatus: http.StatusBadRequest,
+			wantCode:   resp.CodeInvalidBody,
+		},
+		{
+			name:       "register without password",
+			method:     http.MethodPost,
+			path:       "/users/register",
+			body:       `{"user_name":"alice"}`,
+			svc:        &testutil.UserService{},
+			wantStatus: http.StatusBadRequest,
+			wantCode:   resp.CodeValidationFailed,
+		},
+		{
+			name:   "register a taken name",
+			method: http.MethodPost,
+			path:   "/users/register",
+			body:   `{"user_name":"alice","password":"secret"}`,
+			svc: &testutil.UserService{
+				RegisterFunc: func(context.Context, string, string, string) (int64, error) {
+					return 0, fmt.Errorf("service.user.Register: %w", userservice.ErrUserExists)
+				},
+			},
+			wantStatus: http.StatusConflict,
+			wantCode:   resp.CodeUserExists,
+		},
+		{
+			name:   "register an invalid name",
+			method: http.MethodPost,
+			path:   "/users/register",
+			body:   `{"user_name":"a@b","password":"secret"}`,
+			svc: &testutil.UserService{
+				RegisterFunc: func(context.Context, string, string, string) (int64, error) {
+					return 0, fmt.Errorf("service.user.Register: %w", userservice.ErrInvalidUserName)
+				},
+			},
+			wantStatus: http.StatusBadRequest,
+			wantCode:   resp.CodeValidationFailed,
+		},
+		{
+			name:   "login",
+			method: http.MethodPost,
+			path:   "/users/login",
+			body:   `{"user_name":"alice","password":"secret"}`,
+			svc: &testutil.UserService{
+				LoginFunc: func(context.Context, string, string, bool, string, string) (string, error) {
+					return "token", nil
+				},
+			},
+			wantStatus: http.StatusOK,
+			check: func(t *testing.T, res resp.Response) {
+				if res.Token != "token" {
+					t.Errorf("token = %q, want the issued one", res.Token)
+				}
+			},
+		},
+		{
+			name:       "login with broken JSON",
+			method:     http.MethodPost,
+			path:       "/users/login",
+			body:       `[]`,
+			svc:        &testutil.UserService{},
+			wantStatus: http.StatusBadRequest,
+			wantCode:   resp.CodeInvalidBody,
+		},
+		{
+			name:       "login without user name",
+			method:     http.MethodPost,
+			path:       "/users/login",
+			body:       `{"password":"secret"}`,
+			svc:        &testutil.UserService{},
+			wantStatus: http.StatusBadRequest,
+			wantCode:   resp.CodeValidationFailed,
+		},
+		{
+			name:   "login with a wrong password",
+			method: http.MethodPost,
+			path:   "/users/login",
+			body:   `{"user_name":"alice","password":"wrong"}`,
+			svc: &testutil.UserService{
+				LoginFunc: func(context.Context, string, string, bool, string, string) (string, error) {
+					return "", fmt.Errorf("service.user.Login: %w", userservice.ErrInvalidCredentials)
+				},
+			},
+			wantStatus: http.StatusUnauthorized,
+			wantCode:   resp.CodeInvalidCredentials,
+		},
+		{
+			name:   "list",
+			method: http.MethodGet,
+			path:   "/users",
+			svc: &testutil.UserService{
+				GetAllFunc: func(context.Context, int, int) ([]models.User, error) {
+					return []models.User{{ID: 1, Credentials: models.Credentials{UserName: "alice"}}}, nil
+				},
+			},
+			wantStatus: http.StatusOK,
+			check: func(t *testing.T, res resp.Response) {
+				if res.Users == nil || len(*res.Users) != 1 || (*res.Users)[0].UserName != "alice" {
+					t.Errorf("users = %v, want alice", res.Users)
+				}
+			},
+		},
+		{
+			name:   "list fails",
+			method: http.MethodGet,
+			path:   "/users",
+			svc: &testutil.UserService{
+				GetAllFunc: func(context.Context, int, int) ([]models.User, error) {
+					return nil, errors.New("database is locked")
+				},
+			},
+			wantStatus: http.StatusInternalServerError,
+			wantCode:   resp.CodeInternal,
+		},
+		{
+			name:   "get",
+			method: http.MethodGet,
+			path:   "/users/1",
+			svc: &testutil.UserService{
+				UserByIDFunc: registered,
+			},
+			wantStatus: http.StatusOK,
+			check: func(t *testing.T, res resp.Response) {
+				if res.User == nil || res.User.ID != 1 || res.User.UserName != "alice" {
+					t.Errorf("user = %+v, want alice with id 1", res.User)
+				}
+			},
+		},
+		{
+			name:   "get missing",
+			method: http.MethodGet,
+			path:   "/users/1",
+			svc: &testutil.UserService{
+				UserByIDFunc: func(context.Context, int) (models.User, error) {
+					return models.User{}, fmt.Errorf("service.user.UserByID: %w", userservice.ErrUserNotFound)
+				},
+			},
+			wantStatus: http.StatusNotFound,
+			wantCode:   resp.CodeNotFound,
+		},
+		{
+			name:   "update",
+			method: http.MethodPut,
+			path:   fmt.Sprintf("/users/%d", userID),
+			body:   `{"user_name":"bob"}`,
+			svc: &testutil.UserService{
+				UpdateUserNameFunc: func(_ context.Context, id int, userName string) error {
+					if id != userID || userName != "bob" {
+						return fmt.Errorf("renamed %d to %q", id, userName)
+					}
+					return nil
+				},
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "update with broken JSON",
+			method:     http.MethodPut,
+			path:       fmt.Sprintf("/users/%d", userID),
+			body:       `{`,
+			svc:        &testutil.UserService{},
+			wantStatus: http.StatusBadRequest,
+			wantCode:   resp.CodeInvalidBody,
+		},
+		{
+			name:   "update to a taken name",
+			method: http.MethodPut,
+			path:   fmt.Sprintf("/users/%d", userID),
+			body:   `{"user_name":"bob"}`,
+			svc: &testutil.UserService{
+				UpdateUserNameFunc: func(context.Context, int, string) error {
+					return fmt.Errorf("service.user.UpdateUserName: %w", userservice.ErrUserNameTaken)
+				},
+			},
+			wantStatus: http.StatusConflict,
+			wantCode:   resp.CodeUserExists,
+		},
+		{
+			name:       "update another user",
+			method:     http.MethodPut,
+			path:       fmt.Sprintf("/users/%d", userID+1),
+			body:       `{"user_name":"bob"}`,
+			svc:        &testutil.UserService{},
+			wantStatus: http.StatusForbidden,
+			wantCode:   resp.CodeForbidden,
+		},
+		{
+			name:       "update without token",
+			method:     http.MethodPut,
+			path:       fmt.Sprintf("/users/%d", userID),
+			body:       `{"user_name":"bob"}`,
+			noToken:    true,
+			svc:        &testutil.UserService{},
+			wantStatus: http.StatusUnauthorized,
+			wantCode:   resp.CodeUnauthorized,
+		},
+		{
+			name:       "remove without token",
+			method:     http.MethodDelete,
+			path:       fmt.Sprintf("/users/%d", userID),
+			noToken:    true,
+			svc:        &testutil.UserService{},
+			wantStatus: http.StatusUnauthorized,
+			wantCode:   resp.CodeUnauthorized,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router, token := newTestRouter(t, tt.svc)
+			if tt.noToken {
+				token = ""
+			}
+
+			status, res := serve(t, router, tt.method, tt.path, tt.body, token)
+			if status != tt.wantStatus {
+				t.Fatalf("status = %d (%s), want %d", status, res.Error, tt.wantStatus)
+			}
+			if res.Code != tt.wantCode {
+				t.Errorf("code = %q, want %q", res.Code, tt.wantCode)
+			}
+			if tt.wantCode != "" && (res.Status != resp.StatusError || res.Error == "") {
+				t.Errorf("response = %+v, want an error with a message", res)
+			}
+			if tt.check != nil {
+				tt.check(t, res)
+			}
+		})
+	}
+}
@@ -0,0 +1,215 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"blog-api/internal/domain/models"
+	"blog-api/internal/http-server/apperror"
+	mw "blog-api/internal/http-server/middleware"
+	req "blog-api/internal/lib/api/request"
+	resp "blog-api/internal/lib/api/response"
+	"blog-api/internal/lib/jwt"
+	"blog-api/internal/lib/logger"
+	"blog-api/internal/lib/logger/sl"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+type Service interface {
+	List(ctx context.Context, ownerID int) ([]models.Webhook, error)
+	Create(ctx context.Context, w models.Webhook) (models.Webhook, error)
+	Update(ctx context.Context, ownerID int, id int64, rawURL, secret string, events []string, active *bool) (models.Webhook, error)
+	Remove(ctx context.Context, ownerID int, id int64) error
+}
+
+type Webhook struct {
+	log      *slog.Logger
+	service  Service
+	verifier func(http.Handler) http.Handler
+}
+
+func New(log *slog.Logger, service Service, verifier func(http.Handler) http.Handler) *Webhook {
+	return &Webhook{
+		log:      log,
+		service:  service,
+		verifier: verifier,
+	}
+}
+
+// Register serves the webhooks of the token's user
+func (h *Webhook) Register() func(r chi.Router) {
+	return func(r chi.Router) {
+		r.Use(mw.RequireJSON)
+
+		// Require auth
+		r.Use(h.verifier)
+		r.Use(mw.Authenticator)
+
+		r.Get("/", h.list)
+		r.Post("/", h.create)
+		r.Put("/{id}", h.update)
+		r.Delete("/{id}", h.remove)
+	}
+}
+
+func (h *Webhook) list(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.webhook.list"
+
+	log := logger.FromContext(r.Context(), h.log).With(slog.String("op", op))
+
+	userID, err := jwt.UserID(r.Context())
+	if err != nil {
+		log.Error("failed to get user id from token", sl.Error(err))
+		render.Status(r, http.StatusUnauthorized)
+		render.JSON(w, r, resp.Err(r, resp.CodeUnauthorized, "unauthorized"))
+		return
+	}
+
+	// Send to service layer
+	webhooks, err := h.service.List(r.Context(), userID)
+	if err != nil {
+		log.Error("failed to get webhooks", sl.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, resp.Err(r, resp.CodeInternal, "internal error"))
+		return
+	}
+
+	// Write to response
+	render.JSON(w, r, resp.Response{
+		Status:   resp.StatusOk,
+		Webhooks: &webhooks,
+	})
+}
+
+func (h *Webhook) create(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.webhook.create"
+
+	log := logger.FromContext(r.Context(), h.log).With(slog.String("op", op))
+
+	userID, err := jwt.UserID(r.Context())
+	if err != nil {
+		log.Error("failed to get user id from token", sl.Error(err))
+		render.Status(r, http.StatusUnauthorized)
+		render.JSON(w, r, resp.Err(r, resp.CodeUnauthorized, "unauthorized"))
+		return
+	}
+
+	var body req.Webhook
+	err = render.DecodeJSON(r.Body, &body)
+	if err != nil {
+		log.Debug("failed to decode request", sl.Error(err))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, resp.Err(r, resp.CodeInvalidBody, "invalid request body"))
+		return
+	}
+
+	// Send to service layer
+	hook, err := h.service.Create(r.Context(), models.Webhook{
+		OwnerID: userID,
+		URL:     body.URL,
+		Secret:  body.Secret,
+		Events:  body.Events,
+	})
+	if err != nil {
+		if !apperror.Render(w, r, err) {
+			log.Error("failed to create webhook", sl.Error(err))
+		}
+		return
+	}
+
+	// Write to response
+	w.Header().Set("Location", mw.Path(r, fmt.Sprintf("/webhooks/%d", hook.ID)))
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, resp.Response{
+		Status:  resp.StatusOk,
+		ID:      hook.ID,
+		Webhook: &hook,
+	})
+}
+
+func (h *Webhook) update(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.webhook.update"
+
+	log := logger.FromContext(r.Context(), h.log).With(slog.String("op", op))
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		log.Debug("failed to get \"id\" url param", sl.Error(err))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, resp.Err(r, resp.CodeValidationFailed, "invalid webhook id"))
+		return
+	}
+
+	userID, err := jwt.UserID(r.Context())
+	if err != nil {
+		log.Error("failed to get user id from token", sl.Error(err))
+		render.Status(r, http.StatusUnauthorized)
+		render.JSON(w, r, resp.Err(r, resp.CodeUnauthorized, "unauthorized"))
+		return
+	}
+
+	var body req.Webhook
+	err = render.DecodeJSON(r.Body, &body)
+	if err != nil {
+		log.Debug("failed to decode request", sl.Error(err))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, resp.Err(r, resp.CodeInvalidBody, "invalid request body"))
+		return
+	}
+
+	// Send to service layer
+	hook, err := h.service.Update(r.Context(), userID, id, body.URL, body.Secret, body.Events, body.Active)
+	if err != nil {
+		if !apperror.Render(w, r, err) {
+			log.Error("failed to update webhook", sl.Error(err))
+		}
+		return
+	}
+
+	// Write to response
+	render.JSON(w, r, resp.Response{
+		Status:  resp.StatusOk,
+		Webhook: &hook,
+	})
+}
+
+func (h *Webhook) remove(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.webhook.remove"
+
+	log := logger.FromContext(r.Context(), h.log).With(slog.String("op", op))
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		log.Debug("failed to get \"id\" url param", sl.Error(err))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, resp.Err(r, resp.CodeValidationFailed, "invalid webhook id"))
+		return
+	}
+
+	userID, err := jwt.UserID(r.Context())
+	if err != nil {
+		log.Error("failed to get user id from token", sl.Error(err))
+		render.Status(r, http.StatusUnauthorized)
+		render.JSON(w, r, resp.Err(r, resp.CodeUnauthorized, "unauthorized"))
+		return
+	}
+
+	// Send to service layer
+	err = h.service.Remove(r.Context(), userID, id)
+	if err != nil {
+		if !apperror.Render(w, r, err) {
+			log.Error("failed to remove webhook", sl.Error(err))
+		}
+		return
+	}
+
+	// Write to response
+	render.JSON(w, r, resp.Response{
+		Status: resp.StatusOk,
+	})
+}
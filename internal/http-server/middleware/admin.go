@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	resp "blog-api/internal/lib/api/response"
+	"blog-api/internal/lib/jwt"
+
+	"github.com/go-chi/render"
+)
+
+// RequireAdmin lets only admins through. It expects the token to be verified by jwtauth first
+func RequireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !jwt.IsAdmin(r.Context()) {
+			render.Status(r, http.StatusForbidden)
+			render.JSON(w, r, resp.Err(r, resp.CodeForbidden, "not enough rights"))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RequireAdminOrKey lets through admins, as Authenticator and RequireAdmin would, and
+// machine clients that send key in header instead of a token. An empty key admits no machine clients
+func RequireAdminOrKey(header, key string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		admin := Authenticator(RequireAdmin(next))
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if sent := r.Header.Get(header); key != "" && sent != "" {
+				if subtle.ConstantTimeCompare([]byte(sent), []byte(key)) != 1 {
+					render.Status(r, http.StatusUnauthorized)
+					render.JSON(w, r, resp.Err(r, resp.CodeUnauthorized, "unauthorized"))
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			admin.ServeHTTP(w, r)
+		})
+	}
+}
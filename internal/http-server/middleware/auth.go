@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"net/http"
+
+	resp "blog-api/internal/lib/api/response"
+
+	"github.com/go-chi/jwtauth/v5"
+	"github.com/go-chi/render"
+)
+
+// Authenticator rejects requests without a valid token. Like jwtauth.Authenticator
+// it expects jwtauth.Verifier to run first, but answers with the usual JSON error
+func Authenticator(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token, _, err := jwtauth.FromContext(r.Context()); err != nil || token == nil {
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, resp.Err(r, resp.CodeUnauthorized, "unauthorized"))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+)
+
+type basePathKey struct{}
+
+// BasePath remembers the prefix the API is mounted under so that handlers can build links with Path
+func BasePath(prefix string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), basePathKey{}, prefix)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// Path prefixes p with the base path of the API, e.g. for Location headers
+func Path(r *http.Request, p string) string {
+	prefix, _ := r.Context().Value(basePathKey{}).(string)
+	return prefix + p
+}
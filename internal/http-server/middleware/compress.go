@@ -0,0 +1,181 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// compressibleTypes are the content types Compress gzips, other responses such as
+// event streams or already compressed files are sent as they are
+var compressibleTypes = map[string]bool{
+	"application/json": true,
+	"application/xml":  true,
+	"text/xml":         true,
+	"text/plain":       true,
+	"text/html":        true,
+}
+
+// Compress gzips responses for clients that accept it. Responses shorter than minSize,
+// of other content types or already encoded by the handler are sent as they are
+func Compress(level, minSize int) func(http.Handler) http.Handler {
+	pool := sync.Pool{
+		New: func() any {
+			// The level is validated with the config
+			gz, _ := gzip.NewWriterLevel(nil, level)
+			return gz
+		},
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Accept-Encoding")
+
+			if !acceptsGzip(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			gw := &gzipWriter{ResponseWriter: w, pool: &pool, minSize: minSize}
+			defer gw.close()
+
+			next.ServeHTTP(gw, r)
+		})
+	}
+}
+
+// gzipWriter buffers the body until it reaches minSize, then decides whether to compress it
+type gzipWriter struct {
+	http.ResponseWriter
+	pool    *sync.Pool
+	minSize int
+
+	status      int
+	wroteHeader bool
+	started     bool
+	buf         []byte
+	gz          *gzip.Writer
+}
+
+func (g *gzipWriter) WriteHeader(status int) {
+	if g.wroteHeader {
+		return
+	}
+	g.wroteHeader = true
+	g.status = status
+
+	// These have no body to compress
+	if status < http.StatusOK || status == http.StatusNoContent || status == http.StatusNotModified {
+		g.start(false)
+	}
+}
+
+func (g *gzipWriter) Write(p []byte) (int, error) {
+	if !g.wroteHeader {
+		g.WriteHeader(http.StatusOK)
+	}
+
+	if !g.started {
+		g.buf = append(g.buf, p...)
+		if len(g.buf) < g.minSize {
+			return len(p), nil
+		}
+		if err := g.start(true); err != nil {
+			return 0, err
+		}
+		return len(p), nil
+	}
+
+	if g.gz != nil {
+		return g.gz.Write(p)
+	}
+	return g.ResponseWriter.Write(p)
+}
+
+// Flush sends what is buffered, uncompressed if it's still shorter than minSize
+func (g *gzipWriter) Flush() {
+	if !g.wroteHeader {
+		g.WriteHeader(http.StatusOK)
+	}
+	if !g.started {
+		g.start(false)
+	}
+	if g.gz != nil {
+		g.gz.Flush()
+	}
+	if f, ok := g.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (g *gzipWriter) Unwrap() http.ResponseWriter {
+	return g.ResponseWriter
+}
+
+// start writes the header and the buffered body, compressing from now on
+// if compress is set and the response is of a compressible type
+func (g *gzipWriter) start(compress bool) error {
+	g.started = true
+
+	h := g.ResponseWriter.Header()
+	if compress && h.Get("Content-Encoding") == "" && compressible(h.Get("Content-Type")) {
+		h.Del("Content-Length")
+		h.Set("Content-Encoding", "gzip")
+
+		g.gz = g.pool.Get().(*gzip.Writer)
+		g.gz.Reset(g.ResponseWriter)
+	}
+
+	g.ResponseWriter.WriteHeader(g.status)
+
+	buf := g.buf
+	g.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+
+	var err error
+	if g.gz != nil {
+		_, err = g.gz.Write(buf)
+	} else {
+		_, err = g.ResponseWriter.Write(buf)
+	}
+	return err
+}
+
+func (g *gzipWriter) close() {
+	if g.wroteHeader && !g.started {
+		g.start(false)
+	}
+	if g.gz != nil {
+		g.gz.Close()
+		g.pool.Put(g.gz)
+	}
+}
+
+func compressible(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && compressibleTypes[mediaType]
+}
+
+// acceptsGzip reports whether Accept-Encoding lists gzip without q=0
+func acceptsGzip(r *http.Request) bool {
+	for _, enc := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		name, params, _ := strings.Cut(enc, ";")
+		if strings.TrimSpace(name) != "gzip" {
+			continue
+		}
+
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			weight, err := strconv.ParseFloat(q, 64)
+			return err == nil && weight > 0
+		}
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"log/slog"
+	"net"
+	"net/http"
+
+	"blog-api/internal/lib/api/request"
+	"blog-api/internal/lib/logger"
+
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// geoRecord is the part of a GeoLite2-City record that ends up in the logs
+type geoRecord struct {
+	Country struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+	City struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"city"`
+}
+
+// Geo looks up the client IP in a GeoLite2-City database and adds geo_country
+// and geo_city to the logs of the request. It does nothing when db is nil.
+// Must come after middleware.RealIP to see the client address behind a proxy
+func Geo(db *maxminddb.Reader) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if db == nil {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip := net.ParseIP(request.ClientIP(r))
+			if ip == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// Addresses missing from the database, e.g. private ones, leave the record empty
+			var rec geoRecord
+			if err := db.Lookup(ip, &rec); err != nil || rec.Country.ISOCode == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx := logger.WithAttrs(r.Context(),
+				slog.String("geo_country", rec.Country.ISOCode),
+				slog.String("geo_city", rec.City.Names["en"]),
+			)
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"mime"
+	"net/http"
+
+	resp "blog-api/internal/lib/api/response"
+
+	"github.com/go-chi/render"
+)
+
+// RequireJSON answers 415 to POST, PUT and PATCH requests with a body that isn't application/json.
+// Requests without a body are let through
+func RequireJSON(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost, http.MethodPut, http.MethodPatch:
+			if r.ContentLength == 0 {
+				break
+			}
+
+			mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+			if err != nil || mediaType != "application/json" {
+				render.Status(r, http.StatusUnsupportedMediaType)
+				render.JSON(w, r, resp.Err(r, resp.CodeUnsupportedMedia, "content type must be application/json"))
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
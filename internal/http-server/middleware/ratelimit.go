@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	req "blog-api/internal/lib/api/request"
+	resp "blog-api/internal/lib/api/response"
+
+	"github.com/go-chi/render"
+)
+
+// RateLimit lets each client IP make at most n requests per window and answers 429 to the rest.
+// Counters live in memory and are reset together when the window ends
+func RateLimit(n int, window time.Duration) func(http.Handler) http.Handler {
+	var (
+		mu      sync.Mutex
+		start   = time.Now()
+		counts  = make(map[string]int)
+		seconds = strconv.Itoa(int(window.Seconds()))
+	)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip := req.ClientIP(r)
+
+			mu.Lock()
+			if time.Since(start) >= window {
+				start = time.Now()
+				clear(counts)
+			}
+			counts[ip]++
+			limited := counts[ip] > n
+			mu.Unlock()
+
+			if limited {
+				w.Header().Set("Retry-After", seconds)
+				render.Status(r, http.StatusTooManyRequests)
+				render.JSON(w, r, resp.Err(r, resp.CodeRateLimited, "too many requests"))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"net/http"
+
+	resp "blog-api/internal/lib/api/response"
+
+	"github.com/go-chi/render"
+)
+
+// ReadOnlyWhile answers 503 to requests that may modify data while busy reports true
+func ReadOnlyWhile(busy func() bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if Mutating(r) && busy() {
+				w.Header().Set("Retry-After", "30")
+				render.Status(r, http.StatusServiceUnavailable)
+				render.JSON(w, r, resp.Err(r, resp.CodeReadOnly, "service is read-only during maintenance"))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
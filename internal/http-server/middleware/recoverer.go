@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"sync/atomic"
+
+	resp "blog-api/internal/lib/api/response"
+	"blog-api/internal/lib/logger"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+)
+
+// panics counts the panics Recoverer answered since the last RecoveredPanics call
+var panics atomic.Int64
+
+// RecoveredPanics returns how many handler panics were recovered since the last call
+func RecoveredPanics() int64 {
+	return panics.Swap(0)
+}
+
+// Recoverer turns a panic in a handler into a 500 with the usual JSON error and the request id,
+// and logs it with the stack trace. If the handler already started the response, its status
+// and headers can't change anymore, so the panic is only logged
+func Recoverer(log *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+			defer func() {
+				p := recover()
+				if p == nil {
+					return
+				}
+				stack := debug.Stack()
+				// Raised again by Timeout, the handler's own stack is inside
+				if hp, ok := p.(handlerPanic); ok {
+					p, stack = hp.value, hp.stack
+				}
+				// Aborts the response on purpose, net/http handles it
+				if p == http.ErrAbortHandler {
+					panic(p)
+				}
+				panics.Add(1)
+
+				reqID := middleware.GetReqID(r.Context())
+				logger.FromContext(r.Context(), log).Error("handler panicked",
+					slog.String("panic", fmt.Sprint(p)),
+					slog.String("request_id", reqID),
+					slog.String("stack", string(stack)),
+					slog.Bool("response_started", ww.Status() != 0),
+				)
+
+				if ww.Status() != 0 {
+					return
+				}
+
+				response := resp.Err(r, resp.CodeInternal, "internal error")
+				response.RequestID = reqID
+				render.Status(r, http.StatusInternalServerError)
+				render.JSON(ww, r, response)
+			}()
+
+			next.ServeHTTP(ww, r)
+		})
+	}
+}
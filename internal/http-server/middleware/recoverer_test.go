@@ -0,0 +1,128 @@
+package middleware_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	mw "blog-api/internal/http-server/middleware"
+	resp "blog-api/internal/lib/api/response"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// newRecoverer wraps h in Recoverer behind chi's RequestID, as main does
+func newRecoverer(h http.HandlerFunc) http.Handler {
+	return middleware.RequestID(mw.Recoverer(slog.New(slog.NewTextHandler(io.Discard, nil)))(h))
+}
+
+func TestRecovererBeforeWrite(t *testing.T) {
+	var reqID string
+	h := newRecoverer(func(w http.ResponseWriter, r *http.Request) {
+		reqID = middleware.GetReqID(r.Context())
+		panic("boom")
+	})
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/articles", nil))
+
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusInternalServerError)
+	}
+	var res resp.Response
+	if err := json.NewDecoder(w.Body).Decode(&res); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if res.Status != resp.StatusError || res.Code != resp.CodeInternal {
+		t.Errorf("response = %+v, want an error with code %q", res, resp.CodeInternal)
+	}
+	if reqID == "" || res.RequestID != reqID {
+		t.Errorf("request id = %q, want the request's %q", res.RequestID, reqID)
+	}
+}
+
+func TestRecovererAfterPartialWrite(t *testing.T) {
+	h := newRecoverer(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("partial"))
+		panic("boom")
+	})
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/articles", nil))
+
+	// The status is already sent, so nothing is appended to the body
+	if w.Code != http.StatusOK || w.Body.String() != "partial" {
+		t.Errorf("response = %d %q, want the handler's 200 \"partial\" untouched", w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "text/plain" {
+		t.Errorf("Content-Type = %q, want the handler's text/plain", ct)
+	}
+}
+
+func TestRecovererAbortHandler(t *testing.T) {
+	mw.RecoveredPanics()
+	defer func() {
+		if n := mw.RecoveredPanics(); n != 0 {
+			t.Errorf("RecoveredPanics() = %d, want aborts left out", n)
+		}
+	}()
+
+	h := newRecoverer(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	})
+
+	defer func() {
+		if p := recover(); p != http.ErrAbortHandler {
+			t.Errorf("recovered %v, want %v", p, http.ErrAbortHandler)
+		}
+	}()
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/articles", nil))
+	t.Error("ServeHTTP() returned, want http.ErrAbortHandler to reach net/http")
+}
+
+// panickingHandler panics on every request, tests look for its name in the logged stack
+func panickingHandler(w http.ResponseWriter, r *http.Request) {
+	panic("boom")
+}
+
+func TestRecovererThroughTimeout(t *testing.T) {
+	mw.RecoveredPanics()
+
+	var logs bytes.Buffer
+	log := slog.New(slog.NewJSONHandler(&logs, nil))
+	h := mw.Recoverer(log)(mw.Timeout(time.Second)(http.HandlerFunc(panickingHandler)))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/articles", nil))
+
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusInternalServerError)
+	}
+
+	var entry struct {
+		Panic string `json:"panic"`
+		Stack string `json:"stack"`
+	}
+	if err := json.Unmarshal(logs.Bytes(), &entry); err != nil {
+		t.Fatalf("failed to decode log entry %q: %v", logs.String(), err)
+	}
+	if entry.Panic != "boom" {
+		t.Errorf("logged panic = %q, want %q", entry.Panic, "boom")
+	}
+	// Timeout runs the handler in another goroutine, the stack must still be the handler's
+	if !strings.Contains(entry.Stack, "middleware_test.panickingHandler") {
+		t.Errorf("logged stack doesn't name the panicking handler:\n%s", entry.Stack)
+	}
+	if n := mw.RecoveredPanics(); n != 1 {
+		t.Errorf("RecoveredPanics() = %d, want 1", n)
+	}
+}
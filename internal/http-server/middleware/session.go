@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	resp "blog-api/internal/lib/api/response"
+	"blog-api/internal/lib/jwt"
+
+	"github.com/go-chi/jwtauth/v5"
+	"github.com/go-chi/render"
+)
+
+// ActiveSession rejects valid tokens whose login session is no longer active.
+// It expects jwtauth.Verifier to run first. Requests without a valid token
+// are passed on, routes requiring one reject them with Authenticator
+func ActiveSession(active func(ctx context.Context, sid int64) (bool, error)) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if token, _, err := jwtauth.FromContext(r.Context()); err != nil || token == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// Tokens issued before sessions existed have no sid
+			sid, err := jwt.SessionID(r.Context())
+			if err != nil {
+				render.Status(r, http.StatusUnauthorized)
+				render.JSON(w, r, resp.Err(r, resp.CodeSessionExpired, "session expired, log in again"))
+				return
+			}
+
+			ok, err := active(r.Context(), sid)
+			if err != nil {
+				render.Status(r, http.StatusInternalServerError)
+				render.JSON(w, r, resp.Err(r, resp.CodeInternal, "internal error"))
+				return
+			}
+			if !ok {
+				render.Status(r, http.StatusUnauthorized)
+				render.JSON(w, r, resp.Err(r, resp.CodeSessionExpired, "session expired, log in again"))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,238 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	resp "blog-api/internal/lib/api/response"
+
+	"github.com/go-chi/render"
+)
+
+// Timeouts configures RouteTimeout
+type Timeouts struct {
+	// Read limits requests that don't modify data, Write the ones that may
+	Read  time.Duration
+	Write time.Duration
+	// Routes overrides both for paths under a prefix such as "/articles/trending", the longest one wins.
+	// Zero means no limit, not even the server's write timeout
+	Routes map[string]time.Duration
+	// Streams are the paths of live event streams such as "/articles/stream", which are never limited
+	Streams []string
+	// BasePath is left out of the request path before it's matched against Routes and Streams
+	BasePath string
+}
+
+// RouteTimeout wraps every request in Timeout with the duration t picks for it.
+// Event streams are never limited
+func RouteTimeout(t Timeouts) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		limited := make(map[time.Duration]http.Handler)
+		limit := func(d time.Duration) http.Handler {
+			if _, ok := limited[d]; !ok {
+				limited[d] = Timeout(d)(next)
+			}
+			return limited[d]
+		}
+
+		// Built upfront, so that requests only read the map
+		read, write := limit(t.Read), limit(t.Write)
+		for _, d := range t.Routes {
+			if d > 0 {
+				limit(d)
+			}
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			path := t.relative(r.URL.Path)
+
+			// Streams write as they go, Timeout would buffer them
+			if slices.Contains(t.Streams, path) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if d, ok := t.route(path); ok {
+				if d <= 0 {
+					// The server-wide write timeout would cut the response off anyway
+					_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+					next.ServeHTTP(w, r)
+					return
+				}
+				limited[d].ServeHTTP(w, r)
+				return
+			}
+
+			if Mutating(r) {
+				write.ServeHTTP(w, r)
+				return
+			}
+			read.ServeHTTP(w, r)
+		})
+	}
+}
+
+// relative leaves t.BasePath out of path
+func (t Timeouts) relative(path string) string {
+	if t.BasePath != "" && (path == t.BasePath || strings.HasPrefix(path, t.BasePath+"/")) {
+		return strings.TrimPrefix(path, t.BasePath)
+	}
+
+	return path
+}
+
+// route returns the timeout of the longest prefix in t.Routes that path is under
+func (t Timeouts) route(path string) (time.Duration, bool) {
+	var (
+		best  string
+		d     time.Duration
+		found bool
+	)
+	for prefix, limit := range t.Routes {
+		prefix = strings.TrimSuffix(prefix, "/")
+		if path != prefix && !strings.HasPrefix(path, prefix+"/") {
+			continue
+		}
+		if !found || len(prefix) > len(best) {
+			best, d, found = prefix, limit, true
+		}
+	}
+
+	return d, found
+}
+
+// Timeout cancels the request context after d and answers 504 if the handler hasn't
+// finished by then. It also moves the server write deadline to match, so a route may take
+// longer than the server-wide timeout. The response is buffered until the handler returns,
+// so streaming handlers must not be wrapped
+func Timeout(d time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Leave a moment to write the timeout response itself
+			_ = http.NewResponseController(w).SetWriteDeadline(time.Now().Add(d + time.Second))
+
+			ctx, cancel := context.WithTimeout(r.Context(), d)
+			defer cancel()
+
+			// The handler gets its own copy, render.Status below replaces *r
+			hr := r.WithContext(ctx)
+			tw := &timeoutWriter{ctx: ctx, header: make(http.Header)}
+			done := make(chan struct{})
+			panicked := make(chan any, 1)
+
+			go func() {
+				defer func() {
+					if p := recover(); p != nil {
+						// The stack is only there while the handler's goroutine unwinds
+						if p != http.ErrAbortHandler {
+							p = handlerPanic{value: p, stack: debug.Stack()}
+						}
+						panicked <- p
+					}
+				}()
+				next.ServeHTTP(tw, hr)
+				close(done)
+			}()
+
+			select {
+			case p := <-panicked:
+				// Let the recoverer up the chain handle it
+				panic(p)
+			case <-done:
+			case <-ctx.Done():
+			}
+
+			tw.mu.Lock()
+			defer tw.mu.Unlock()
+
+			// Both may be ready at once, a handler that returned after the deadline timed out too
+			if ctx.Err() != nil {
+				tw.timedOut = true
+				render.Status(r, http.StatusGatewayTimeout)
+				render.JSON(w, r, resp.Err(r, resp.CodeTimeout, "request timed out"))
+				return
+			}
+
+			for k, v := range tw.header {
+				w.Header()[k] = v
+			}
+			if tw.status == 0 {
+				tw.status = http.StatusOK
+			}
+			w.WriteHeader(tw.status)
+			w.Write(tw.buf.Bytes())
+		})
+	}
+}
+
+// handlerPanic is a panic Timeout recovered in the handler's goroutine and raised again in the
+// request's one, with the stack of the handler. Recoverer unwraps it
+type handlerPanic struct {
+	value any
+	stack []byte
+}
+
+func (p handlerPanic) String() string {
+	return fmt.Sprint(p.value)
+}
+
+// timeoutWriter holds the handler's response until Timeout knows it made it in time
+type timeoutWriter struct {
+	// ctx is the handler's, writes fail once it's done
+	ctx      context.Context
+	mu       sync.Mutex
+	header   http.Header
+	buf      bytes.Buffer
+	status   int
+	timedOut bool
+}
+
+func (tw *timeoutWriter) Header() http.Header {
+	return tw.header
+}
+
+func (tw *timeoutWriter) Write(p []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if tw.timedOut || tw.ctx.Err() != nil {
+		tw.timedOut = true
+		return 0, http.ErrHandlerTimeout
+	}
+	if tw.status == 0 {
+		tw.status = http.StatusOK
+	}
+
+	return tw.buf.Write(p)
+}
+
+func (tw *timeoutWriter) WriteHeader(status int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if tw.timedOut || tw.ctx.Err() != nil {
+		tw.timedOut = true
+		return
+	}
+	if tw.status != 0 {
+		return
+	}
+	tw.status = status
+}
+
+// Mutating reports whether the request may modify data
+func Mutating(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return false
+	default:
+		return true
+	}
+}
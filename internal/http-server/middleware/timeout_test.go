@@ -0,0 +1,238 @@
+package middleware_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mw "blog-api/internal/http-server/middleware"
+	resp "blog-api/internal/lib/api/response"
+)
+
+// slowHandler blocks until its request is cancelled, then reports the context error
+// and what writing the response returned
+type slowHandler struct {
+	ctxErr   chan error
+	writeErr chan error
+}
+
+func newSlowHandler() *slowHandler {
+	return &slowHandler{
+		ctxErr:   make(chan error, 1),
+		writeErr: make(chan error, 1),
+	}
+}
+
+func (h *slowHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	<-r.Context().Done()
+	h.ctxErr <- r.Context().Err()
+
+	_, err := w.Write([]byte("too late"))
+	h.writeErr <- err
+}
+
+// receive fails the test unless ch receives within a second
+func receive[T any](t *testing.T, ch <-chan T) T {
+	t.Helper()
+
+	select {
+	case v := <-ch:
+		return v
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for the handler")
+		var zero T
+		return zero
+	}
+}
+
+func TestTimeoutFastHandler(t *testing.T) {
+	h := mw.Timeout(time.Second)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Test", "1")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("done"))
+	}))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/articles", nil))
+
+	if w.Code != http.StatusCreated || w.Body.String() != "done" || w.Header().Get("X-Test") != "1" {
+		t.Errorf("response = %d %q with X-Test %q, want the handler's 201 \"done\" with X-Test 1", w.Code, w.Body.String(), w.Header().Get("X-Test"))
+	}
+}
+
+func TestTimeoutSlowHandler(t *testing.T) {
+	slow := newSlowHandler()
+	h := mw.Timeout(10 * time.Millisecond)(slow)
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/articles", nil))
+
+	if w.Code != http.StatusGatewayTimeout {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusGatewayTimeout)
+	}
+	var res resp.Response
+	if err := json.NewDecoder(w.Body).Decode(&res); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if res.Status != resp.StatusError || res.Code != resp.CodeTimeout {
+		t.Errorf("response = %+v, want an error with code %q", res, resp.CodeTimeout)
+	}
+
+	// The handler sees its context cancelled and can't write over the 504
+	if err := receive(t, slow.ctxErr); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("handler context error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if err := receive(t, slow.writeErr); !errors.Is(err, http.ErrHandlerTimeout) {
+		t.Errorf("late write error = %v, want %v", err, http.ErrHandlerTimeout)
+	}
+}
+
+func TestTimeoutClientGone(t *testing.T) {
+	slow := newSlowHandler()
+	h := mw.Timeout(time.Minute)(slow)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r := httptest.NewRequest(http.MethodGet, "/articles", nil).WithContext(ctx)
+
+	served := make(chan struct{})
+	go func() {
+		h.ServeHTTP(httptest.NewRecorder(), r)
+		close(served)
+	}()
+
+	// A client that goes away cancels the handler long before the timeout
+	cancel()
+	if err := receive(t, slow.ctxErr); !errors.Is(err, context.Canceled) {
+		t.Errorf("handler context error = %v, want %v", err, context.Canceled)
+	}
+	receive(t, served)
+}
+
+func TestTimeoutPanic(t *testing.T) {
+	h := mw.Timeout(time.Second)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}))
+
+	defer func() {
+		if p := recover(); fmt.Sprint(p) != "boom" {
+			t.Errorf("recovered %v, want the handler's panic", p)
+		}
+	}()
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/articles", nil))
+	t.Error("ServeHTTP() returned, want the panic to reach the caller")
+}
+
+func TestRouteTimeoutStream(t *testing.T) {
+	timeouts := mw.Timeouts{
+		Read:     10 * time.Millisecond,
+		Write:    10 * time.Millisecond,
+		Streams:  []string{"/articles/stream"},
+		BasePath: "/api/v1",
+	}
+
+	tests := []struct {
+		name       string
+		path       string
+		wantStatus int
+	}{
+		{name: "stream", path: "/api/v1/articles/stream", wantStatus: http.StatusOK},
+		// Only the registered stream is exempt, not every path ending the same way
+		{name: "other path ending in stream", path: "/api/v1/users/alice/stream", wantStatus: http.StatusGatewayTimeout},
+		{name: "path under the stream", path: "/api/v1/articles/stream/x", wantStatus: http.StatusGatewayTimeout},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := mw.RouteTimeout(timeouts)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				// A stream writes to the connection itself and outlives the read timeout
+				select {
+				case <-r.Context().Done():
+					return
+				case <-time.After(30 * time.Millisecond):
+				}
+				if _, ok := w.(http.Flusher); !ok {
+					t.Error("stream got a buffered writer")
+				}
+				w.Write([]byte("event"))
+			}))
+
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestRouteTimeoutRoutes(t *testing.T) {
+	timeouts := mw.Timeouts{
+		Read:  10 * time.Millisecond,
+		Write: 10 * time.Millisecond,
+		Routes: map[string]time.Duration{
+			"/articles/search": 0,
+		},
+		BasePath: "/api/v1",
+	}
+
+	tests := []struct {
+		name       string
+		path       string
+		wantStatus int
+	}{
+		{name: "default read timeout", path: "/api/v1/articles", wantStatus: http.StatusGatewayTimeout},
+		{name: "route without limit", path: "/api/v1/articles/search", wantStatus: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := mw.RouteTimeout(timeouts)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				select {
+				case <-r.Context().Done():
+				case <-time.After(30 * time.Millisecond):
+					w.WriteHeader(http.StatusOK)
+				}
+			}))
+
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestRouteTimeoutUnlimitedOutlivesServer(t *testing.T) {
+	timeouts := mw.Timeouts{
+		Read:   time.Second,
+		Routes: map[string]time.Duration{"/export": 0},
+	}
+	h := mw.RouteTimeout(timeouts)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(50 * time.Millisecond)
+		w.Write([]byte("done"))
+	}))
+
+	srv := httptest.NewUnstartedServer(h)
+	srv.Config.WriteTimeout = 10 * time.Millisecond
+	srv.Start()
+	defer srv.Close()
+
+	res, err := srv.Client().Get(srv.URL + "/export")
+	if err != nil {
+		t.Fatalf("GET /export error = %v, want the response despite the server write timeout", err)
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil || string(body) != "done" {
+		t.Errorf("body = %q (%v), want \"done\"", body, err)
+	}
+}
@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"net/http"
+
+	"blog-api/internal/lib/jwt"
+
+	"github.com/go-chi/jwtauth/v5"
+)
+
+// Verifier works like jwtauth.Verifier, but checks the token with the key its kid names,
+// so that tokens signed with a retired key stay valid until they expire
+func Verifier(keys *jwt.KeyProvider) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Routers mounted under one that already verified the token don't repeat it
+			if token, _, err := jwtauth.FromContext(r.Context()); err == nil && token != nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			tokenString := jwtauth.TokenFromHeader(r)
+			if tokenString == "" {
+				tokenString = jwtauth.TokenFromCookie(r)
+			}
+			if tokenString == "" {
+				ctx := jwtauth.NewContext(r.Context(), nil, jwtauth.ErrNoTokenFound)
+				next.ServeHTTP(w, r.WithContext(ctx))
+				return
+			}
+
+			token, err := keys.Verify(tokenString)
+
+			ctx := jwtauth.NewContext(r.Context(), token, err)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
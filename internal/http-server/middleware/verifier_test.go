@@ -0,0 +1,53 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"blog-api/internal/domain/models"
+	mw "blog-api/internal/http-server/middleware"
+	"blog-api/internal/lib/jwt"
+)
+
+func TestVerifierRetiredKey(t *testing.T) {
+	oldKeys, err := jwt.LoadKeys(jwt.HS256, "old secret", nil, "", "")
+	if err != nil {
+		t.Fatalf("failed to load keys: %v", err)
+	}
+	token, err := jwt.NewToken(models.User{ID: 1, Role: models.RoleUser}, 1, time.Hour, oldKeys)
+	if err != nil {
+		t.Fatalf("failed to issue token: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		previous   []string
+		wantStatus int
+	}{
+		{name: "key retired", previous: []string{"old secret"}, wantStatus: http.StatusOK},
+		{name: "key removed", wantStatus: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys, err := jwt.LoadKeys(jwt.HS256, "new secret", tt.previous, "", "")
+			if err != nil {
+				t.Fatalf("failed to load keys: %v", err)
+			}
+			h := mw.Verifier(keys)(mw.Authenticator(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			})))
+
+			r := httptest.NewRequest(http.MethodGet, "/articles", nil)
+			r.Header.Set("Authorization", "Bearer "+token)
+			w := httptest.NewRecorder()
+
+			h.ServeHTTP(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+		})
+	}
+}
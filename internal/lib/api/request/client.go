@@ -0,0 +1,16 @@
+package request
+
+import (
+	"net"
+	"net/http"
+)
+
+// ClientIP returns the client address without the port.
+// Behind a proxy it relies on middleware.RealIP having put X-Real-IP into RemoteAddr
+func ClientIP(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+
+	return r.RemoteAddr
+}
@@ -0,0 +1,36 @@
+package request
+
+import (
+	"errors"
+	"net/http"
+	"time"
+)
+
+// DateRange parses "from" and "to" query params as YYYY-MM-DD dates.
+// A missing bound leaves the range open on that side, "to" includes the whole day
+func DateRange(r *http.Request) (from, to time.Time, err error) {
+	q := r.URL.Query()
+
+	from = time.Time{}
+	to = time.Date(9999, 12, 31, 0, 0, 0, 0, time.UTC)
+
+	if f := q.Get("from"); f != "" {
+		from, err = time.Parse(time.DateOnly, f)
+		if err != nil {
+			return time.Time{}, time.Time{}, errors.New("invalid from date, expected YYYY-MM-DD")
+		}
+	}
+
+	if t := q.Get("to"); t != "" {
+		to, err = time.Parse(time.DateOnly, t)
+		if err != nil {
+			return time.Time{}, time.Time{}, errors.New("invalid to date, expected YYYY-MM-DD")
+		}
+	}
+
+	if from.After(to) {
+		return time.Time{}, time.Time{}, errors.New("from date is after to date")
+	}
+
+	return from, to.Add(24*time.Hour - time.Nanosecond), nil
+}
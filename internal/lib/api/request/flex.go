@@ -0,0 +1,61 @@
+package request
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strconv"
+)
+
+// FlexInt is an int that clients may also send as a numeric string, e.g. "5"
+type FlexInt int
+
+func (n *FlexInt) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+
+	raw := string(data)
+	kind := "number"
+	if s, err := strconv.Unquote(raw); err == nil {
+		raw = s
+		kind = "string"
+	}
+
+	v, err := strconv.Atoi(raw)
+	if err != nil {
+		return &json.UnmarshalTypeError{Value: kind, Type: reflect.TypeOf(0)}
+	}
+	*n = FlexInt(v)
+
+	return nil
+}
+
+// decodeField decodes a FlexInt field. The decoder leaves the field name out of
+// errors returned by UnmarshalJSON, so it's set here
+func decodeField(name string, raw json.RawMessage, n *FlexInt) error {
+	if raw == nil {
+		return nil
+	}
+
+	if err := n.UnmarshalJSON(raw); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			typeErr.Field = name
+		}
+		return err
+	}
+
+	return nil
+}
+
+// InvalidField returns the name of the body field whose value has the wrong type,
+// e.g. "abc" for an id, when err is such a decoding error
+func InvalidField(err error) (string, bool) {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return typeErr.Field, true
+	}
+
+	return "", false
+}
@@ -0,0 +1,58 @@
+package request_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"blog-api/internal/lib/api/request"
+)
+
+func TestFlexInt(t *testing.T) {
+	tests := []struct {
+		name      string
+		body      string
+		wantID    request.FlexInt
+		wantVer   request.FlexInt
+		wantField string
+	}{
+		{name: "numbers", body: `{"author_id":5,"version":2}`, wantID: 5, wantVer: 2},
+		{name: "numeric strings", body: `{"author_id":"5","version":"2"}`, wantID: 5, wantVer: 2},
+		{name: "null and missing", body: `{"author_id":null}`},
+		{name: "non-numeric author_id", body: `{"author_id":"abc"}`, wantField: "author_id"},
+		{name: "non-numeric version", body: `{"version":"abc"}`, wantField: "version"},
+		{name: "fractional version", body: `{"version":1.5}`, wantField: "version"},
+		{name: "boolean version", body: `{"version":true}`, wantField: "version"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var art request.Article
+			err := json.Unmarshal([]byte(tt.body), &art)
+
+			if tt.wantField != "" {
+				field, ok := request.InvalidField(err)
+				if !ok || field != tt.wantField {
+					t.Fatalf("InvalidField(%v) = %q, %t, want %q, true", err, field, ok, tt.wantField)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Unmarshal() error = %v", err)
+			}
+			if art.AuthorID != tt.wantID || art.Version != tt.wantVer {
+				t.Errorf("author_id, version = %d, %d, want %d, %d", art.AuthorID, art.Version, tt.wantID, tt.wantVer)
+			}
+		})
+	}
+}
+
+func TestInvalidFieldOtherErrors(t *testing.T) {
+	var art request.Article
+	err := json.Unmarshal([]byte(`{"title":`), &art)
+	if err == nil {
+		t.Fatal("Unmarshal() of broken JSON error = nil")
+	}
+	if field, ok := request.InvalidField(err); ok {
+		t.Errorf("InvalidField(%v) = %q, true, want false for a syntax error", err, field)
+	}
+}
@@ -0,0 +1,43 @@
+package request
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+)
+
+var (
+	ErrInvalidLimit  = errors.New("invalid limit: must be an integer between 1 and 100")
+	ErrInvalidOffset = errors.New("invalid offset: must be a non-negative integer")
+)
+
+// Pagination reads "limit" and "offset" query params, falling back to defaults when they are omitted
+func Pagination(r *http.Request) (limit, offset int, err error) {
+	return PaginationWithLimit(r, DefaultLimit)
+}
+
+// PaginationWithLimit is Pagination with a different default limit
+func PaginationWithLimit(r *http.Request, defaultLimit int) (limit, offset int, err error) {
+	limit, offset = defaultLimit, 0
+
+	if l := r.URL.Query().Get("limit"); l != "" {
+		limit, err = strconv.Atoi(l)
+		if err != nil || limit < 1 || limit > MaxLimit {
+			return 0, 0, ErrInvalidLimit
+		}
+	}
+
+	if o := r.URL.Query().Get("offset"); o != "" {
+		offset, err = strconv.Atoi(o)
+		if err != nil || offset < 0 {
+			return 0, 0, ErrInvalidOffset
+		}
+	}
+
+	return limit, offset, nil
+}
@@ -0,0 +1,42 @@
+package request_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"blog-api/internal/lib/api/request"
+)
+
+func TestPagination(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      string
+		wantLimit  int
+		wantOffset int
+		wantErr    error
+	}{
+		{name: "defaults", query: "", wantLimit: request.DefaultLimit},
+		{name: "limit and offset", query: "?limit=5&offset=10", wantLimit: 5, wantOffset: 10},
+		{name: "max limit", query: "?limit=100", wantLimit: request.MaxLimit},
+		{name: "zero limit", query: "?limit=0", wantErr: request.ErrInvalidLimit},
+		{name: "limit over max", query: "?limit=101", wantErr: request.ErrInvalidLimit},
+		{name: "limit not a number", query: "?limit=ten", wantErr: request.ErrInvalidLimit},
+		{name: "negative offset", query: "?offset=-1", wantErr: request.ErrInvalidOffset},
+		{name: "offset not a number", query: "?offset=x", wantErr: request.ErrInvalidOffset},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/users"+tt.query, nil)
+
+			limit, offset, err := request.Pagination(r)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Pagination() error = %v, want %v", err, tt.wantErr)
+			}
+			if limit != tt.wantLimit || offset != tt.wantOffset {
+				t.Errorf("Pagination() = %d, %d, want %d, %d", limit, offset, tt.wantLimit, tt.wantOffset)
+			}
+		})
+	}
+}
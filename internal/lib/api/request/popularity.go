@@ -0,0 +1,38 @@
+package request
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"blog-api/internal/domain/models"
+)
+
+var (
+	ErrInvalidMinLikes = errors.New("invalid min_likes: must be a non-negative integer")
+	ErrInvalidMinViews = errors.New("invalid min_views: must be a non-negative integer")
+)
+
+// Popularity reads the "min_likes" and "min_views" query params, omitted ones don't filter
+func Popularity(r *http.Request) (models.PopularityFilter, error) {
+	var (
+		popular models.PopularityFilter
+		err     error
+	)
+
+	if l := r.URL.Query().Get("min_likes"); l != "" {
+		popular.MinLikes, err = strconv.Atoi(l)
+		if err != nil || popular.MinLikes < 0 {
+			return models.PopularityFilter{}, ErrInvalidMinLikes
+		}
+	}
+
+	if v := r.URL.Query().Get("min_views"); v != "" {
+		popular.MinViews, err = strconv.Atoi(v)
+		if err != nil || popular.MinViews < 0 {
+			return models.PopularityFilter{}, ErrInvalidMinViews
+		}
+	}
+
+	return popular, nil
+}
@@ -1,11 +1,109 @@
 package request
 
+import (
+	"encoding/json"
+
+	"blog-api/internal/domain/models"
+)
+
+// Credentials are sent on register and login. On login user_name may hold an email as well
 type Credentials struct {
 	UserName string `json:"user_name,omitempty"`
+	Email    string `json:"email,omitempty"`
 	Password string `json:"password,omitempty"`
+	// RememberMe asks for a token that lasts longer, on login only
+	RememberMe bool `json:"remember_me,omitempty"`
+}
+
+// Article is the body of article create and update requests
+type Article struct {
+	Title        string `json:"title,omitempty"`
+	Content      string `json:"content,omitempty"`
+	Language     string `json:"language,omitempty"`
+	CanonicalURL string `json:"canonical_url,omitempty"`
+	Status       string `json:"status,omitempty"`
+	// AuthorID is ignored, articles are created by the token's user. It's only
+	// decoded to reject a mismatching one from older clients
+	AuthorID FlexInt `json:"author_id,omitempty"`
+	Version  FlexInt `json:"version,omitempty"`
+}
+
+func (a *Article) UnmarshalJSON(data []byte) error {
+	type plain Article
+	var body struct {
+		plain
+		AuthorID json.RawMessage `json:"author_id"`
+		Version  json.RawMessage `json:"version"`
+	}
+	if err := json.Unmarshal(data, &body); err != nil {
+		return err
+	}
+
+	*a = Article(body.plain)
+	if err := decodeField("author_id", body.AuthorID, &a.AuthorID); err != nil {
+		return err
+	}
+
+	return decodeField("version", body.Version, &a.Version)
+}
+
+func (a Article) Model() models.Article {
+	return models.Article{
+		Title:        a.Title,
+		Content:      a.Content,
+		Language:     a.Language,
+		CanonicalURL: a.CanonicalURL,
+		Status:       a.Status,
+		AuthorID:     int(a.AuthorID),
+		Version:      int(a.Version),
+	}
 }
 
 type Update struct {
 	UserName string `json:"user_name,omitempty"`
-	Status   string `json:"status,omitempty"`
+	Email    string `json:"email,omitempty"`
+	// Status is only changed when present, an empty one clears it
+	Status *string `json:"status,omitempty"`
+}
+
+type ForgotPassword struct {
+	Email string `json:"email"`
+}
+
+// ResetPassword sets a new password with the token from the reset email
+type ResetPassword struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"new_password"`
+}
+
+// MarkRead lists notifications to mark as read, empty means all of them
+type MarkRead struct {
+	IDs []int64 `json:"ids,omitempty"`
+}
+
+type BulkDelete struct {
+	IDs []int `json:"ids"`
+}
+
+// Webhook creates a webhook, or on update changes the fields that are present
+type Webhook struct {
+	URL    string   `json:"url,omitempty"`
+	Secret string   `json:"secret,omitempty"`
+	Events []string `json:"events,omitempty"`
+	Active *bool    `json:"active,omitempty"`
+}
+
+// Collection creates a collection, or on update changes the fields that are present
+type Collection struct {
+	Title       string  `json:"title,omitempty"`
+	Description *string `json:"description,omitempty"`
+	IsPublic    *bool   `json:"is_public,omitempty"`
+}
+
+type CollectionArticle struct {
+	ArticleID int `json:"article_id"`
+}
+
+type LogLevel struct {
+	Level string `json:"level"`
 }
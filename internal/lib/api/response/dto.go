@@ -0,0 +1,73 @@
+package response
+
+import (
+	"time"
+
+	"blog-api/internal/domain/models"
+)
+
+// UserDTO is the public representation of a user, it never carries credentials
+type UserDTO struct {
+	ID                 int64      `json:"id"`
+	UserName           string     `json:"user_name"`
+	RegistrationDate   *time.Time `json:"registration_date,omitempty"`
+	UpdatedAt          *time.Time `json:"updated_at,omitempty"`
+	Status             string     `json:"status,omitempty"`
+	TotalLikesReceived int        `json:"total_likes_received,omitempty"`
+	CurrentStreak      int        `json:"current_streak"`
+	LongestStreak      int        `json:"longest_streak"`
+}
+
+func NewUserDTO(user models.User) *UserDTO {
+	return &UserDTO{
+		ID:                 user.ID,
+		UserName:           user.UserName,
+		RegistrationDate:   user.RegistrationDate,
+		UpdatedAt:          user.UpdatedAt,
+		Status:             user.Status,
+		TotalLikesReceived: user.TotalLikesReceived,
+		CurrentStreak:      user.CurrentStreak,
+		LongestStreak:      user.LongestStreak,
+	}
+}
+
+type ArticleDTO struct {
+	ID           int        `json:"id"`
+	Title        string     `json:"title"`
+	Content      string     `json:"content"`
+	Language     string     `json:"language"`
+	CanonicalURL string     `json:"canonical_url,omitempty"`
+	PublishDate  *time.Time `json:"publish_date,omitempty"`
+	CreatedAt    *time.Time `json:"created_at,omitempty"`
+	UpdatedAt    *time.Time `json:"updated_at,omitempty"`
+	Status       string     `json:"status"`
+	AuthorID     int        `json:"author_id"`
+	Pinned       bool       `json:"pinned"`
+	Version      int        `json:"version"`
+	Views        int        `json:"views"`
+	Likes        int        `json:"likes"`
+	Dislikes     int        `json:"dislikes"`
+	Score        int        `json:"score"`
+	Author       *UserDTO   `json:"author,omitempty"`
+}
+
+func NewArticleDTO(art *models.Article) *ArticleDTO {
+	return &ArticleDTO{
+		ID:           art.ID,
+		Title:        art.Title,
+		Content:      art.Content,
+		Language:     art.Language,
+		CanonicalURL: art.CanonicalURL,
+		PublishDate:  art.PublishDate,
+		CreatedAt:    art.CreatedAt,
+		UpdatedAt:    art.UpdatedAt,
+		Status:       art.Status,
+		AuthorID:     art.AuthorID,
+		Pinned:       art.Pinned,
+		Version:      art.Version,
+		Views:        art.Views,
+		Likes:        art.Likes,
+		Dislikes:     art.Dislikes,
+		Score:        art.Score,
+	}
+}
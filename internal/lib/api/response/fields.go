@@ -0,0 +1,96 @@
+package response
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// articleFields read the article fields clients may pick with ?fields=, by their JSON name.
+// A table of getters keeps the selection free of reflection
+var articleFields = map[string]func(a *ArticleDTO) any{
+	"id":            func(a *ArticleDTO) any { return a.ID },
+	"title":         func(a *ArticleDTO) any { return a.Title },
+	"content":       func(a *ArticleDTO) any { return a.Content },
+	"language":      func(a *ArticleDTO) any { return a.Language },
+	"canonical_url": func(a *ArticleDTO) any { return a.CanonicalURL },
+	"publish_date":  func(a *ArticleDTO) any { return a.PublishDate },
+	"created_at":    func(a *ArticleDTO) any { return a.CreatedAt },
+	"updated_at":    func(a *ArticleDTO) any { return a.UpdatedAt },
+	"status":        func(a *ArticleDTO) any { return a.Status },
+	"author_id":     func(a *ArticleDTO) any { return a.AuthorID },
+	"pinned":        func(a *ArticleDTO) any { return a.Pinned },
+	"version":       func(a *ArticleDTO) any { return a.Version },
+	"views":         func(a *ArticleDTO) any { return a.Views },
+	"likes":         func(a *ArticleDTO) any { return a.Likes },
+	"dislikes":      func(a *ArticleDTO) any { return a.Dislikes },
+	"score":         func(a *ArticleDTO) any { return a.Score },
+}
+
+// ArticleFields parses a comma separated list of article fields such as "id,title,publish_date".
+// An empty list means all fields and is returned as nil
+func ArticleFields(list string) ([]string, error) {
+	if list == "" {
+		return nil, nil
+	}
+
+	var fields []string
+	for _, f := range strings.Split(list, ",") {
+		f = strings.TrimSpace(f)
+		if _, ok := articleFields[f]; !ok {
+			supported := make([]string, 0, len(articleFields))
+			for name := range articleFields {
+				supported = append(supported, name)
+			}
+			slices.Sort(supported)
+			return nil, fmt.Errorf("unknown field %q, supported: %s", f, strings.Join(supported, ", "))
+		}
+		if !slices.Contains(fields, f) {
+			fields = append(fields, f)
+		}
+	}
+
+	return fields, nil
+}
+
+// Partial is a Response whose articles only have the fields the client picked.
+// Its fields take precedence over the ones of the same name in Response
+type Partial struct {
+	Response
+	Article  map[string]any    `json:"article,omitempty"`
+	Articles *[]map[string]any `json:"articles,omitempty"`
+}
+
+// SelectFields keeps only the given fields of the articles in r, or returns r as is when fields is nil.
+// An included author is kept either way
+func SelectFields(r Response, fields []string) any {
+	if fields == nil {
+		return r
+	}
+
+	p := Partial{Response: r}
+	if r.Article != nil {
+		p.Article = partialArticle(r.Article, fields)
+	}
+	if r.Articles != nil {
+		articles := make([]map[string]any, 0, len(*r.Articles))
+		for i := range *r.Articles {
+			articles = append(articles, partialArticle(NewArticleDTO(&(*r.Articles)[i]), fields))
+		}
+		p.Articles = &articles
+	}
+
+	return p
+}
+
+func partialArticle(a *ArticleDTO, fields []string) map[string]any {
+	m := make(map[string]any, len(fields)+1)
+	for _, f := range fields {
+		m[f] = articleFields[f](a)
+	}
+	if a.Author != nil {
+		m["author"] = a.Author
+	}
+
+	return m
+}
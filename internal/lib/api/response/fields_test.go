@@ -0,0 +1,66 @@
+package response_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"blog-api/internal/domain/models"
+	resp "blog-api/internal/lib/api/response"
+)
+
+// benchArticles returns n published articles with a few paragraphs of content each
+func benchArticles(n int) []models.Article {
+	now := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	content := strings.Repeat("Lorem ipsum dolor sit amet, consectetur adipiscing elit. ", 40)
+
+	articles := make([]models.Article, n)
+	for i := range articles {
+		articles[i] = models.Article{
+			ID:          i + 1,
+			Title:       fmt.Sprintf("Article %d", i+1),
+			Content:     content,
+			Language:    "en",
+			Status:      models.ArticlePublished,
+			AuthorID:    i%10 + 1,
+			PublishDate: &now,
+			CreatedAt:   &now,
+			UpdatedAt:   &now,
+			Version:     1,
+		}
+	}
+
+	return articles
+}
+
+// BenchmarkSelectFields encodes a page of 100 articles as GET /articles does, in full
+// and with ?fields= picking a few of them
+func BenchmarkSelectFields(b *testing.B) {
+	articles := benchArticles(100)
+
+	for _, list := range []string{"", "id,title,publish_date", "id,title,content,author_id,views,likes"} {
+		name := "fields=" + list
+		if list == "" {
+			name = "all"
+		}
+
+		b.Run(name, func(b *testing.B) {
+			fields, err := resp.ArticleFields(list)
+			if err != nil {
+				b.Fatalf("ArticleFields(%q) error = %v", list, err)
+			}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				r := resp.Response{Status: resp.StatusOk, Articles: &articles}
+				if err := json.NewEncoder(io.Discard).Encode(resp.SelectFields(r, fields)); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
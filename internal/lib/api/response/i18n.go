@@ -0,0 +1,79 @@
+package response
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// defaultLanguage is the language of the messages handlers pass to Err
+const defaultLanguage = "en"
+
+// messages translate error codes into the other supported languages.
+// A translation replaces the whole message, so details of e.g. a validation error are lost
+var messages = map[string]map[string]string{
+	"ru": {
+		CodeInternal:           "внутренняя ошибка",
+		CodeValidationFailed:   "некорректные параметры запроса",
+		CodeInvalidBody:        "некорректное тело запроса",
+		CodeUnsupportedMedia:   "неподдерживаемый тип содержимого",
+		CodePayloadTooLarge:    "слишком большой запрос",
+		CodeNotFound:           "не найдено",
+		CodeMethodNotAllowed:   "метод не поддерживается",
+		CodeUnauthorized:       "требуется авторизация",
+		CodeInvalidCredentials: "неверное имя пользователя или пароль",
+		CodeSessionExpired:     "сессия истекла, войдите снова",
+		CodeForbidden:          "недостаточно прав",
+		CodeUserExists:         "имя пользователя уже занято",
+		CodeEmailTaken:         "email уже занят",
+		CodeArticleExists:      "статья с таким заголовком уже существует",
+		CodeConflict:           "конфликт с текущим состоянием ресурса",
+		CodeBackupCorrupted:    "резервная копия повреждена",
+		CodeRateLimited:        "слишком много запросов",
+		CodeTimeout:            "превышено время ожидания запроса",
+		CodeReadOnly:           "сервис доступен только для чтения на время обслуживания",
+		CodeUnavailable:        "сервис временно недоступен",
+		CodeAccountLocked:      "слишком много неудачных попыток входа, аккаунт временно заблокирован",
+		CodeVersionConflict:    "статья была изменена после того, как вы её открыли",
+		CodeVersionRequired:    "требуется версия статьи",
+	},
+}
+
+// Message returns errMsg translated into the language the client prefers by its Accept-Language header.
+// Without a matching translation errMsg is returned as is
+func Message(r *http.Request, code, errMsg string) string {
+	lang := language(r.Header.Get("Accept-Language"))
+	if msg, ok := messages[lang][code]; ok {
+		return msg
+	}
+	return errMsg
+}
+
+// language picks the supported language with the highest weight in an Accept-Language header,
+// e.g. "ru" for "ru-RU,ru;q=0.9,en;q=0.8". Regional variants match their base language
+func language(header string) string {
+	best, bestQ := defaultLanguage, 0.0
+
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+
+		base, _, _ := strings.Cut(strings.ToLower(tag), "-")
+		if _, ok := messages[base]; !ok && base != defaultLanguage {
+			continue
+		}
+		if q > bestQ {
+			best, bestQ = base, q
+		}
+	}
+
+	return best
+}
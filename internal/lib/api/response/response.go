@@ -1,6 +1,9 @@
 package response
 
 import (
+	"net/http"
+	"time"
+
 	"blog-api/internal/domain/models"
 )
 
@@ -9,17 +12,86 @@ const (
 	StatusError = "Error"
 )
 
+// Error codes are stable identifiers clients can branch on, unlike the messages
+const (
+	CodeInternal           = "internal_error"
+	CodeValidationFailed   = "validation_failed"
+	CodeInvalidBody        = "invalid_body"
+	CodeUnsupportedMedia   = "unsupported_media_type"
+	CodePayloadTooLarge    = "payload_too_large"
+	CodeNotFound           = "not_found"
+	CodeMethodNotAllowed   = "method_not_allowed"
+	CodeUnauthorized       = "unauthorized"
+	CodeInvalidCredentials = "invalid_credentials"
+	CodeSessionExpired     = "session_expired"
+	CodeForbidden          = "forbidden"
+	CodeUserExists         = "user_exists"
+	CodeEmailTaken         = "email_taken"
+	CodeArticleExists      = "article_exists"
+	CodeConflict           = "conflict"
+	CodeBackupCorrupted    = "backup_corrupted"
+	CodeRateLimited        = "rate_limited"
+	CodeTimeout            = "timeout"
+	CodeReadOnly           = "read_only"
+	CodeUnavailable        = "unavailable"
+	CodeAccountLocked      = "account_locked"
+
+	// CodeVersionConflict marks an update based on an outdated version of the resource
+	CodeVersionConflict = "version_conflict"
+	CodeVersionRequired = "version_required"
+)
+
 type Response struct {
 	Status   string            `json:"status"`
 	Error    string            `json:"error,omitempty"`
+	Code     string            `json:"code,omitempty"`
+	ID       int64             `json:"id,omitempty"`
 	Token    string            `json:"token,omitempty"`
+	User     *UserDTO          `json:"user,omitempty"`
+	Article  *ArticleDTO       `json:"article,omitempty"`
 	Users    *[]models.User    `json:"users,omitempty"`
 	Articles *[]models.Article `json:"articles,omitempty"`
+	Backups  *[]models.Backup  `json:"backups,omitempty"`
+	Marked   *int64            `json:"marked,omitempty"`
+	Unread   *int              `json:"unread,omitempty"`
+	Deleted  *int              `json:"deleted,omitempty"`
+	Version  *int              `json:"version,omitempty"`
+
+	Available *bool  `json:"available,omitempty"`
+	LogLevel  string `json:"log_level,omitempty"`
+
+	MissingIDs *[]int                  `json:"missing_ids,omitempty"`
+	Results    *[]models.RemovalResult `json:"results,omitempty"`
+
+	Webhook  *models.Webhook   `json:"webhook,omitempty"`
+	Webhooks *[]models.Webhook `json:"webhooks,omitempty"`
+
+	Collection  *models.Collection   `json:"collection,omitempty"`
+	Collections *[]models.Collection `json:"collections,omitempty"`
+
+	Attachment *models.Attachment `json:"attachment,omitempty"`
+
+	Stats     *models.ArticleStats `json:"stats,omitempty"`
+	UserStats *models.UserStats    `json:"user_stats,omitempty"`
+
+	Digest *models.Digest `json:"digest,omitempty"`
+
+	Notifications *[]models.Notification `json:"notifications,omitempty"`
+	LoginHistory  *[]models.LoginEvent   `json:"login_history,omitempty"`
+	Sessions      *[]models.Session      `json:"sessions,omitempty"`
+
+	// LockedUntil tells when a locked account accepts logins again
+	LockedUntil *time.Time `json:"locked_until,omitempty"`
+	// RequestID is only sent with internal errors, to find them in the logs
+	RequestID string `json:"request_id,omitempty"`
 }
 
-func Err(errMsg string) Response {
+// Err builds an error response, code is one of the Code constants.
+// errMsg is translated into the language the client accepts, if possible
+func Err(r *http.Request, code, errMsg string) Response {
 	return Response{
 		Status: StatusError,
-		Error:  errMsg,
+		Error:  Message(r, code, errMsg),
+		Code:   code,
 	}
 }
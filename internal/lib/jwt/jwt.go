@@ -13,14 +13,33 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
-func NewToken(user models.User, duration time.Duration, secret string) (string, error) {
-	token := jwt.New(jwt.SigningMethodHS256)
+var (
+	ErrNoToken      = errors.New("no valid token")
+	ErrClaimMissing = errors.New("claim missing")
+	ErrInvalidClaim = errors.New("invalid claim")
+)
+
+// NewToken issues a token for the user's login session sid
+func NewToken(user models.User, sid int64, duration time.Duration, keys *KeyProvider) (string, error) {
+	if keys == nil || keys.signKey == nil {
+		return "", ErrNoSignKey
+	}
+
+	method := jwt.GetSigningMethod(keys.Algorithm)
+	if method == nil {
+		return "", fmt.Errorf("unsupported signing algorithm %q", keys.Algorithm)
+	}
+
+	token := jwt.New(method)
+	token.Header["kid"] = keys.signKID
 
 	claims := token.Claims.(jwt.MapClaims)
 	claims["uid"] = user.ID
+	claims["role"] = user.Role
+	claims["sid"] = sid
 	claims["exp"] = time.Now().Add(duration).Unix()
 
-	tokenString, err := token.SignedString([]byte(secret))
+	tokenString, err := token.SignedString(keys.signKey)
 	if err != nil {
 		return "", err
 	}
@@ -28,44 +47,91 @@ func NewToken(user models.User, duration time.Duration, secret string) (string,
 	return tokenString, nil
 }
 
+// UserID returns the id of the user the token in ctx was issued to
+func UserID(ctx context.Context) (int, error) {
+	const op = "jwt.UserID"
+
+	token, claims, err := jwtauth.FromContext(ctx)
+	if err != nil || token == nil {
+		return 0, fmt.Errorf("%s: %w", op, ErrNoToken)
+	}
+
+	uid, ok := claims["uid"].(float64)
+	if !ok {
+		return 0, fmt.Errorf("%s: %w: uid", op, ErrClaimMissing)
+	}
+
+	return int(uid), nil
+}
+
+// SessionID returns the login session the token in ctx belongs to
+func SessionID(ctx context.Context) (int64, error) {
+	const op = "jwt.SessionID"
+
+	token, claims, err := jwtauth.FromContext(ctx)
+	if err != nil || token == nil {
+		return 0, fmt.Errorf("%s: %w", op, ErrNoToken)
+	}
+
+	sid, ok := claims["sid"].(float64)
+	if !ok {
+		return 0, fmt.Errorf("%s: %w: sid", op, ErrClaimMissing)
+	}
+
+	return int64(sid), nil
+}
+
+// Role returns the role of the user the token in ctx was issued to
+func Role(ctx context.Context) (string, error) {
+	const op = "jwt.Role"
+
+	token, claims, err := jwtauth.FromContext(ctx)
+	if err != nil || token == nil {
+		return "", fmt.Errorf("%s: %w", op, ErrNoToken)
+	}
+
+	role, ok := claims["role"].(string)
+	if !ok {
+		return "", fmt.Errorf("%s: %w: role", op, ErrClaimMissing)
+	}
+
+	return role, nil
+}
+
+// IsAdmin reports whether the token in ctx was issued to an admin
+func IsAdmin(ctx context.Context) bool {
+	ok, err := CheckClaim(ctx, "role", models.RoleAdmin)
+	return err == nil && ok
+}
+
+// CheckClaim reports whether the claim of the token in ctx equals expectedClaim.
+// A mismatch isn't an error, err is only set when there is no valid token
+// or the claim is missing or malformed
 func CheckClaim(ctx context.Context, claim, expectedClaim string) (bool, error) {
-	const op = "CheckClaim"
+	const op = "jwt.CheckClaim"
 
-	_, claims, err := jwtauth.FromContext(ctx)
-	if err != nil {
-		return false, fmt.Errorf("%s: %w", op, err)
+	token, claims, err := jwtauth.FromContext(ctx)
+	if err != nil || token == nil {
+		return false, fmt.Errorf("%s: %w", op, ErrNoToken)
 	}
 
 	c, ok := claims[claim]
 	if !ok {
-		return false, fmt.Errorf("%s: claim not found", op)
+		return false, fmt.Errorf("%s: %w: %s", op, ErrClaimMissing, claim)
 	}
 
-	switch c.(type) {
+	switch c := c.(type) {
 	case float64:
-		claim, ok := c.(float64)
-		if !ok {
-			return false, fmt.Errorf("%s: %w", op, errors.New("type not found"))
-		}
-
+		// Something that isn't a number can't be equal to a numeric claim
 		expClaim, err := strconv.ParseFloat(expectedClaim, 64)
 		if err != nil {
-			return false, fmt.Errorf("%s: %w", op, err)
-		}
-
-		if claim != expClaim {
 			return false, nil
 		}
-	case string:
-		claim, ok := c.(string)
-		if !ok {
-			return false, fmt.Errorf("%s: %w", op, errors.New("type not found"))
-		}
 
-		if claim != expectedClaim {
-			return false, nil
-		}
+		return c == expClaim, nil
+	case string:
+		return c == expectedClaim, nil
+	default:
+		return false, fmt.Errorf("%s: %w: %s has type %T", op, ErrInvalidClaim, claim, c)
 	}
-
-	return true, nil
 }
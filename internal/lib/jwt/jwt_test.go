@@ -0,0 +1,139 @@
+package jwt_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"blog-api/internal/domain/models"
+	"blog-api/internal/lib/jwt"
+
+	"github.com/go-chi/jwtauth/v5"
+	jwx "github.com/lestrrat-go/jwx/v2/jwt"
+)
+
+// tokenContext returns a context carrying a token with the given claims,
+// as the jwtauth verifier leaves it for the handlers
+func tokenContext(t *testing.T, claims map[string]any) context.Context {
+	t.Helper()
+
+	token := jwx.New()
+	for k, v := range claims {
+		if err := token.Set(k, v); err != nil {
+			t.Fatalf("failed to set claim %q: %v", k, err)
+		}
+	}
+
+	return jwtauth.NewContext(context.Background(), token, nil)
+}
+
+func TestCheckClaim(t *testing.T) {
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		claim    string
+		expected string
+		want     bool
+		wantErr  error
+	}{
+		{
+			name:     "string claim matches",
+			ctx:      tokenContext(t, map[string]any{"role": models.RoleAdmin}),
+			claim:    "role",
+			expected: models.RoleAdmin,
+			want:     true,
+		},
+		{
+			name:     "string claim differs",
+			ctx:      tokenContext(t, map[string]any{"role": models.RoleUser}),
+			claim:    "role",
+			expected: models.RoleAdmin,
+		},
+		{
+			name:     "numeric claim matches",
+			ctx:      tokenContext(t, map[string]any{"uid": float64(7)}),
+			claim:    "uid",
+			expected: "7",
+			want:     true,
+		},
+		{
+			name:     "numeric claim differs",
+			ctx:      tokenContext(t, map[string]any{"uid": float64(7)}),
+			claim:    "uid",
+			expected: "8",
+		},
+		{
+			name:     "numeric claim against a non-number",
+			ctx:      tokenContext(t, map[string]any{"uid": float64(7)}),
+			claim:    "uid",
+			expected: "seven",
+		},
+		{
+			name:     "no token",
+			ctx:      context.Background(),
+			claim:    "role",
+			expected: models.RoleAdmin,
+			wantErr:  jwt.ErrNoToken,
+		},
+		{
+			name:     "verification failed",
+			ctx:      jwtauth.NewContext(context.Background(), jwx.New(), jwtauth.ErrExpired),
+			claim:    "role",
+			expected: models.RoleAdmin,
+			wantErr:  jwt.ErrNoToken,
+		},
+		{
+			name:     "claim missing",
+			ctx:      tokenContext(t, map[string]any{"uid": float64(7)}),
+			claim:    "role",
+			expected: models.RoleAdmin,
+			wantErr:  jwt.ErrClaimMissing,
+		},
+		{
+			name:     "claim of another type",
+			ctx:      tokenContext(t, map[string]any{"role": []string{models.RoleAdmin}}),
+			claim:    "role",
+			expected: models.RoleAdmin,
+			wantErr:  jwt.ErrInvalidClaim,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := jwt.CheckClaim(tt.ctx, tt.claim, tt.expected)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CheckClaim() error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("CheckClaim() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckClaimIssuedToken(t *testing.T) {
+	keys, err := jwt.LoadKeys(jwt.HS256, "secret", nil, "", "")
+	if err != nil {
+		t.Fatalf("LoadKeys() error = %v", err)
+	}
+
+	tokenString, err := jwt.NewToken(models.User{ID: 42, Role: models.RoleUser}, 1, time.Hour, keys)
+	if err != nil {
+		t.Fatalf("NewToken() error = %v", err)
+	}
+	token, err := keys.Verify(tokenString)
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	ctx := jwtauth.NewContext(context.Background(), token, nil)
+
+	if ok, err := jwt.CheckClaim(ctx, "uid", "42"); err != nil || !ok {
+		t.Errorf("CheckClaim(uid, 42) = %t, %v, want true, nil", ok, err)
+	}
+	if ok, err := jwt.CheckClaim(ctx, "role", models.RoleAdmin); err != nil || ok {
+		t.Errorf("CheckClaim(role, admin) = %t, %v, want false, nil", ok, err)
+	}
+	if jwt.IsAdmin(ctx) {
+		t.Error("IsAdmin() = true for a user token")
+	}
+}
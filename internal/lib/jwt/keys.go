@@ -0,0 +1,194 @@
+package jwt
+
+import (
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"sync/atomic"
+
+	"github.com/go-chi/jwtauth/v5"
+	"github.com/golang-jwt/jwt/v5"
+	jwx "github.com/lestrrat-go/jwx/v2/jwt"
+)
+
+const (
+	HS256 = "HS256"
+	RS256 = "RS256"
+)
+
+var (
+	ErrNoSignKey  = errors.New("signing key is not configured")
+	ErrUnknownKey = errors.New("token signed with an unknown key")
+)
+
+// KeyProvider holds the algorithm and the keys tokens are signed and verified with.
+// Every key has an id, sent in the kid header of the tokens it signs, so that
+// verification picks the right key. Retired keys only verify tokens issued before a rotation
+type KeyProvider struct {
+	Algorithm string
+	// signKey is nil when the service may only verify tokens
+	signKey any
+	signKID string
+	// keys are tried on tokens without a kid in this order, the active key first
+	keys []*verifyKey
+}
+
+type verifyKey struct {
+	kid  string
+	auth *jwtauth.JWTAuth
+	// used counts the tokens a retired key verified since the last report
+	used atomic.Int64
+}
+
+// LoadKeys builds the keys for the algorithm: HS256 uses the shared secret and,
+// if any, the previous ones, RS256 reads PEM encoded RSA keys from the given paths
+func LoadKeys(algorithm, secret string, previousSecrets []string, privateKeyPath, publicKeyPath string) (*KeyProvider, error) {
+	const op = "jwt.LoadKeys"
+
+	switch algorithm {
+	case HS256:
+		p := &KeyProvider{
+			Algorithm: HS256,
+			signKey:   []byte(secret),
+			signKID:   keyID([]byte(secret)),
+		}
+
+		p.addVerifyKey([]byte(secret), []byte(secret))
+		for _, prev := range previousSecrets {
+			p.addVerifyKey([]byte(prev), []byte(prev))
+		}
+
+		return p, nil
+	case RS256:
+		p := &KeyProvider{Algorithm: RS256}
+
+		var verifyKey *rsa.PublicKey
+		if privateKeyPath != "" {
+			pem, err := os.ReadFile(privateKeyPath)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", op, err)
+			}
+
+			privateKey, err := jwt.ParseRSAPrivateKeyFromPEM(pem)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", op, err)
+			}
+
+			p.signKey = privateKey
+			verifyKey = &privateKey.PublicKey
+		}
+
+		if publicKeyPath != "" {
+			pem, err := os.ReadFile(publicKeyPath)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", op, err)
+			}
+
+			verifyKey, err = jwt.ParseRSAPublicKeyFromPEM(pem)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", op, err)
+			}
+		}
+
+		if verifyKey == nil {
+			return nil, fmt.Errorf("%s: no RSA key configured", op)
+		}
+
+		der, err := x509.MarshalPKIXPublicKey(verifyKey)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		if p.signKey != nil {
+			p.signKID = keyID(der)
+		}
+		p.addVerifyKey(verifyKey, der)
+
+		return p, nil
+	default:
+		return nil, fmt.Errorf("%s: unsupported algorithm %q", op, algorithm)
+	}
+}
+
+// addVerifyKey adds a key identified by the hash of id, a key seen before is skipped
+func (p *KeyProvider) addVerifyKey(key any, id []byte) {
+	kid := keyID(id)
+	for _, k := range p.keys {
+		if k.kid == kid {
+			return
+		}
+	}
+
+	p.keys = append(p.keys, &verifyKey{
+		kid:  kid,
+		auth: jwtauth.New(p.Algorithm, nil, key),
+	})
+}
+
+// keyID derives the kid of a key from its secret or public key, so that it stays
+// the same across restarts without being configured, and tells nothing about the key
+func keyID(key []byte) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:8])
+}
+
+// Verify checks tokenString with the key its kid header names. Tokens without a kid,
+// issued before tokens carried one, are tried with every key, the active one first
+func (p *KeyProvider) Verify(tokenString string) (jwx.Token, error) {
+	kid := tokenKID(tokenString)
+
+	var firstErr error
+	for i, k := range p.keys {
+		if kid != "" && k.kid != kid {
+			continue
+		}
+
+		token, err := jwtauth.VerifyToken(k.auth, tokenString)
+		if err == nil {
+			if i > 0 {
+				k.used.Add(1)
+			}
+			return token, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	if firstErr == nil {
+		return nil, ErrUnknownKey
+	}
+	return nil, firstErr
+}
+
+// RetiredUse returns how many tokens each retired key verified since the last call, by kid.
+// Keys that verified none are left out
+func (p *KeyProvider) RetiredUse() map[string]int64 {
+	use := make(map[string]int64)
+	for _, k := range p.keys[1:] {
+		if n := k.used.Swap(0); n > 0 {
+			use[k.kid] = n
+		}
+	}
+
+	return use
+}
+
+// Retired reports whether there are keys that only verify tokens
+func (p *KeyProvider) Retired() bool {
+	return len(p.keys) > 1
+}
+
+// tokenKID reads the kid header of tokenString without verifying it, "" when there is none
+func tokenKID(tokenString string) string {
+	token, _, err := jwt.NewParser().ParseUnverified(tokenString, jwt.MapClaims{})
+	if err != nil {
+		return ""
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	return kid
+}
@@ -0,0 +1,108 @@
+package jwt_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"blog-api/internal/domain/models"
+	"blog-api/internal/lib/jwt"
+
+	gojwt "github.com/golang-jwt/jwt/v5"
+)
+
+// mustLoadKeys loads HS256 keys signing with secret and verifying with the previous ones too
+func mustLoadKeys(t *testing.T, secret string, previous ...string) *jwt.KeyProvider {
+	t.Helper()
+
+	keys, err := jwt.LoadKeys(jwt.HS256, secret, previous, "", "")
+	if err != nil {
+		t.Fatalf("failed to load keys: %v", err)
+	}
+
+	return keys
+}
+
+// mustIssue returns a token of user 1 signed with keys, carrying their kid
+func mustIssue(t *testing.T, keys *jwt.KeyProvider) string {
+	t.Helper()
+
+	token, err := jwt.NewToken(models.User{ID: 1, Role: models.RoleUser}, 1, time.Hour, keys)
+	if err != nil {
+		t.Fatalf("failed to issue token: %v", err)
+	}
+
+	return token
+}
+
+// signWithoutKID returns a token of user 1 signed with secret and no kid header,
+// as tokens were issued before they carried one
+func signWithoutKID(t *testing.T, secret string) string {
+	t.Helper()
+
+	token, err := gojwt.NewWithClaims(gojwt.SigningMethodHS256, gojwt.MapClaims{
+		"uid": 1,
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}).SignedString([]byte(secret))
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+
+	return token
+}
+
+func TestVerify(t *testing.T) {
+	keys := mustLoadKeys(t, "new secret", "old secret")
+
+	tests := []struct {
+		name        string
+		token       string
+		wantErr     bool
+		wantErrIs   error
+		wantRetired int64
+	}{
+		{name: "kid of the active key", token: mustIssue(t, mustLoadKeys(t, "new secret"))},
+		{name: "kid of a retired key", token: mustIssue(t, mustLoadKeys(t, "old secret")), wantRetired: 1},
+		{name: "kid of an unknown key", token: mustIssue(t, mustLoadKeys(t, "other secret")), wantErr: true, wantErrIs: jwt.ErrUnknownKey},
+		{name: "no kid, active key", token: signWithoutKID(t, "new secret")},
+		{name: "no kid, retired key", token: signWithoutKID(t, "old secret"), wantRetired: 1},
+		{name: "no kid, unknown key", token: signWithoutKID(t, "other secret"), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := keys.Verify(tt.token)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Verify() error = %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantErrIs != nil && !errors.Is(err, tt.wantErrIs) {
+				t.Errorf("Verify() error = %v, want %v", err, tt.wantErrIs)
+			}
+			if !tt.wantErr {
+				if uid, _ := token.Get("uid"); uid != float64(1) {
+					t.Errorf("Verify() token uid = %v, want 1", uid)
+				}
+			}
+
+			var retired int64
+			for _, n := range keys.RetiredUse() {
+				retired += n
+			}
+			if retired != tt.wantRetired {
+				t.Errorf("RetiredUse() = %d, want %d", retired, tt.wantRetired)
+			}
+		})
+	}
+}
+
+func TestVerifyAfterKeyRemoved(t *testing.T) {
+	old := mustIssue(t, mustLoadKeys(t, "old secret"))
+
+	if _, err := mustLoadKeys(t, "new secret", "old secret").Verify(old); err != nil {
+		t.Fatalf("Verify() while the key is retired error = %v", err)
+	}
+
+	// Once the secret is dropped from the previous ones, its tokens stop working
+	if _, err := mustLoadKeys(t, "new secret").Verify(old); !errors.Is(err, jwt.ErrUnknownKey) {
+		t.Errorf("Verify() after the key is removed error = %v, want %v", err, jwt.ErrUnknownKey)
+	}
+}
@@ -0,0 +1,35 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+)
+
+type ctxKey struct{}
+
+// WithAttrs returns a context carrying attrs in addition to the ones already in ctx.
+// Middleware uses it to add request details to the logs of handlers
+func WithAttrs(ctx context.Context, attrs ...slog.Attr) context.Context {
+	prev, _ := ctx.Value(ctxKey{}).([]slog.Attr)
+
+	all := make([]slog.Attr, 0, len(prev)+len(attrs))
+	all = append(all, prev...)
+	all = append(all, attrs...)
+
+	return context.WithValue(ctx, ctxKey{}, all)
+}
+
+// FromContext returns log with the attrs stored in ctx by WithAttrs
+func FromContext(ctx context.Context, log *slog.Logger) *slog.Logger {
+	attrs, _ := ctx.Value(ctxKey{}).([]slog.Attr)
+	if len(attrs) == 0 {
+		return log
+	}
+
+	args := make([]any, len(attrs))
+	for i, a := range attrs {
+		args[i] = a
+	}
+
+	return log.With(args...)
+}
@@ -20,9 +20,9 @@ func (d *DiscardLogger) Handle(_ context.Context, _ slog.Record) error {
 }
 
 func (d *DiscardLogger) WithAttrs(_ []slog.Attr) slog.Handler {
-	return nil
+	return d
 }
 
 func (d *DiscardLogger) WithGroup(_ string) slog.Handler {
-	return nil
+	return d
 }
@@ -0,0 +1,130 @@
+package slogpretty
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+)
+
+const (
+	timeFormat = "15:04:05.000"
+
+	reset   = "\033[0m"
+	gray    = "\033[90m"
+	red     = "\033[31m"
+	yellow  = "\033[33m"
+	blue    = "\033[34m"
+	magenta = "\033[35m"
+	cyan    = "\033[36m"
+)
+
+// PrettyHandler writes one colored line per record, meant for reading logs in a terminal:
+//
+//	12:30:00.000 INFO  message key=value
+type PrettyHandler struct {
+	opts   *slog.HandlerOptions
+	mu     *sync.Mutex
+	w      io.Writer
+	attrs  string
+	prefix string
+}
+
+func NewPrettyHandler(w io.Writer, opts *slog.HandlerOptions) *PrettyHandler {
+	if opts == nil {
+		opts = &slog.HandlerOptions{}
+	}
+
+	return &PrettyHandler{
+		opts: opts,
+		mu:   &sync.Mutex{},
+		w:    w,
+	}
+}
+
+func (h *PrettyHandler) Enabled(_ context.Context, level slog.Level) bool {
+	minLevel := slog.LevelInfo
+	if h.opts.Level != nil {
+		minLevel = h.opts.Level.Level()
+	}
+
+	return level >= minLevel
+}
+
+func (h *PrettyHandler) Handle(_ context.Context, r slog.Record) error {
+	var b strings.Builder
+
+	b.WriteString(gray + r.Time.Format(timeFormat) + reset + " ")
+	b.WriteString(levelColor(r.Level) + fmt.Sprintf("%-5s", r.Level.String()) + reset + " ")
+	b.WriteString(r.Message)
+	b.WriteString(h.attrs)
+
+	r.Attrs(func(a slog.Attr) bool {
+		writeAttr(&b, h.prefix, a)
+		return true
+	})
+
+	b.WriteString("\n")
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	_, err := io.WriteString(h.w, b.String())
+	return err
+}
+
+func (h *PrettyHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	var b strings.Builder
+	for _, a := range attrs {
+		writeAttr(&b, h.prefix, a)
+	}
+
+	h2 := *h
+	h2.attrs = h.attrs + b.String()
+	return &h2
+}
+
+func (h *PrettyHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+
+	h2 := *h
+	h2.prefix = h.prefix + name + "."
+	return &h2
+}
+
+func writeAttr(b *strings.Builder, prefix string, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+
+	if a.Value.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			prefix += a.Key + "."
+		}
+		for _, ga := range a.Value.Group() {
+			writeAttr(b, prefix, ga)
+		}
+		return
+	}
+
+	b.WriteString(" " + cyan + prefix + a.Key + "=" + reset)
+	b.WriteString(fmt.Sprintf("%+v", a.Value.Any()))
+}
+
+func levelColor(level slog.Level) string {
+	switch {
+	case level >= slog.LevelError:
+		return red
+	case level >= slog.LevelWarn:
+		return yellow
+	case level >= slog.LevelInfo:
+		return blue
+	default:
+		return magenta
+	}
+}
@@ -1,33 +1,65 @@
 package logger
 
 import (
+	"fmt"
+	"io"
 	"log/slog"
-	"os"
+	"strings"
+
+	"blog-api/internal/lib/logger/handlers/slogpretty"
 )
 
 const (
-	local = "local"
-	dev   = "dev"
-	prod  = "prod"
+	formatText   = "text"
+	formatJSON   = "json"
+	formatPretty = "pretty"
 )
 
-func New(env string) *slog.Logger {
-	var log *slog.Logger
-
-	switch env {
-	case local:
-		log = slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
-			Level: slog.LevelDebug,
-		}))
-	case dev:
-		log = slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
-			Level: slog.LevelDebug,
-		}))
-	case prod:
-		log = slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
-			Level: slog.LevelInfo,
-		}))
+// New builds a logger writing to w with the given level (debug, info, warn, error)
+// and format (text, json, pretty). The returned LevelVar changes the level while running
+func New(level, format string, w io.Writer) (*slog.Logger, *slog.LevelVar, error) {
+	const op = "logger.New"
+
+	lvl, err := ParseLevel(level)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	levelVar := &slog.LevelVar{}
+	levelVar.Set(lvl)
+
+	opts := &slog.HandlerOptions{
+		Level: levelVar,
+	}
+
+	var handler slog.Handler
+
+	switch strings.ToLower(format) {
+	case formatText:
+		handler = slog.NewTextHandler(w, opts)
+	case formatJSON:
+		handler = slog.NewJSONHandler(w, opts)
+	case formatPretty:
+		handler = slogpretty.NewPrettyHandler(w, opts)
+	default:
+		return nil, nil, fmt.Errorf("%s: unknown log format %q", op, format)
+	}
+
+	return slog.New(handler), levelVar, nil
+}
+
+// ParseLevel converts a level name into slog.Level
+func ParseLevel(level string) (slog.Level, error) {
+	switch strings.ToLower(level) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "info":
+		return slog.LevelInfo, nil
+	case "warn":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
 	}
 
-	return log
+	return 0, fmt.Errorf("unknown log level %q", level)
 }
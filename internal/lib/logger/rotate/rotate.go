@@ -0,0 +1,147 @@
+package rotate
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+)
+
+const megabyte = 1024 * 1024
+
+// File is an io.Writer appending to a file that is rotated once it grows past the size limit.
+// Rotated files are renamed to path.1, path.2 and so on, path.1 being the newest,
+// at most maxBackups of them are kept
+type File struct {
+	mu         sync.Mutex
+	path       string
+	maxSize    int64
+	maxBackups int
+	file       *os.File
+	size       int64
+}
+
+// New opens the file at path for appending, creating it and its directory if needed
+func New(path string, maxSizeMB, maxBackups int) (*File, error) {
+	const op = "rotate.New"
+
+	if maxSizeMB <= 0 {
+		return nil, fmt.Errorf("%s: max size must be positive", op)
+	}
+	if maxBackups < 0 {
+		return nil, fmt.Errorf("%s: max backups can't be negative", op)
+	}
+
+	f := &File{
+		path:       path,
+		maxSize:    int64(maxSizeMB) * megabyte,
+		maxBackups: maxBackups,
+	}
+
+	if err := f.open(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return f, nil
+}
+
+func (f *File) Write(p []byte) (int, error) {
+	const op = "rotate.Write"
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	// A single write larger than the limit still goes into one file
+	if f.size > 0 && f.size+int64(len(p)) > f.maxSize {
+		// If only renaming failed, the file is reopened and rotation is retried on the next write
+		if err := f.rotate(); err != nil && f.file == nil {
+			return 0, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	if err != nil {
+		return n, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return n, nil
+}
+
+func (f *File) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return nil
+	}
+
+	return f.file.Close()
+}
+
+func (f *File) open() error {
+	if err := os.MkdirAll(filepath.Dir(f.path), 0o755); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	f.file = file
+	f.size = info.Size()
+
+	return nil
+}
+
+// rotate shifts the backups by one, dropping the oldest, and starts a new file.
+// f.file is nil afterwards only if no file could be opened
+func (f *File) rotate() error {
+	if f.file != nil {
+		if err := f.file.Close(); err != nil {
+			return err
+		}
+		f.file = nil
+	}
+
+	if err := f.shift(); err != nil {
+		if oErr := f.open(); oErr != nil {
+			return oErr
+		}
+		return err
+	}
+
+	return f.open()
+}
+
+func (f *File) shift() error {
+	if f.maxBackups == 0 {
+		if err := os.Remove(f.path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+
+	if err := os.Remove(f.backup(f.maxBackups)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for i := f.maxBackups - 1; i >= 1; i-- {
+		if err := os.Rename(f.backup(i), f.backup(i+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	return os.Rename(f.path, f.backup(1))
+}
+
+func (f *File) backup(n int) string {
+	return f.path + "." + strconv.Itoa(n)
+}
@@ -0,0 +1,25 @@
+package notification
+
+import "time"
+
+// Notifier tells users by email about activity around them
+type Notifier interface {
+	SendComment(to, fromUsername, articleTitle, commentText string) error
+	SendMention(to, fromUsername, articleTitle string) error
+	SendPasswordReset(to, userName, token string, ttl time.Duration) error
+}
+
+// NullNotifier sends nothing, it's used when email notifications are disabled
+type NullNotifier struct{}
+
+func (NullNotifier) SendComment(to, fromUsername, articleTitle, commentText string) error {
+	return nil
+}
+
+func (NullNotifier) SendMention(to, fromUsername, articleTitle string) error {
+	return nil
+}
+
+func (NullNotifier) SendPasswordReset(to, userName, token string, ttl time.Duration) error {
+	return nil
+}
@@ -0,0 +1,130 @@
+package notification
+
+import (
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sendTimeout bounds a whole delivery, net/smtp has no timeouts of its own
+const sendTimeout = 10 * time.Second
+
+// SMTPNotifier sends plain text emails through an SMTP relay without authentication
+type SMTPNotifier struct {
+	addr string
+	host string
+	from string
+}
+
+func NewSMTP(host string, port int, from string) *SMTPNotifier {
+	return &SMTPNotifier{
+		addr: net.JoinHostPort(host, strconv.Itoa(port)),
+		host: host,
+		from: from,
+	}
+}
+
+func (n *SMTPNotifier) SendComment(to, fromUsername, articleTitle, commentText string) error {
+	const op = "notification.SendComment"
+
+	subject := fmt.Sprintf("%s commented on %q", fromUsername, articleTitle)
+	body := fmt.Sprintf("%s commented on your article %q:\n\n%s\n", fromUsername, articleTitle, commentText)
+
+	if err := n.send(to, subject, body); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (n *SMTPNotifier) SendMention(to, fromUsername, articleTitle string) error {
+	const op = "notification.SendMention"
+
+	subject := fmt.Sprintf("%s mentioned you in %q", fromUsername, articleTitle)
+	body := fmt.Sprintf("%s mentioned you in the article %q.\n", fromUsername, articleTitle)
+
+	if err := n.send(to, subject, body); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (n *SMTPNotifier) SendPasswordReset(to, userName, token string, ttl time.Duration) error {
+	const op = "notification.SendPasswordReset"
+
+	subject := "Reset your password"
+	body := fmt.Sprintf("Hi %s,\n\nsomeone asked to reset your password. If it was you, send this token "+
+		"to POST /users/reset-password along with the new password:\n\n%s\n\n"+
+		"The token expires in %d minutes and works once. If you didn't ask for it, ignore this email.\n", userName, token, int(ttl.Minutes()))
+
+	if err := n.send(to, subject, body); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (n *SMTPNotifier) send(to, subject, body string) error {
+	conn, err := net.DialTimeout("tcp", n.addr, sendTimeout)
+	if err != nil {
+		return err
+	}
+	if err := conn.SetDeadline(time.Now().Add(sendTimeout)); err != nil {
+		conn.Close()
+		return err
+	}
+
+	c, err := smtp.NewClient(conn, n.host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: n.host}); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(n.from); err != nil {
+		return err
+	}
+	if err := c.Rcpt(to); err != nil {
+		return err
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(message(n.from, to, subject, body)); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	return c.Quit()
+}
+
+func message(from, to, subject, body string) []byte {
+	var b strings.Builder
+
+	b.WriteString("From: " + from + "\r\n")
+	b.WriteString("To: " + to + "\r\n")
+	// Encoding also keeps line breaks in titles from starting new headers
+	b.WriteString("Subject: " + mime.QEncoding.Encode("UTF-8", subject) + "\r\n")
+	b.WriteString("Date: " + time.Now().UTC().Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+
+	return []byte(b.String())
+}
@@ -5,49 +5,182 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"net/url"
+	"regexp"
+	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"blog-api/internal/domain/models"
+	"blog-api/internal/events"
+	"blog-api/internal/lib/cache"
 	"blog-api/internal/lib/logger/sl"
 	"blog-api/internal/storage"
 )
 
+const (
+	maxTitleLen   = 200
+	maxContentLen = 100_000
+
+	// MaxBulkIDs limits how many articles are fetched or removed at once
+	MaxBulkIDs = 100
+
+	// MaxPinned limits how many articles an author may pin
+	MaxPinned = 3
+
+	// maxCopies limits how many copies of one article Duplicate looks for a free title among
+	maxCopies = 100
+
+	// Repeated views by the same viewer within this period count once
+	viewPeriod = 24 * time.Hour
+
+	// maxMentions limits how many users one article may mention by email
+	maxMentions = 20
+)
+
+const (
+	// DefaultTrendingHours is the trending window when neither a period nor hours are given
+	DefaultTrendingHours = 48
+	// MaxTrendingHours matches the longest period, a month
+	MaxTrendingHours = 30 * 24
+
+	// Trending results are cached for trendingTTL, which is also how often the stats are rolled up
+	trendingTTL       = 5 * time.Minute
+	trendingCacheSize = 64
+)
+
+// trendingPeriods are the periods Trending accepts
+var trendingPeriods = map[string]time.Duration{
+	"day":   24 * time.Hour,
+	"week":  7 * 24 * time.Hour,
+	"month": 30 * 24 * time.Hour,
+}
+
+// mentionPattern matches "@name" unless it's part of a word or an email address
+var mentionPattern = regexp.MustCompile(`(?:^|[^\w@.])@(\w+)`)
+
+// languageTag loosely matches a BCP-47 tag: an ISO 639 language code with optional subtags, e.g. "en" or "pt-BR"
+var languageTag = regexp.MustCompile(`^[a-zA-Z]{2,3}(-[a-zA-Z0-9]{1,8})*$`)
+
 var (
 	ErrArticleExists   = errors.New("article already exists")
 	ErrArticleNotFound = errors.New("article not found")
+	ErrVersionConflict = errors.New("article was changed since it was read")
+	ErrVersionRequired = errors.New("article version is required")
+	ErrForbidden       = errors.New("not enough rights")
+
+	ErrInvalidStatus       = errors.New("invalid article status")
+	ErrInvalidReaction     = errors.New("invalid reaction")
+	ErrInvalidCanonicalURL = errors.New("invalid canonical url, expected an absolute https url")
+	ErrInvalidLanguage     = errors.New("invalid language, expected a BCP-47 tag such as \"en\" or \"pt-BR\"")
+	ErrInvalidPeriod       = errors.New("invalid period, supported: day, week, month")
+	ErrInvalidHours        = fmt.Errorf("invalid hours: must be an integer between 1 and %d", MaxTrendingHours)
+	ErrPeriodAndHours      = errors.New("period and hours can't be used together")
+	ErrInvalidSort         = fmt.Errorf("invalid sort, supported: %s, %s, %s", models.ArticleSortUpdated, models.ArticleSortLikes, models.ArticleSortViews)
+	ErrNoIDs               = errors.New("no article ids given")
+	ErrTooManyIDs          = fmt.Errorf("more than %d article ids given", MaxBulkIDs)
+	ErrTooManyPinned       = fmt.Errorf("no more than %d articles may be pinned", MaxPinned)
+	ErrEmptyTitle          = errors.New("title is empty")
+	ErrTitleTooLong        = fmt.Errorf("title is longer than %d characters", maxTitleLen)
+	ErrContentTooLong      = fmt.Errorf("content is longer than %d characters", maxContentLen)
 )
 
 type Storage interface {
-	GetAllArticles(ctx context.Context) ([]models.Article, error)
-	GetArticleByID(ctx context.Context, id int) (*models.Article, error)
-	CreateArticle(ctx context.Context, userID int, title, content string, publishDate time.Time) error
-	UpdateArticleTitle(ctx context.Context, id int, title string) error
-	UpdateArticleContent(ctx context.Context, id int, content string) error
+	GetAllArticles(ctx context.Context, language, sort string, popular models.PopularityFilter, visibleOnly bool, viewerID, limit, offset int) ([]models.Article, error)
+	GetArticlesInRange(ctx context.Context, from, to time.Time, language, sort string, visibleOnly bool, viewerID, limit, offset int) ([]models.Article, error)
+	GetArticlesByAuthorID(ctx context.Context, authorID int, visibleOnly bool, viewerID, limit, offset int) ([]models.Article, error)
+	GetArticleByID(ctx context.Context, id int, visibleOnly bool, viewerID int) (*models.Article, error)
+	LastArticleUpdate(ctx context.Context) (time.Time, error)
+	GetArticlesByIDs(ctx context.Context, ids []int, visibleOnly bool, viewerID int) ([]models.Article, error)
+	GetTrendingArticles(ctx context.Context, since time.Time, limit int) ([]models.Article, error)
+	AggregateArticleStats(ctx context.Context) error
+	UserByID(ctx context.Context, id int) (models.User, error)
+	UpdateStreak(ctx context.Context, authorID int) error
+	EmailsByUserNames(ctx context.Context, names []string) (map[int]string, error)
+	AddArticleView(ctx context.Context, articleID int, fingerprint string, viewedAt, since time.Time) error
+	LikeArticle(ctx context.Context, userID, articleID int) error
+	DislikeArticle(ctx context.Context, userID, articleID int) error
+	RemoveReaction(ctx context.Context, userID, articleID int) error
+	CreateArticle(ctx context.Context, userID int, title, content, language, canonicalURL, status string, publishDate *time.Time) (int64, error)
+	UpdateArticle(ctx context.Context, id int, title, content, language, canonicalURL string, version int) (int, error)
+	PinArticle(ctx context.Context, id int) error
+	UnpinArticle(ctx context.Context, id int) error
+	CountPinnedByAuthor(ctx context.Context, authorID int) (int, error)
 	RemoveArticle(ctx context.Context, id int) error
+	RemoveArticlesBulk(ctx context.Context, authorID int, ids []int) (removed, foreign []int, err error)
+}
+
+// Notifier delivers notifications to users asynchronously
+type Notifier interface {
+	Notify(userID int, typ string, payload any)
+}
+
+// Publisher broadcasts article events to live subscribers
+type Publisher interface {
+	Publish(e events.Event)
+}
+
+// Mailer emails users mentioned in articles
+type Mailer interface {
+	SendMention(to, fromUsername, articleTitle string) error
+}
+
+// Webhooks calls external systems subscribed to article events asynchronously
+type Webhooks interface {
+	Dispatch(event string, data any)
+}
+
+// trendingKey identifies a cached trending list
+type trendingKey struct {
+	window time.Duration
+	limit  int
 }
 
 type Service struct {
-	log     *slog.Logger
-	storage Storage
+	log            *slog.Logger
+	storage        Storage
+	notifier       Notifier
+	publisher      Publisher
+	webhooks       Webhooks
+	mailer         Mailer
+	requireVersion bool
+	trending       *cache.LRU[trendingKey, []models.Article]
 }
 
-func New(log *slog.Logger, storage Storage) *Service {
+// New creates the service, notifier, publisher, webhooks and mailer may be nil to send no notifications or events.
+// With requireVersion updates must carry the version of the article they were based on
+func New(log *slog.Logger, storage Storage, notifier Notifier, publisher Publisher, webhooks Webhooks, mailer Mailer, requireVersion bool) *Service {
 	return &Service{
-		log:     log,
-		storage: storage,
+		log:            log,
+		storage:        storage,
+		notifier:       notifier,
+		publisher:      publisher,
+		webhooks:       webhooks,
+		mailer:         mailer,
+		requireVersion: requireVersion,
+		trending:       cache.New[trendingKey, []models.Article](trendingCacheSize, trendingTTL),
 	}
 }
 
-func (s *Service) GetAll() ([]models.Article, error) {
+// GetAll returns all articles in the language, or in any language when it's empty.
+// sort may be empty or one of the models.ArticleSort values. Articles less popular than popular
+// are left out. With visibleOnly the only drafts returned are those of viewerID,
+// 0 stands for an anonymous caller. A negative limit lifts it
+func (s *Service) GetAll(ctx context.Context, language, sort string, popular models.PopularityFilter, visibleOnly bool, viewerID, limit, offset int) ([]models.Article, error) {
 	const op = "service.article.GetAll"
 
 	log := s.log.With(slog.String("op", op))
 
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+	if err := validateSort(sort); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if language != "" && !languageTag.MatchString(language) {
+		return nil, fmt.Errorf("%s: %w", op, ErrInvalidLanguage)
+	}
 
 	// Send to storage layer
-	arts, err := s.storage.GetAllArticles(ctx)
+	arts, err := s.storage.GetAllArticles(ctx, language, sort, popular, visibleOnly, viewerID, limit, offset)
 	if err != nil {
 		log.Error("failed to get all articles", sl.Error(err))
 		return nil, fmt.Errorf("%s: %w", op, err)
@@ -56,16 +189,126 @@ func (s *Service) GetAll() ([]models.Article, error) {
 	return arts, nil
 }
 
-func (s *Service) GetByID(id int) (*models.Article, error) {
-	const op = "service.article.GetByID"
+// GetInRange returns articles published between from and to inclusive,
+// visibleOnly and viewerID leave out drafts as in GetAll
+func (s *Service) GetInRange(ctx context.Context, from, to time.Time, language, sort string, visibleOnly bool, viewerID, limit, offset int) ([]models.Article, error) {
+	const op = "service.article.GetInRange"
 
 	log := s.log.With(slog.String("op", op))
 
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+	if err := validateSort(sort); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if language != "" && !languageTag.MatchString(language) {
+		return nil, fmt.Errorf("%s: %w", op, ErrInvalidLanguage)
+	}
 
 	// Send to storage layer
-	art, err := s.storage.GetArticleByID(ctx, id)
+	arts, err := s.storage.GetArticlesInRange(ctx, from, to, language, sort, visibleOnly, viewerID, limit, offset)
+	if err != nil {
+		log.Error("failed to get articles in range", sl.Error(err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return arts, nil
+}
+
+// Trending returns the most viewed and liked published articles over the period or the last hours,
+// the last DefaultTrendingHours when neither is given, topped up with the latest ones when there are
+// fewer than limit. Results are cached for a few minutes
+func (s *Service) Trending(ctx context.Context, period string, hours, limit int) ([]models.Article, error) {
+	const op = "service.article.Trending"
+
+	log := s.log.With(slog.String("op", op))
+
+	window, err := trendingWindow(period, hours)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	key := trendingKey{window: window, limit: limit}
+	if arts, ok := s.trending.Get(key); ok {
+		return arts, nil
+	}
+
+	// Send to storage layer
+	arts, err := s.storage.GetTrendingArticles(ctx, time.Now().UTC().Add(-window), limit)
+	if err != nil {
+		log.Error("failed to get trending articles", sl.Error(err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.trending.Set(key, arts)
+
+	return arts, nil
+}
+
+func trendingWindow(period string, hours int) (time.Duration, error) {
+	switch {
+	case period != "" && hours != 0:
+		return 0, ErrPeriodAndHours
+	case period != "":
+		d, ok := trendingPeriods[period]
+		if !ok {
+			return 0, ErrInvalidPeriod
+		}
+		return d, nil
+	case hours == 0:
+		return DefaultTrendingHours * time.Hour, nil
+	case hours < 1 || hours > MaxTrendingHours:
+		return 0, ErrInvalidHours
+	default:
+		return time.Duration(hours) * time.Hour, nil
+	}
+}
+
+// AggregateStats rolls recent views and likes up into the hourly stats trending is built on.
+// It's meant to be run periodically in the background
+func (s *Service) AggregateStats(ctx context.Context) error {
+	const op = "service.article.AggregateStats"
+
+	// Send to storage layer
+	if err := s.storage.AggregateArticleStats(ctx); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func validateSort(sort string) error {
+	switch sort {
+	case "", models.ArticleSortUpdated, models.ArticleSortLikes, models.ArticleSortViews:
+		return nil
+	default:
+		return ErrInvalidSort
+	}
+}
+
+// GetByAuthor returns the author's articles, pinned ones first.
+// visibleOnly and viewerID leave out drafts as in GetAll
+func (s *Service) GetByAuthor(ctx context.Context, authorID int, visibleOnly bool, viewerID, limit, offset int) ([]models.Article, error) {
+	const op = "service.article.GetByAuthor"
+
+	log := s.log.With(slog.String("op", op))
+
+	// Send to storage layer
+	arts, err := s.storage.GetArticlesByAuthorID(ctx, authorID, visibleOnly, viewerID, limit, offset)
+	if err != nil {
+		log.Error("failed to get articles by author", sl.Error(err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return arts, nil
+}
+
+// GetByID returns the article, a draft left out by visibleOnly and viewerID as in GetAll isn't found
+func (s *Service) GetByID(ctx context.Context, id int, visibleOnly bool, viewerID int) (*models.Article, error) {
+	const op = "service.article.GetByID"
+
+	log := s.log.With(slog.String("op", op))
+
+	// Send to storage layer
+	art, err := s.storage.GetArticleByID(ctx, id, visibleOnly, viewerID)
 	if err != nil {
 		if errors.Is(err, storage.ErrArticleNotFound) {
 			log.Error("article not found", sl.Error(err))
@@ -78,63 +321,538 @@ func (s *Service) GetByID(id int) (*models.Article, error) {
 	return art, nil
 }
 
-func (s *Service) Create(art *models.Article) error {
-	const op = "service.article.Create"
+// LastModified returns when articles last changed, the zero time if there never were any
+func (s *Service) LastModified(ctx context.Context) (time.Time, error) {
+	const op = "service.article.LastModified"
 
 	log := s.log.With(slog.String("op", op))
 
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+	// Send to storage layer
+	t, err := s.storage.LastArticleUpdate(ctx)
+	if err != nil {
+		log.Error("failed to get last article update", sl.Error(err))
+		return time.Time{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return t, nil
+}
+
+// GetByIDs returns the articles in the order of ids, repeated ids are returned once.
+// Ids of articles that don't exist or are drafts left out by visibleOnly and viewerID
+// as in GetAll are returned as missing
+func (s *Service) GetByIDs(ctx context.Context, ids []int, visibleOnly bool, viewerID int) (arts []models.Article, missing []int, err error) {
+	const op = "service.article.GetByIDs"
+
+	log := s.log.With(slog.String("op", op))
+
+	// Validation
+	if len(ids) == 0 {
+		return nil, nil, fmt.Errorf("%s: %w", op, ErrNoIDs)
+	}
+	if len(ids) > MaxBulkIDs {
+		return nil, nil, fmt.Errorf("%s: %w", op, ErrTooManyIDs)
+	}
 
 	// Send to storage layer
-	err := s.storage.CreateArticle(ctx, art.AuthorID, art.Title, art.Content, time.Now())
+	found, err := s.storage.GetArticlesByIDs(ctx, ids, visibleOnly, viewerID)
 	if err != nil {
-		if errors.Is(err, storage.ErrArticleExists) {
-			log.Error("article not found", sl.Error(err))
-			return fmt.Errorf("%s: %w", op, ErrArticleExists)
+		log.Error("failed to get articles by ids", sl.Error(err))
+		return nil, nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	byID := make(map[int]models.Article, len(found))
+	for _, art := range found {
+		byID[art.ID] = art
+	}
+
+	arts, missing = []models.Article{}, []int{}
+	seen := make(map[int]bool, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			continue
 		}
-		log.Error("failed to get art", sl.Error(err))
+		seen[id] = true
+
+		if art, ok := byID[id]; ok {
+			arts = append(arts, art)
+		} else {
+			missing = append(missing, id)
+		}
+	}
+
+	return arts, missing, nil
+}
+
+// GetByIDWithAuthor returns the article together with its author, visibility is checked as in GetByID
+func (s *Service) GetByIDWithAuthor(ctx context.Context, id int, visibleOnly bool, viewerID int) (*models.Article, *models.User, error) {
+	const op = "service.article.GetByIDWithAuthor"
+
+	log := s.log.With(slog.String("op", op))
+
+	art, err := s.GetByID(ctx, id, visibleOnly, viewerID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// Send to storage layer
+	author, err := s.storage.UserByID(ctx, art.AuthorID)
+	if err != nil {
+		log.Error("failed to get article author", sl.Error(err))
+		return nil, nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return art, &author, nil
+}
+
+// View counts a view of the article by the viewer identified by fingerprint
+func (s *Service) View(ctx context.Context, id int, fingerprint string) error {
+	const op = "service.article.View"
+
+	log := s.log.With(slog.String("op", op))
+
+	now := time.Now().UTC()
+
+	// Send to storage layer
+	err := s.storage.AddArticleView(ctx, id, fingerprint, now, now.Add(-viewPeriod))
+	if err != nil {
+		if errors.Is(err, storage.ErrArticleNotFound) {
+			log.Debug("article not found", sl.Error(err))
+			return fmt.Errorf("%s: %w", op, ErrArticleNotFound)
+		}
+		log.Error("failed to add article view", sl.Error(err))
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
 	return nil
 }
 
-func (s *Service) Update(art *models.Article) error {
-	const op = "service.article.Update"
+// React sets the user's reaction to the article, an empty reaction removes it.
+// Drafts of others can't be reacted to, they aren't found
+func (s *Service) React(ctx context.Context, userID, id int, reaction string) error {
+	const op = "service.article.React"
 
 	log := s.log.With(slog.String("op", op))
 
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+	if _, err := s.GetByID(ctx, id, true, userID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
 
 	// Send to storage layer
 	var err error
-	if art.Title != "" {
-		err = s.storage.UpdateArticleTitle(ctx, art.ID, art.Title)
-	}
-	if art.Content != "" {
-		err = s.storage.UpdateArticleContent(ctx, art.ID, art.Content)
+	switch reaction {
+	case models.ReactionLike:
+		err = s.storage.LikeArticle(ctx, userID, id)
+	case models.ReactionDislike:
+		err = s.storage.DislikeArticle(ctx, userID, id)
+	case "":
+		err = s.storage.RemoveReaction(ctx, userID, id)
+	default:
+		return fmt.Errorf("%s: %w", op, ErrInvalidReaction)
 	}
 	if err != nil {
-		/* if errors.As(err, &storage.ErrArticleNotFound) {
-			log.Error("article not found", sl.Error(err))
+		if errors.Is(err, storage.ErrArticleNotFound) {
+			log.Debug("article not found", sl.Error(err))
 			return fmt.Errorf("%s: %w", op, ErrArticleNotFound)
-		} */
+		}
+		log.Error("failed to save reaction", sl.Error(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if reaction == models.ReactionLike {
+		s.notifyLiked(ctx, userID, id)
+	}
+
+	return nil
+}
+
+// notifyLiked tells the author that the article got a like
+func (s *Service) notifyLiked(ctx context.Context, userID, id int) {
+	const op = "service.article.notifyLiked"
+
+	if s.notifier == nil {
+		return
+	}
+
+	log := s.log.With(slog.String("op", op))
+
+	// Send to storage layer
+	art, err := s.storage.GetArticleByID(ctx, id, false, 0)
+	if err != nil {
+		log.Error("failed to get article by id", sl.Error(err))
+		return
+	}
+
+	if art.AuthorID == userID {
+		return
+	}
+
+	s.notifier.Notify(art.AuthorID, models.NotificationArticleLiked, map[string]int{
+		"article_id": id,
+		"user_id":    userID,
+	})
+}
+
+func (s *Service) Create(ctx context.Context, art *models.Article) (int64, error) {
+	const op = "service.article.Create"
+
+	log := s.log.With(slog.String("op", op))
+
+	art.Title = cleanTitle(art.Title)
+	art.Content = cleanContent(art.Content)
+
+	// Validation
+	if art.Title == "" {
+		return 0, fmt.Errorf("%s: %w", op, ErrEmptyTitle)
+	}
+	if utf8.RuneCountInString(art.Title) > maxTitleLen {
+		return 0, fmt.Errorf("%s: %w", op, ErrTitleTooLong)
+	}
+	if utf8.RuneCountInString(art.Content) > maxContentLen {
+		return 0, fmt.Errorf("%s: %w", op, ErrContentTooLong)
+	}
+
+	language := art.Language
+	if language == "" {
+		language = models.DefaultLanguage
+	}
+	if !languageTag.MatchString(language) {
+		return 0, fmt.Errorf("%s: %w", op, ErrInvalidLanguage)
+	}
+	if art.CanonicalURL != "" && !validCanonicalURL(art.CanonicalURL) {
+		return 0, fmt.Errorf("%s: %w", op, ErrInvalidCanonicalURL)
+	}
+
+	status := art.Status
+	if status == "" {
+		status = models.ArticlePublished
+	}
+
+	// Drafts get their publish date once they are published
+	var publishDate *time.Time
+	switch status {
+	case models.ArticlePublished:
+		now := time.Now().UTC()
+		publishDate = &now
+	case models.ArticleDraft:
+	default:
+		return 0, fmt.Errorf("%s: %w", op, ErrInvalidStatus)
+	}
+
+	// Send to storage layer
+	id, err := s.storage.CreateArticle(ctx, art.AuthorID, art.Title, art.Content, language, art.CanonicalURL, status, publishDate)
+	if err != nil {
+		if errors.Is(err, storage.ErrArticleExists) {
+			log.Error("article already exists", sl.Error(err))
+			return 0, fmt.Errorf("%s: %w", op, ErrArticleExists)
+		}
+		log.Error("failed to create article", sl.Error(err))
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// Drafts aren't public, nobody is told about them
+	if status == models.ArticlePublished {
+		// Send to storage layer
+		err = s.storage.UpdateStreak(ctx, art.AuthorID)
+		if err != nil {
+			// The article is already published, a missed streak day isn't worth failing it
+			log.Error("failed to update writing streak", sl.Error(err))
+		}
+
+		published := models.Article{
+			ID:          int(id),
+			Title:       art.Title,
+			Content:     art.Content,
+			PublishDate: publishDate,
+			Status:      status,
+			AuthorID:    art.AuthorID,
+		}
+
+		if s.publisher != nil {
+			s.publisher.Publish(events.Event{
+				Type: events.ArticleCreated,
+				Data: published,
+			})
+		}
+		if s.webhooks != nil {
+			s.webhooks.Dispatch(models.WebhookArticlePublished, published)
+		}
+		if s.mailer != nil {
+			if names := mentions(art.Content); len(names) > 0 {
+				go s.mailMentions(art.AuthorID, art.Title, names)
+			}
+		}
+	}
+
+	return id, nil
+}
+
+// mailMentions emails the mentioned users who have an email, except the author.
+// It runs after the article is published, so failures are only logged
+func (s *Service) mailMentions(authorID int, title string, names []string) {
+	const op = "service.article.mailMentions"
+
+	log := s.log.With(slog.String("op", op))
+
+	ctx := context.Background()
+
+	// Send to storage layer
+	author, err := s.storage.UserByID(ctx, authorID)
+	if err != nil {
+		log.Error("failed to get article author", sl.Error(err))
+		return
+	}
+
+	// Send to storage layer
+	emails, err := s.storage.EmailsByUserNames(ctx, names)
+	if err != nil {
+		log.Error("failed to get emails of mentioned users", sl.Error(err))
+		return
+	}
+
+	for userID, email := range emails {
+		if userID == authorID {
+			continue
+		}
+		if err := s.mailer.SendMention(email, author.UserName, title); err != nil {
+			log.Warn("failed to send mention email", slog.Int("user_id", userID), sl.Error(err))
+		}
+	}
+}
+
+// mentions returns the distinct names mentioned in content as "@name", at most maxMentions
+func mentions(content string) []string {
+	seen := make(map[string]bool)
+
+	var names []string
+	for _, m := range mentionPattern.FindAllStringSubmatch(content, -1) {
+		key := strings.ToLower(m[1])
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		names = append(names, m[1])
+		if len(names) == maxMentions {
+			break
+		}
+	}
+
+	return names
+}
+
+// Update changes the non-empty fields of the article and returns its new version.
+// If art.Version is set and the article was changed since, ErrVersionConflict
+// is returned together with the current version. Only the author or an admin may update it
+func (s *Service) Update(ctx context.Context, art *models.Article, requesterID int, role string) (int, error) {
+	const op = "service.article.Update"
+
+	log := s.log.With(slog.String("op", op))
+
+	// An empty title leaves it unchanged, one of only whitespace is a mistake
+	sentTitle := art.Title != ""
+	art.Title = cleanTitle(art.Title)
+	art.Content = cleanContent(art.Content)
+
+	// Validation
+	if sentTitle && art.Title == "" {
+		return 0, fmt.Errorf("%s: %w", op, ErrEmptyTitle)
+	}
+	if utf8.RuneCountInString(art.Title) > maxTitleLen {
+		return 0, fmt.Errorf("%s: %w", op, ErrTitleTooLong)
+	}
+	if utf8.RuneCountInString(art.Content) > maxContentLen {
+		return 0, fmt.Errorf("%s: %w", op, ErrContentTooLong)
+	}
+	if art.Language != "" && !languageTag.MatchString(art.Language) {
+		return 0, fmt.Errorf("%s: %w", op, ErrInvalidLanguage)
+	}
+	if art.CanonicalURL != "" && !validCanonicalURL(art.CanonicalURL) {
+		return 0, fmt.Errorf("%s: %w", op, ErrInvalidCanonicalURL)
+	}
+	if art.Version == 0 && s.requireVersion {
+		return 0, fmt.Errorf("%s: %w", op, ErrVersionRequired)
+	}
+
+	if _, err := s.authorize(ctx, art.ID, requesterID, role); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// Send to storage layer
+	version, err := s.storage.UpdateArticle(ctx, art.ID, art.Title, art.Content, art.Language, art.CanonicalURL, art.Version)
+	if err != nil {
+		if errors.Is(err, storage.ErrVersionConflict) {
+			log.Debug("article version conflict", slog.Int("version", art.Version), slog.Int("current", version))
+			return version, fmt.Errorf("%s: %w", op, ErrVersionConflict)
+		}
+		if errors.Is(err, storage.ErrArticleExists) {
+			log.Debug("article title already taken", sl.Error(err))
+			return 0, fmt.Errorf("%s: %w", op, ErrArticleExists)
+		}
+		if errors.Is(err, storage.ErrArticleNotFound) {
+			log.Debug("article not found", sl.Error(err))
+			return 0, fmt.Errorf("%s: %w", op, ErrArticleNotFound)
+		}
 		log.Error("failed to update article", sl.Error(err))
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return version, nil
+}
+
+// validCanonicalURL accepts absolute https urls only
+func validCanonicalURL(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	return err == nil && u.Scheme == "https" && u.Host != ""
+}
+
+// cleanTitle drops control characters and collapses runs of whitespace into single spaces
+func cleanTitle(title string) string {
+	title = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) && !unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, title)
+
+	return strings.Join(strings.Fields(title), " ")
+}
+
+// cleanContent keeps the formatting of the content and only strips null bytes
+func cleanContent(content string) string {
+	return strings.ReplaceAll(content, "\x00", "")
+}
+
+// Pin puts the article at the top of its author's list, pinning it again is a no-op.
+// Only its author or an admin may do it
+func (s *Service) Pin(ctx context.Context, id, requesterID int, role string) error {
+	const op = "service.article.Pin"
+
+	log := s.log.With(slog.String("op", op))
+
+	art, err := s.authorize(ctx, id, requesterID, role)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if art.Pinned {
+		return nil
+	}
+
+	// Send to storage layer
+	pinned, err := s.storage.CountPinnedByAuthor(ctx, art.AuthorID)
+	if err != nil {
+		log.Error("failed to count pinned articles", sl.Error(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if pinned >= MaxPinned {
+		return fmt.Errorf("%s: %w", op, ErrTooManyPinned)
+	}
+
+	// Send to storage layer
+	err = s.storage.PinArticle(ctx, art.ID)
+	if err != nil {
+		if errors.Is(err, storage.ErrArticleNotFound) {
+			log.Debug("article not found", sl.Error(err))
+			return fmt.Errorf("%s: %w", op, ErrArticleNotFound)
+		}
+		log.Error("failed to pin article", sl.Error(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Unpin returns the article to its place in its author's list, only its author or an admin may do it
+func (s *Service) Unpin(ctx context.Context, id, requesterID int, role string) error {
+	const op = "service.article.Unpin"
+
+	log := s.log.With(slog.String("op", op))
+
+	if _, err := s.authorize(ctx, id, requesterID, role); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	// Send to storage layer
+	err := s.storage.UnpinArticle(ctx, id)
+	if err != nil {
+		if errors.Is(err, storage.ErrArticleNotFound) {
+			log.Debug("article not found", sl.Error(err))
+			return fmt.Errorf("%s: %w", op, ErrArticleNotFound)
+		}
+		log.Error("failed to unpin article", sl.Error(err))
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
 	return nil
 }
 
-func (s *Service) Remove(id int) error {
-	const op = "service.article.RemoveUser"
+// Duplicate creates a draft copy of the article owned by the requester and returns its id.
+// Published articles may be copied by anyone, drafts only by their author or an admin
+func (s *Service) Duplicate(ctx context.Context, id, requesterID int, role string) (int64, error) {
+	const op = "service.article.Duplicate"
+
+	log := s.log.With(slog.String("op", op))
+
+	// Send to storage layer, drafts of others aren't found unless an admin asks
+	orig, err := s.storage.GetArticleByID(ctx, id, role != models.RoleAdmin, requesterID)
+	if err != nil {
+		if errors.Is(err, storage.ErrArticleNotFound) {
+			log.Debug("article not found", sl.Error(err))
+			return 0, fmt.Errorf("%s: %w", op, ErrArticleNotFound)
+		}
+		log.Error("failed to get article", sl.Error(err))
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// Titles are unique per author, a taken one moves on to "Copy 2 of", "Copy 3 of" and so on
+	for n := 1; n <= maxCopies; n++ {
+		art := models.Article{
+			Title:    copyTitle(orig.Title, n),
+			Content:  orig.Content,
+			Language: orig.Language,
+			Status:   models.ArticleDraft,
+			AuthorID: requesterID,
+		}
+
+		newID, err := s.Create(ctx, &art)
+		if errors.Is(err, ErrArticleExists) {
+			continue
+		}
+		if err != nil {
+			return 0, fmt.Errorf("%s: %w", op, err)
+		}
+
+		return newID, nil
+	}
+
+	log.Debug("no free copy title", slog.Int("id", id))
+	return 0, fmt.Errorf("%s: %w", op, ErrArticleExists)
+}
+
+// copyTitle names the nth copy of an article titled title. The title is cut
+// so that the copy still fits in maxTitleLen
+func copyTitle(title string, n int) string {
+	prefix := "Copy of "
+	if n > 1 {
+		prefix = fmt.Sprintf("Copy %d of ", n)
+	}
+
+	runes := []rune(title)
+	if limit := maxTitleLen - utf8.RuneCountInString(prefix); len(runes) > limit {
+		runes = runes[:limit]
+	}
+
+	return prefix + string(runes)
+}
+
+// Remove deletes the article, only its author or an admin may do it
+func (s *Service) Remove(ctx context.Context, id, requesterID int, role string) error {
+	const op = "service.article.Remove"
 
 	log := s.log.With(slog.String("op", op))
 
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+	if _, err := s.authorize(ctx, id, requesterID, role); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
 
 	// Send to storage layer
 	err := s.storage.RemoveArticle(ctx, id)
@@ -149,3 +867,82 @@ func (s *Service) Remove(id int) error {
 
 	return nil
 }
+
+// RemoveMany removes the requester's articles with the given ids, an admin may remove anyone's.
+// The result for each id, in the order given, tells whether it was deleted, not found or forbidden
+func (s *Service) RemoveMany(ctx context.Context, requesterID int, role string, ids []int) ([]models.RemovalResult, error) {
+	const op = "service.article.RemoveMany"
+
+	log := s.log.With(slog.String("op", op))
+
+	// Validation
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("%s: %w", op, ErrNoIDs)
+	}
+	if len(ids) > MaxBulkIDs {
+		return nil, fmt.Errorf("%s: %w", op, ErrTooManyIDs)
+	}
+
+	authorID := requesterID
+	if role == models.RoleAdmin {
+		authorID = 0
+	}
+
+	// Send to storage layer
+	removed, foreign, err := s.storage.RemoveArticlesBulk(ctx, authorID, ids)
+	if err != nil {
+		log.Error("failed to remove articles", sl.Error(err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	outcome := make(map[int]string, len(ids))
+	for _, id := range removed {
+		outcome[id] = models.RemovalDeleted
+	}
+	for _, id := range foreign {
+		outcome[id] = models.RemovalForbidden
+	}
+
+	results := make([]models.RemovalResult, 0, len(ids))
+	seen := make(map[int]bool, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		result, ok := outcome[id]
+		if !ok {
+			result = models.RemovalNotFound
+		}
+		results = append(results, models.RemovalResult{ID: id, Result: result})
+	}
+
+	return results, nil
+}
+
+// authorize lets the author of the article or an admin change it and returns the article.
+// Authors never change, so the check holds until the following write
+func (s *Service) authorize(ctx context.Context, id, requesterID int, role string) (*models.Article, error) {
+	const op = "service.article.authorize"
+
+	log := s.log.With(slog.String("op", op))
+
+	// Send to storage layer
+	art, err := s.storage.GetArticleByID(ctx, id, false, 0)
+	if err != nil {
+		if errors.Is(err, storage.ErrArticleNotFound) {
+			log.Debug("article not found", sl.Error(err))
+			return nil, fmt.Errorf("%s: %w", op, ErrArticleNotFound)
+		}
+		log.Error("failed to get article", sl.Error(err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if art.AuthorID != requesterID && role != models.RoleAdmin {
+		log.Debug("requester isn't the author", slog.Int("article_id", id), slog.Int("requester_id", requesterID))
+		return nil, fmt.Errorf("%s: %w", op, ErrForbidden)
+	}
+
+	return art, nil
+}
@@ -0,0 +1,182 @@
+package article_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"blog-api/internal/domain/models"
+	"blog-api/internal/service/article"
+	"blog-api/internal/storage"
+	"blog-api/internal/testutil"
+)
+
+const (
+	articleID = 10
+	authorID  = 1
+	otherID   = 2
+)
+
+// newOwnedStorage serves one article of authorID and counts the writes that reach it
+func newOwnedStorage(writes *int) *testutil.ArticleStorage {
+	return &testutil.ArticleStorage{
+		GetArticleByIDFunc: func(_ context.Context, id int, _ bool, _ int) (*models.Article, error) {
+			if id != articleID {
+				return nil, storage.ErrArticleNotFound
+			}
+			return &models.Article{ID: articleID, AuthorID: authorID, Title: "Title", Version: 1}, nil
+		},
+		UpdateArticleFunc: func(context.Context, int, string, string, string, string, int) (int, error) {
+			*writes++
+			return 2, nil
+		},
+		RemoveArticleFunc: func(context.Context, int) error {
+			*writes++
+			return nil
+		},
+	}
+}
+
+func newTestService(s article.Storage) *article.Service {
+	return article.New(slog.New(slog.NewTextHandler(io.Discard, nil)), s, nil, nil, nil, nil, false)
+}
+
+func TestOwnership(t *testing.T) {
+	tests := []struct {
+		name        string
+		id          int
+		requesterID int
+		role        string
+		wantErr     error
+	}{
+		{name: "own article", id: articleID, requesterID: authorID, role: models.RoleUser},
+		{name: "someone else's article", id: articleID, requesterID: otherID, role: models.RoleUser, wantErr: article.ErrForbidden},
+		{name: "admin", id: articleID, requesterID: otherID, role: models.RoleAdmin},
+		{name: "missing article", id: 99, requesterID: authorID, role: models.RoleUser, wantErr: article.ErrArticleNotFound},
+	}
+
+	ops := []struct {
+		name string
+		call func(s *article.Service, id, requesterID int, role string) error
+	}{
+		{
+			name: "Update",
+			call: func(s *article.Service, id, requesterID int, role string) error {
+				_, err := s.Update(context.Background(), &models.Article{ID: id, Title: "New title", Version: 1}, requesterID, role)
+				return err
+			},
+		},
+		{
+			name: "Remove",
+			call: func(s *article.Service, id, requesterID int, role string) error {
+				return s.Remove(context.Background(), id, requesterID, role)
+			},
+		},
+	}
+
+	for _, op := range ops {
+		for _, tt := range tests {
+			t.Run(op.name+"/"+tt.name, func(t *testing.T) {
+				var writes int
+				s := newTestService(newOwnedStorage(&writes))
+
+				err := op.call(s, tt.id, tt.requesterID, tt.role)
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("%s() error = %v, want %v", op.name, err, tt.wantErr)
+				}
+
+				wantWrites := 1
+				if tt.wantErr != nil {
+					wantWrites = 0
+				}
+				if writes != wantWrites {
+					t.Errorf("%s() wrote %d times, want %d", op.name, writes, wantWrites)
+				}
+			})
+		}
+	}
+}
+
+func TestRemoveManyScopesToRequester(t *testing.T) {
+	tests := []struct {
+		name         string
+		role         string
+		wantAuthorID int
+	}{
+		{name: "user removes own articles only", role: models.RoleUser, wantAuthorID: otherID},
+		{name: "admin removes anyone's", role: models.RoleAdmin, wantAuthorID: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotAuthorID := -1
+			s := newTestService(&testutil.ArticleStorage{
+				RemoveArticlesBulkFunc: func(_ context.Context, authorID int, ids []int) ([]int, []int, error) {
+					gotAuthorID = authorID
+					return nil, ids, nil
+				},
+			})
+
+			if _, err := s.RemoveMany(context.Background(), otherID, tt.role, []int{articleID}); err != nil {
+				t.Fatalf("RemoveMany() error = %v", err)
+			}
+			if gotAuthorID != tt.wantAuthorID {
+				t.Errorf("RemoveMany() removed articles of author %d, want %d", gotAuthorID, tt.wantAuthorID)
+			}
+		})
+	}
+}
+
+func TestDuplicateTitle(t *testing.T) {
+	long := strings.Repeat("й", 200)
+	// fitted is a copy of long with the prefix, cut to 200 characters
+	fitted := func(prefix string) string {
+		return prefix + strings.Repeat("й", 200-len(prefix))
+	}
+
+	tests := []struct {
+		name      string
+		title     string
+		taken     []string
+		wantTitle string
+	}{
+		{name: "first copy", title: "Title", wantTitle: "Copy of Title"},
+		{name: "longest title", title: long, wantTitle: fitted("Copy of ")},
+		{name: "second copy", title: "Title", taken: []string{"Copy of Title"}, wantTitle: "Copy 2 of Title"},
+		{name: "third copy of the longest title", title: long, taken: []string{fitted("Copy of "), fitted("Copy 2 of ")}, wantTitle: fitted("Copy 3 of ")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var created string
+			s := newTestService(&testutil.ArticleStorage{
+				GetArticleByIDFunc: func(_ context.Context, id int, _ bool, _ int) (*models.Article, error) {
+					return &models.Article{ID: id, AuthorID: authorID, Title: tt.title, Content: "Content"}, nil
+				},
+				CreateArticleFunc: func(_ context.Context, _ int, title, _, _, _, _ string, _ *time.Time) (int64, error) {
+					for _, taken := range tt.taken {
+						if title == taken {
+							return 0, storage.ErrArticleExists
+						}
+					}
+					created = title
+					return 11, nil
+				},
+			})
+
+			id, err := s.Duplicate(context.Background(), articleID, otherID, models.RoleUser)
+			if err != nil {
+				t.Fatalf("Duplicate() error = %v", err)
+			}
+			if id != 11 || created != tt.wantTitle {
+				t.Errorf("Duplicate() created %d titled %q, want 11 titled %q", id, created, tt.wantTitle)
+			}
+			if n := utf8.RuneCountInString(created); n > 200 {
+				t.Errorf("Duplicate() title has %d characters, want at most 200", n)
+			}
+		})
+	}
+}
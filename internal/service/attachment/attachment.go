@@ -0,0 +1,203 @@
+package attachment
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"blog-api/internal/domain/models"
+	"blog-api/internal/lib/logger/sl"
+	"blog-api/internal/storage"
+)
+
+// MaxSize is the largest file that may be attached, in bytes
+const MaxSize = 10 << 20
+
+// allowedTypes are sniffed from the content, the type the client claims is ignored
+var allowedTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/webp": true,
+}
+
+var sumPattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+var (
+	ErrArticleNotFound    = errors.New("article not found")
+	ErrForbidden          = errors.New("not enough rights")
+	ErrAttachmentNotFound = errors.New("attachment not found")
+	ErrTooLarge           = fmt.Errorf("file is larger than %d MB", MaxSize>>20)
+	ErrUnsupportedType    = errors.New("only JPEG, PNG and WebP images may be attached")
+)
+
+type Storage interface {
+	GetArticleByID(ctx context.Context, id int, visibleOnly bool, viewerID int) (*models.Article, error)
+	AddAttachment(ctx context.Context, a models.Attachment) (models.Attachment, error)
+	AttachmentBySHA256(ctx context.Context, sum string) (models.Attachment, error)
+}
+
+type Service struct {
+	log     *slog.Logger
+	storage Storage
+	dir     string
+}
+
+// New creates the service, files are stored in dir by their SHA256
+func New(log *slog.Logger, storage Storage, dir string) *Service {
+	return &Service{
+		log:     log,
+		storage: storage,
+		dir:     dir,
+	}
+}
+
+// Upload attaches content to the article, only its author or an admin may do so.
+// Content already stored for another article isn't written again
+func (s *Service) Upload(ctx context.Context, requesterID int, role string, articleID int, content io.Reader) (models.Attachment, error) {
+	const op = "service.attachment.Upload"
+
+	log := s.log.With(slog.String("op", op))
+
+	// Send to storage layer
+	art, err := s.storage.GetArticleByID(ctx, articleID, false, 0)
+	if err != nil {
+		if errors.Is(err, storage.ErrArticleNotFound) {
+			return models.Attachment{}, fmt.Errorf("%s: %w", op, ErrArticleNotFound)
+		}
+		log.Error("failed to get article", sl.Error(err))
+		return models.Attachment{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if art.AuthorID != requesterID && role != models.RoleAdmin {
+		log.Debug("requester isn't the author", slog.Int("article_id", articleID), slog.Int("requester_id", requesterID))
+		return models.Attachment{}, fmt.Errorf("%s: %w", op, ErrForbidden)
+	}
+
+	a, err := s.save(content)
+	if err != nil {
+		if !errors.Is(err, ErrTooLarge) && !errors.Is(err, ErrUnsupportedType) {
+			log.Error("failed to save attachment", sl.Error(err))
+		}
+		return models.Attachment{}, fmt.Errorf("%s: %w", op, err)
+	}
+	a.ArticleID = articleID
+	a.CreatedAt = time.Now().UTC()
+
+	// Send to storage layer
+	a, err = s.storage.AddAttachment(ctx, a)
+	if err != nil {
+		if errors.Is(err, storage.ErrArticleNotFound) {
+			return models.Attachment{}, fmt.Errorf("%s: %w", op, ErrArticleNotFound)
+		}
+		log.Error("failed to add attachment", sl.Error(err))
+		return models.Attachment{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return a, nil
+}
+
+// Open returns the attachment with the content and the file to serve it from, the caller closes the file
+func (s *Service) Open(ctx context.Context, sum string) (models.Attachment, *os.File, error) {
+	const op = "service.attachment.Open"
+
+	log := s.log.With(slog.String("op", op))
+
+	// Also keeps the path inside the attachment dir
+	if !sumPattern.MatchString(sum) {
+		return models.Attachment{}, nil, fmt.Errorf("%s: %w", op, ErrAttachmentNotFound)
+	}
+
+	// Send to storage layer
+	a, err := s.storage.AttachmentBySHA256(ctx, sum)
+	if err != nil {
+		if errors.Is(err, storage.ErrAttachmentNotFound) {
+			return models.Attachment{}, nil, fmt.Errorf("%s: %w", op, ErrAttachmentNotFound)
+		}
+		log.Error("failed to get attachment", sl.Error(err))
+		return models.Attachment{}, nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	f, err := os.Open(s.path(sum))
+	if err != nil {
+		log.Error("failed to open attachment file", sl.Error(err))
+		if errors.Is(err, os.ErrNotExist) {
+			return models.Attachment{}, nil, fmt.Errorf("%s: %w", op, ErrAttachmentNotFound)
+		}
+		return models.Attachment{}, nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return a, f, nil
+}
+
+// save writes content to a temporary file while hashing it, then moves it to its
+// content address unless a file is already there
+func (s *Service) save(content io.Reader) (models.Attachment, error) {
+	if err := os.MkdirAll(s.dir, 0o750); err != nil {
+		return models.Attachment{}, err
+	}
+
+	tmp, err := os.CreateTemp(s.dir, "upload-*")
+	if err != nil {
+		return models.Attachment{}, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	br := bufio.NewReaderSize(io.LimitReader(content, MaxSize+1), 512)
+	head, err := br.Peek(512)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return models.Attachment{}, err
+	}
+
+	mimeType := http.DetectContentType(head)
+	if !allowedTypes[mimeType] {
+		return models.Attachment{}, ErrUnsupportedType
+	}
+
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, hash), br)
+	if err != nil {
+		return models.Attachment{}, err
+	}
+	if size > MaxSize {
+		return models.Attachment{}, ErrTooLarge
+	}
+	if err := tmp.Close(); err != nil {
+		return models.Attachment{}, err
+	}
+
+	sum := hex.EncodeToString(hash.Sum(nil))
+	path := s.path(sum)
+
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+			return models.Attachment{}, err
+		}
+		if err := os.Rename(tmp.Name(), path); err != nil {
+			return models.Attachment{}, err
+		}
+	} else if err != nil {
+		return models.Attachment{}, err
+	}
+
+	return models.Attachment{
+		SHA256:   sum,
+		MimeType: mimeType,
+		Size:     size,
+	}, nil
+}
+
+// path spreads files over subdirectories by the first byte of their sum
+func (s *Service) path(sum string) string {
+	return filepath.Join(s.dir, sum[:2], sum)
+}
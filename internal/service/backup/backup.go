@@ -0,0 +1,209 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"blog-api/internal/domain/models"
+	"blog-api/internal/lib/logger/sl"
+	"blog-api/internal/storage"
+)
+
+const (
+	namePrefix = "backup-"
+	nameSuffix = ".db"
+	// timeLayout has microseconds so that backups made within a second get different names
+	timeLayout = "20060102-150405.000000"
+	// parseLayout accepts names with and without the fraction, older backups have none
+	parseLayout = "20060102-150405"
+)
+
+var (
+	ErrBackupNotFound = errors.New("backup not found")
+	ErrInvalidName    = errors.New("invalid backup name")
+	ErrCorrupted      = errors.New("backup is corrupted")
+	ErrBusy           = errors.New("restore is already in progress")
+	ErrBackupExists   = errors.New("backup already exists")
+)
+
+type Storage interface {
+	Backup(ctx context.Context, path string) error
+	Integrity(ctx context.Context) error
+	Restore(ctx context.Context, path string) error
+}
+
+type Service struct {
+	log       *slog.Logger
+	storage   Storage
+	dir       string
+	restoring atomic.Bool
+}
+
+func New(log *slog.Logger, storage Storage, dir string) *Service {
+	return &Service{
+		log:     log,
+		storage: storage,
+		dir:     dir,
+	}
+}
+
+// Restoring reports whether a restore is running, writes must be rejected meanwhile
+func (s *Service) Restoring() bool {
+	return s.restoring.Load()
+}
+
+func (s *Service) Create(ctx context.Context) (models.Backup, error) {
+	const op = "service.backup.Create"
+
+	log := s.log.With(slog.String("op", op))
+
+	if err := os.MkdirAll(s.dir, 0o750); err != nil {
+		log.Error("failed to create backup dir", sl.Error(err))
+		return models.Backup{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	now := time.Now().UTC()
+	name := namePrefix + now.Format(timeLayout) + nameSuffix
+	path := filepath.Join(s.dir, name)
+
+	// Reserve the name, an existing backup is never overwritten
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o640)
+	if err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return models.Backup{}, fmt.Errorf("%s: %w", op, ErrBackupExists)
+		}
+		log.Error("failed to create backup file", sl.Error(err))
+		return models.Backup{}, fmt.Errorf("%s: %w", op, err)
+	}
+	f.Close()
+
+	// Send to storage layer
+	if err := s.storage.Backup(ctx, path); err != nil {
+		os.Remove(path)
+		log.Error("failed to create backup", sl.Error(err))
+		return models.Backup{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		log.Error("failed to stat backup", sl.Error(err))
+		return models.Backup{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("backup created", slog.String("name", name))
+
+	return models.Backup{
+		Name:      name,
+		Size:      info.Size(),
+		CreatedAt: now,
+	}, nil
+}
+
+// List returns backups from the newest to the oldest
+func (s *Service) List() ([]models.Backup, error) {
+	const op = "service.backup.List"
+
+	log := s.log.With(slog.String("op", op))
+
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return []models.Backup{}, nil
+		}
+		log.Error("failed to read backup dir", sl.Error(err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	backups := []models.Backup{}
+	for _, entry := range entries {
+		createdAt, ok := parseName(entry.Name())
+		if !ok || entry.IsDir() {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			log.Error("failed to stat backup", sl.Error(err))
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		backups = append(backups, models.Backup{
+			Name:      entry.Name(),
+			Size:      info.Size(),
+			CreatedAt: createdAt,
+		})
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].CreatedAt.After(backups[j].CreatedAt)
+	})
+
+	return backups, nil
+}
+
+// Restore replaces the database with the named backup
+func (s *Service) Restore(ctx context.Context, name string) error {
+	const op = "service.backup.Restore"
+
+	log := s.log.With(slog.String("op", op), slog.String("name", name))
+
+	if _, ok := parseName(name); !ok {
+		return fmt.Errorf("%s: %w", op, ErrInvalidName)
+	}
+
+	path := filepath.Join(s.dir, name)
+	if _, err := os.Stat(path); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("%s: %w", op, ErrBackupNotFound)
+		}
+		log.Error("failed to stat backup", sl.Error(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if !s.restoring.CompareAndSwap(false, true) {
+		return fmt.Errorf("%s: %w", op, ErrBusy)
+	}
+	defer s.restoring.Store(false)
+
+	// Send to storage layer
+	if err := s.storage.Restore(ctx, path); err != nil {
+		if errors.Is(err, storage.ErrCorrupted) {
+			log.Error("backup is corrupted", sl.Error(err))
+			return fmt.Errorf("%s: %w", op, ErrCorrupted)
+		}
+		log.Error("failed to restore backup", sl.Error(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := s.storage.Integrity(ctx); err != nil {
+		log.Error("database integrity check failed after restore", sl.Error(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("backup restored")
+
+	return nil
+}
+
+// parseName checks that name was produced by Create and returns its creation time.
+// This also keeps restore from reaching outside the backup dir
+func parseName(name string) (time.Time, bool) {
+	if filepath.Base(name) != name || !strings.HasPrefix(name, namePrefix) || !strings.HasSuffix(name, nameSuffix) {
+		return time.Time{}, false
+	}
+
+	createdAt, err := time.Parse(parseLayout, strings.TrimSuffix(strings.TrimPrefix(name, namePrefix), nameSuffix))
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return createdAt, true
+}
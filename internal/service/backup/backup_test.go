@@ -0,0 +1,76 @@
+package backup_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"blog-api/internal/service/backup"
+)
+
+// fileStorage writes its content to the backup path like VACUUM INTO does:
+// the target must be missing or empty
+type fileStorage struct {
+	content []byte
+}
+
+func (f *fileStorage) Backup(_ context.Context, path string) error {
+	if info, err := os.Stat(path); err == nil && info.Size() > 0 {
+		return os.ErrExist
+	}
+	return os.WriteFile(path, f.content, 0o640)
+}
+
+func (f *fileStorage) Integrity(context.Context) error { return nil }
+
+func (f *fileStorage) Restore(context.Context, string) error { return nil }
+
+func TestCreateUniqueNames(t *testing.T) {
+	dir := t.TempDir()
+	s := backup.New(slog.New(slog.NewTextHandler(io.Discard, nil)), &fileStorage{content: []byte("db")}, dir)
+
+	// Backups made in a row mostly fall within the same second
+	const n = 5
+	names := make(map[string]bool)
+	for i := 0; i < n; i++ {
+		b, err := s.Create(context.Background())
+		if err != nil {
+			t.Fatalf("Create() #%d error = %v", i, err)
+		}
+		if names[b.Name] {
+			t.Fatalf("Create() #%d name %q was already used", i, b.Name)
+		}
+		names[b.Name] = true
+	}
+
+	list, err := s.List()
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(list) != n {
+		t.Errorf("List() returned %d backups, want %d", len(list), n)
+	}
+}
+
+func TestListOlderNames(t *testing.T) {
+	dir := t.TempDir()
+	const old = "backup-20240102-030405.db"
+	if err := os.WriteFile(filepath.Join(dir, old), []byte("db"), 0o640); err != nil {
+		t.Fatal(err)
+	}
+
+	s := backup.New(slog.New(slog.NewTextHandler(io.Discard, nil)), &fileStorage{}, dir)
+	list, err := s.List()
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(list) != 1 || list[0].Name != old {
+		t.Fatalf("List() = %+v, want only %q", list, old)
+	}
+	if got := list[0].CreatedAt.Format("2006-01-02 15:04:05"); got != "2024-01-02 03:04:05" {
+		t.Errorf("CreatedAt = %s, want 2024-01-02 03:04:05", got)
+	}
+}
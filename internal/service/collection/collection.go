@@ -0,0 +1,293 @@
+package collection
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"blog-api/internal/domain/models"
+	"blog-api/internal/lib/logger/sl"
+	"blog-api/internal/storage"
+)
+
+const (
+	maxTitleLen       = 200
+	maxDescriptionLen = 1000
+)
+
+var (
+	ErrCollectionNotFound     = errors.New("collection not found")
+	ErrForbidden              = errors.New("not enough rights")
+	ErrArticleNotFound        = errors.New("article not found")
+	ErrArticleInCollection    = errors.New("article is already in the collection")
+	ErrArticleNotInCollection = errors.New("article is not in the collection")
+
+	ErrInvalidTitle       = fmt.Errorf("title must be 1 to %d characters long", maxTitleLen)
+	ErrDescriptionTooLong = fmt.Errorf("description is longer than %d characters", maxDescriptionLen)
+)
+
+type Storage interface {
+	CreateCollection(ctx context.Context, c models.Collection) (int64, error)
+	CollectionByID(ctx context.Context, id int64) (models.Collection, error)
+	Collections(ctx context.Context, ownerID int, publicOnly bool) ([]models.Collection, error)
+	UpdateCollection(ctx context.Context, c models.Collection) error
+	RemoveCollection(ctx context.Context, id int64) error
+	AddCollectionArticle(ctx context.Context, collectionID int64, articleID int, addedAt time.Time) error
+	RemoveCollectionArticle(ctx context.Context, collectionID int64, articleID int) error
+	CollectionArticles(ctx context.Context, collectionID int64) ([]models.Article, error)
+	GetArticleByID(ctx context.Context, id int, visibleOnly bool, viewerID int) (*models.Article, error)
+}
+
+type Service struct {
+	log     *slog.Logger
+	storage Storage
+}
+
+func New(log *slog.Logger, storage Storage) *Service {
+	return &Service{
+		log:     log,
+		storage: storage,
+	}
+}
+
+// List returns the owner's collections, private ones only when the owner is the requester.
+// requesterID is 0 for anonymous requests
+func (s *Service) List(ctx context.Context, ownerID, requesterID int) ([]models.Collection, error) {
+	const op = "service.collection.List"
+
+	log := s.log.With(slog.String("op", op))
+
+	// Send to storage layer
+	collections, err := s.storage.Collections(ctx, ownerID, ownerID != requesterID)
+	if err != nil {
+		log.Error("failed to get collections", sl.Error(err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return collections, nil
+}
+
+// Create saves a collection of c.OwnerID and returns it
+func (s *Service) Create(ctx context.Context, c models.Collection) (models.Collection, error) {
+	const op = "service.collection.Create"
+
+	log := s.log.With(slog.String("op", op))
+
+	c.Title = strings.TrimSpace(c.Title)
+	c.Description = strings.TrimSpace(c.Description)
+	c.CreatedAt = time.Now().UTC()
+
+	if err := validate(c); err != nil {
+		return models.Collection{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// Send to storage layer
+	id, err := s.storage.CreateCollection(ctx, c)
+	if err != nil {
+		log.Error("failed to create collection", sl.Error(err))
+		return models.Collection{}, fmt.Errorf("%s: %w", op, err)
+	}
+	c.ID = id
+
+	return c, nil
+}
+
+// Update changes the title when it's not empty, and the description and visibility when they are not nil.
+// Only the owner may update the collection
+func (s *Service) Update(ctx context.Context, requesterID int, id int64, title string, description *string, isPublic *bool) (models.Collection, error) {
+	const op = "service.collection.Update"
+
+	log := s.log.With(slog.String("op", op))
+
+	c, err := s.owned(ctx, requesterID, id)
+	if err != nil {
+		return models.Collection{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if title != "" {
+		c.Title = strings.TrimSpace(title)
+	}
+	if description != nil {
+		c.Description = strings.TrimSpace(*description)
+	}
+	if isPublic != nil {
+		c.IsPublic = *isPublic
+	}
+
+	if err := validate(c); err != nil {
+		return models.Collection{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// Send to storage layer
+	err = s.storage.UpdateCollection(ctx, c)
+	if err != nil {
+		if errors.Is(err, storage.ErrCollectionNotFound) {
+			return models.Collection{}, fmt.Errorf("%s: %w", op, ErrCollectionNotFound)
+		}
+		log.Error("failed to update collection", sl.Error(err))
+		return models.Collection{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return c, nil
+}
+
+// Remove deletes the collection, only the owner may remove it
+func (s *Service) Remove(ctx context.Context, requesterID int, id int64) error {
+	const op = "service.collection.Remove"
+
+	log := s.log.With(slog.String("op", op))
+
+	if _, err := s.owned(ctx, requesterID, id); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	// Send to storage layer
+	err := s.storage.RemoveCollection(ctx, id)
+	if err != nil {
+		if errors.Is(err, storage.ErrCollectionNotFound) {
+			return fmt.Errorf("%s: %w", op, ErrCollectionNotFound)
+		}
+		log.Error("failed to remove collection", sl.Error(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// AddArticle appends a published article to the end of the collection, only the owner may add to it
+func (s *Service) AddArticle(ctx context.Context, requesterID int, id int64, articleID int) error {
+	const op = "service.collection.AddArticle"
+
+	log := s.log.With(slog.String("op", op))
+
+	if _, err := s.owned(ctx, requesterID, id); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	// Send to storage layer
+	art, err := s.storage.GetArticleByID(ctx, articleID, false, 0)
+	if err != nil {
+		if errors.Is(err, storage.ErrArticleNotFound) {
+			return fmt.Errorf("%s: %w", op, ErrArticleNotFound)
+		}
+		log.Error("failed to get article", sl.Error(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	// Drafts are private to their author
+	if art.Status != models.ArticlePublished {
+		return fmt.Errorf("%s: %w", op, ErrArticleNotFound)
+	}
+
+	// Send to storage layer
+	err = s.storage.AddCollectionArticle(ctx, id, articleID, time.Now().UTC())
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrArticleInCollection):
+			return fmt.Errorf("%s: %w", op, ErrArticleInCollection)
+		case errors.Is(err, storage.ErrArticleNotFound):
+			return fmt.Errorf("%s: %w", op, ErrArticleNotFound)
+		}
+		log.Error("failed to add article to collection", sl.Error(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// RemoveArticle takes the article out of the collection, only the owner may remove from it
+func (s *Service) RemoveArticle(ctx context.Context, requesterID int, id int64, articleID int) error {
+	const op = "service.collection.RemoveArticle"
+
+	log := s.log.With(slog.String("op", op))
+
+	if _, err := s.owned(ctx, requesterID, id); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	// Send to storage layer
+	err := s.storage.RemoveCollectionArticle(ctx, id, articleID)
+	if err != nil {
+		if errors.Is(err, storage.ErrArticleNotInCollection) {
+			return fmt.Errorf("%s: %w", op, ErrArticleNotInCollection)
+		}
+		log.Error("failed to remove article from collection", sl.Error(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Articles returns the collection with its published articles in order.
+// Private collections are only found by their owner, requesterID is 0 for anonymous requests
+func (s *Service) Articles(ctx context.Context, requesterID int, id int64) (models.Collection, []models.Article, error) {
+	const op = "service.collection.Articles"
+
+	log := s.log.With(slog.String("op", op))
+
+	c, err := s.byID(ctx, id)
+	if err != nil {
+		return models.Collection{}, nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if !c.IsPublic && c.OwnerID != requesterID {
+		return models.Collection{}, nil, fmt.Errorf("%s: %w", op, ErrCollectionNotFound)
+	}
+
+	// Send to storage layer
+	arts, err := s.storage.CollectionArticles(ctx, id)
+	if err != nil {
+		log.Error("failed to get collection articles", sl.Error(err))
+		return models.Collection{}, nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return c, arts, nil
+}
+
+// owned returns the collection if it belongs to requesterID.
+// Others' private collections are reported as missing rather than forbidden
+func (s *Service) owned(ctx context.Context, requesterID int, id int64) (models.Collection, error) {
+	c, err := s.byID(ctx, id)
+	if err != nil {
+		return models.Collection{}, err
+	}
+
+	if c.OwnerID != requesterID {
+		if !c.IsPublic {
+			return models.Collection{}, ErrCollectionNotFound
+		}
+		return models.Collection{}, ErrForbidden
+	}
+
+	return c, nil
+}
+
+func (s *Service) byID(ctx context.Context, id int64) (models.Collection, error) {
+	const op = "service.collection.byID"
+
+	// Send to storage layer
+	c, err := s.storage.CollectionByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, storage.ErrCollectionNotFound) {
+			return models.Collection{}, ErrCollectionNotFound
+		}
+		s.log.Error("failed to get collection", slog.String("op", op), sl.Error(err))
+		return models.Collection{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return c, nil
+}
+
+func validate(c models.Collection) error {
+	if c.Title == "" || utf8.RuneCountInString(c.Title) > maxTitleLen {
+		return ErrInvalidTitle
+	}
+	if utf8.RuneCountInString(c.Description) > maxDescriptionLen {
+		return ErrDescriptionTooLong
+	}
+
+	return nil
+}
@@ -0,0 +1,86 @@
+package newsletter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"blog-api/internal/domain/models"
+	"blog-api/internal/lib/logger/sl"
+	"blog-api/internal/storage"
+)
+
+type Storage interface {
+	GetArticlesInRange(ctx context.Context, from, to time.Time, language, sort string, visibleOnly bool, viewerID, limit, offset int) ([]models.Article, error)
+	UserByID(ctx context.Context, id int) (models.User, error)
+}
+
+type Service struct {
+	log     *slog.Logger
+	storage Storage
+}
+
+func New(log *slog.Logger, storage Storage) *Service {
+	return &Service{
+		log:     log,
+		storage: storage,
+	}
+}
+
+// Digest returns the articles published between from and to inclusive, grouped by author.
+// Authors come in the order of their first article in the period
+func (s *Service) Digest(ctx context.Context, from, to time.Time) (models.Digest, error) {
+	const op = "service.newsletter.Digest"
+
+	log := s.log.With(slog.String("op", op))
+
+	// Send to storage layer, drafts are left out and a negative limit lifts it
+	arts, err := s.storage.GetArticlesInRange(ctx, from, to, "", "", true, 0, -1, 0)
+	if err != nil {
+		log.Error("failed to get articles in range", sl.Error(err))
+		return models.Digest{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	digest := models.Digest{
+		Period:           from.Format(time.DateOnly) + "/" + to.Format(time.DateOnly),
+		ArticlesByAuthor: []models.AuthorSection{},
+	}
+
+	sections := make(map[int]int)
+	for _, art := range arts {
+		i, ok := sections[art.AuthorID]
+		if !ok {
+			author, err := s.author(ctx, art.AuthorID)
+			if err != nil {
+				return models.Digest{}, fmt.Errorf("%s: %w", op, err)
+			}
+
+			i = len(digest.ArticlesByAuthor)
+			sections[art.AuthorID] = i
+			digest.ArticlesByAuthor = append(digest.ArticlesByAuthor, models.AuthorSection{Author: author})
+		}
+
+		digest.ArticlesByAuthor[i].Articles = append(digest.ArticlesByAuthor[i].Articles, art)
+	}
+
+	return digest, nil
+}
+
+func (s *Service) author(ctx context.Context, id int) (models.User, error) {
+	const op = "service.newsletter.author"
+
+	// Send to storage layer
+	author, err := s.storage.UserByID(ctx, id)
+	if err != nil {
+		// Removed between the two queries, the articles are still worth sending
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return models.User{ID: int64(id)}, nil
+		}
+		s.log.Error("failed to get author", slog.String("op", op), sl.Error(err))
+		return models.User{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return author, nil
+}
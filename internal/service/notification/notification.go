@@ -0,0 +1,197 @@
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"blog-api/internal/domain/models"
+	"blog-api/internal/lib/logger/sl"
+)
+
+const (
+	// queueSize bounds notifications waiting to be written, more are dropped
+	queueSize = 256
+
+	// MaxBulkIDs limits how many notifications are marked as read at once
+	MaxBulkIDs = 100
+)
+
+var ErrTooManyIDs = fmt.Errorf("more than %d notification ids given", MaxBulkIDs)
+
+type Storage interface {
+	AddNotification(ctx context.Context, n models.Notification) error
+	Notifications(ctx context.Context, userID int, unreadOnly bool, limit, offset int) ([]models.Notification, error)
+	UnreadNotificationsCount(ctx context.Context, userID int) (int, error)
+	MarkAllNotificationsRead(ctx context.Context, userID int, readAt time.Time) (int64, error)
+	MarkNotificationsRead(ctx context.Context, userID int, ids []int64, readAt time.Time) (int64, error)
+}
+
+type Service struct {
+	log     *slog.Logger
+	storage Storage
+	queue   chan models.Notification
+	stop    chan struct{}
+	wg      sync.WaitGroup
+}
+
+func New(log *slog.Logger, storage Storage) *Service {
+	return &Service{
+		log:     log,
+		storage: storage,
+		queue:   make(chan models.Notification, queueSize),
+		stop:    make(chan struct{}),
+	}
+}
+
+// Start runs the writer that saves queued notifications
+func (s *Service) Start() {
+	s.wg.Add(1)
+	go s.write()
+}
+
+// Stop waits for already queued notifications to be written or for ctx to expire
+func (s *Service) Stop(ctx context.Context) error {
+	const op = "service.notification.Stop"
+
+	close(s.stop)
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("%s: %w", op, ctx.Err())
+	}
+}
+
+// Notify queues a notification for the user without waiting for it to be saved.
+// Failing to notify must not fail the operation that caused it, so errors are only logged
+func (s *Service) Notify(userID int, typ string, payload any) {
+	const op = "service.notification.Notify"
+
+	log := s.log.With(slog.String("op", op))
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		log.Error("failed to encode notification payload", sl.Error(err))
+		return
+	}
+
+	n := models.Notification{
+		UserID:    userID,
+		Type:      typ,
+		Payload:   data,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	select {
+	case s.queue <- n:
+	default:
+		log.Warn("notification queue is full, dropping notification", slog.Int("user_id", userID), slog.String("type", typ))
+	}
+}
+
+func (s *Service) write() {
+	defer s.wg.Done()
+
+	for {
+		select {
+		case n := <-s.queue:
+			s.save(n)
+		case <-s.stop:
+			// Drain what is already queued
+			for {
+				select {
+				case n := <-s.queue:
+					s.save(n)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (s *Service) save(n models.Notification) {
+	const op = "service.notification.save"
+
+	log := s.log.With(slog.String("op", op))
+
+	// Send to storage layer
+	err := s.storage.AddNotification(context.Background(), n)
+	if err != nil {
+		log.Error("failed to save notification", sl.Error(err))
+	}
+}
+
+// List returns the user's notifications, newest first, and the number of unread ones
+func (s *Service) List(ctx context.Context, userID int, unreadOnly bool, limit, offset int) ([]models.Notification, int, error) {
+	const op = "service.notification.List"
+
+	log := s.log.With(slog.String("op", op))
+
+	// Send to storage layer
+	notifications, err := s.storage.Notifications(ctx, userID, unreadOnly, limit, offset)
+	if err != nil {
+		log.Error("failed to get notifications", sl.Error(err))
+		return nil, 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// Send to storage layer
+	unread, err := s.storage.UnreadNotificationsCount(ctx, userID)
+	if err != nil {
+		log.Error("failed to count unread notifications", sl.Error(err))
+		return nil, 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return notifications, unread, nil
+}
+
+// MarkAllRead marks every notification of the user as read and returns how many were unread
+func (s *Service) MarkAllRead(ctx context.Context, userID int) (int64, error) {
+	const op = "service.notification.MarkAllRead"
+
+	log := s.log.With(slog.String("op", op))
+
+	// Send to storage layer
+	marked, err := s.storage.MarkAllNotificationsRead(ctx, userID, time.Now().UTC())
+	if err != nil {
+		log.Error("failed to mark notifications as read", sl.Error(err))
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return marked, nil
+}
+
+// MarkRead marks the user's notifications with the given ids as read, no ids means all of them.
+// At most MaxBulkIDs ids are accepted
+func (s *Service) MarkRead(ctx context.Context, userID int, ids []int64) (int64, error) {
+	const op = "service.notification.MarkRead"
+
+	if len(ids) == 0 {
+		return s.MarkAllRead(ctx, userID)
+	}
+	if len(ids) > MaxBulkIDs {
+		return 0, fmt.Errorf("%s: %w", op, ErrTooManyIDs)
+	}
+
+	log := s.log.With(slog.String("op", op))
+
+	// Send to storage layer
+	marked, err := s.storage.MarkNotificationsRead(ctx, userID, ids, time.Now().UTC())
+	if err != nil {
+		log.Error("failed to mark notifications as read", sl.Error(err))
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return marked, nil
+}
@@ -0,0 +1,104 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"blog-api/internal/domain/models"
+	"blog-api/internal/lib/logger/sl"
+	"blog-api/internal/storage"
+)
+
+// touchInterval limits how often last_seen of a session is written
+const touchInterval = time.Minute
+
+var ErrSessionNotFound = errors.New("session not found")
+
+type Storage interface {
+	SessionByID(ctx context.Context, id int64) (models.Session, error)
+	ActiveSessions(ctx context.Context, userID int) ([]models.Session, error)
+	TouchSession(ctx context.Context, id int64, lastSeen time.Time) error
+	RevokeSession(ctx context.Context, userID int, id int64) error
+}
+
+type Service struct {
+	log     *slog.Logger
+	storage Storage
+}
+
+func New(log *slog.Logger, storage Storage) *Service {
+	return &Service{
+		log:     log,
+		storage: storage,
+	}
+}
+
+// Active reports whether the session exists and isn't revoked,
+// and records that it was just used
+func (s *Service) Active(ctx context.Context, sid int64) (bool, error) {
+	const op = "service.session.Active"
+
+	log := s.log.With(slog.String("op", op))
+
+	// Send to storage layer
+	sess, err := s.storage.SessionByID(ctx, sid)
+	if err != nil {
+		if errors.Is(err, storage.ErrSessionNotFound) {
+			return false, nil
+		}
+		log.Error("failed to get session", sl.Error(err))
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if sess.Revoked {
+		return false, nil
+	}
+
+	if now := time.Now().UTC(); now.Sub(sess.LastSeen) >= touchInterval {
+		// Send to storage layer
+		if err := s.storage.TouchSession(ctx, sid, now); err != nil {
+			// The session is still valid, only its last_seen is stale
+			log.Error("failed to update session last seen", sl.Error(err))
+		}
+	}
+
+	return true, nil
+}
+
+func (s *Service) List(ctx context.Context, userID int) ([]models.Session, error) {
+	const op = "service.session.List"
+
+	log := s.log.With(slog.String("op", op))
+
+	// Send to storage layer
+	sessions, err := s.storage.ActiveSessions(ctx, userID)
+	if err != nil {
+		log.Error("failed to get sessions", sl.Error(err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return sessions, nil
+}
+
+// Revoke ends the user's session, its tokens stop working right away
+func (s *Service) Revoke(ctx context.Context, userID int, sid int64) error {
+	const op = "service.session.Revoke"
+
+	log := s.log.With(slog.String("op", op))
+
+	// Send to storage layer
+	err := s.storage.RevokeSession(ctx, userID, sid)
+	if err != nil {
+		if errors.Is(err, storage.ErrSessionNotFound) {
+			log.Debug("session not found", sl.Error(err))
+			return fmt.Errorf("%s: %w", op, ErrSessionNotFound)
+		}
+		log.Error("failed to revoke session", sl.Error(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
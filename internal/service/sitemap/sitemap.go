@@ -0,0 +1,134 @@
+package sitemap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"blog-api/internal/domain/models"
+	"blog-api/internal/lib/logger/sl"
+)
+
+// MaxEntries is the sitemap protocol limit of urls in one sitemap
+const MaxEntries = 50_000
+
+var ErrPageNotFound = errors.New("sitemap page not found")
+
+// Entry is a page to list in a sitemap, Path is relative to the base url
+type Entry struct {
+	Path    string
+	LastMod *time.Time
+}
+
+type Storage interface {
+	PublishedArticlesCount(ctx context.Context) (int, error)
+	PublishedArticleDates(ctx context.Context, limit, offset int) ([]models.Article, error)
+	UsersCount(ctx context.Context) (int, error)
+	GetAllUsers(ctx context.Context, limit, offset int) ([]models.User, error)
+}
+
+type Service struct {
+	log     *slog.Logger
+	storage Storage
+}
+
+func New(log *slog.Logger, storage Storage) *Service {
+	return &Service{
+		log:     log,
+		storage: storage,
+	}
+}
+
+// Pages returns how many sitemaps are needed to list every entry, at least one
+func (s *Service) Pages(ctx context.Context) (int, error) {
+	const op = "service.sitemap.Pages"
+
+	articles, users, err := s.counts(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return pages(articles + users), nil
+}
+
+// Page returns entries of the n-th sitemap, counting from 1.
+// Published articles come first, then user profiles
+func (s *Service) Page(ctx context.Context, n int) ([]Entry, error) {
+	const op = "service.sitemap.Page"
+
+	log := s.log.With(slog.String("op", op))
+
+	articles, users, err := s.counts(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if n < 1 || n > pages(articles+users) {
+		return nil, fmt.Errorf("%s: %w", op, ErrPageNotFound)
+	}
+
+	offset := (n - 1) * MaxEntries
+	entries := make([]Entry, 0, MaxEntries)
+
+	if offset < articles {
+		// Send to storage layer
+		arts, err := s.storage.PublishedArticleDates(ctx, MaxEntries, offset)
+		if err != nil {
+			log.Error("failed to get articles", sl.Error(err))
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		for _, art := range arts {
+			entries = append(entries, Entry{
+				Path:    "/articles/" + strconv.Itoa(art.ID),
+				LastMod: art.PublishDate,
+			})
+		}
+	}
+
+	if left := MaxEntries - len(entries); left > 0 {
+		// Send to storage layer
+		usrs, err := s.storage.GetAllUsers(ctx, left, max(0, offset-articles))
+		if err != nil {
+			log.Error("failed to get users", sl.Error(err))
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		for _, usr := range usrs {
+			entries = append(entries, Entry{
+				Path: "/users/" + strconv.FormatInt(usr.ID, 10),
+			})
+		}
+	}
+
+	return entries, nil
+}
+
+func (s *Service) counts(ctx context.Context) (articles, users int, err error) {
+	const op = "service.sitemap.counts"
+
+	log := s.log.With(slog.String("op", op))
+
+	// Send to storage layer
+	articles, err = s.storage.PublishedArticlesCount(ctx)
+	if err != nil {
+		log.Error("failed to count articles", sl.Error(err))
+		return 0, 0, err
+	}
+
+	// Send to storage layer
+	users, err = s.storage.UsersCount(ctx)
+	if err != nil {
+		log.Error("failed to count users", sl.Error(err))
+		return 0, 0, err
+	}
+
+	return articles, users, nil
+}
+
+func pages(entries int) int {
+	return max(1, (entries+MaxEntries-1)/MaxEntries)
+}
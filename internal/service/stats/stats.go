@@ -0,0 +1,108 @@
+package stats
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"blog-api/internal/domain/models"
+	"blog-api/internal/lib/logger/sl"
+	"blog-api/internal/storage"
+)
+
+// Days is how many days of views ArticleStats covers, today included
+const Days = 30
+
+var (
+	ErrArticleNotFound = errors.New("article not found")
+	ErrForbidden       = errors.New("not enough rights")
+)
+
+type Storage interface {
+	GetArticleByID(ctx context.Context, id int, visibleOnly bool, viewerID int) (*models.Article, error)
+	ViewsByDay(ctx context.Context, articleID int, since time.Time) (map[string]int, error)
+	CountLikes(ctx context.Context, articleID int) (int, error)
+	FirstLikeAt(ctx context.Context, articleID int) (*time.Time, error)
+}
+
+type Service struct {
+	log     *slog.Logger
+	storage Storage
+}
+
+func New(log *slog.Logger, storage Storage) *Service {
+	return &Service{
+		log:     log,
+		storage: storage,
+	}
+}
+
+// ArticleStats returns the article's views per day over the last Days days and its likes,
+// only its author or an admin may see them
+func (s *Service) ArticleStats(ctx context.Context, requesterID int, role string, articleID int) (models.ArticleStats, error) {
+	const op = "service.stats.ArticleStats"
+
+	log := s.log.With(slog.String("op", op))
+
+	// Send to storage layer
+	art, err := s.storage.GetArticleByID(ctx, articleID, false, 0)
+	if err != nil {
+		if errors.Is(err, storage.ErrArticleNotFound) {
+			return models.ArticleStats{}, fmt.Errorf("%s: %w", op, ErrArticleNotFound)
+		}
+		log.Error("failed to get article", sl.Error(err))
+		return models.ArticleStats{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if art.AuthorID != requesterID && role != models.RoleAdmin {
+		log.Debug("requester isn't the author", slog.Int("article_id", articleID), slog.Int("requester_id", requesterID))
+		return models.ArticleStats{}, fmt.Errorf("%s: %w", op, ErrForbidden)
+	}
+
+	now := time.Now().UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	since := today.AddDate(0, 0, -(Days - 1))
+
+	// Send to storage layer
+	views, err := s.storage.ViewsByDay(ctx, articleID, since)
+	if err != nil {
+		log.Error("failed to get views by day", sl.Error(err))
+		return models.ArticleStats{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// Send to storage layer
+	likes, err := s.storage.CountLikes(ctx, articleID)
+	if err != nil {
+		log.Error("failed to count likes", sl.Error(err))
+		return models.ArticleStats{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// Send to storage layer
+	firstLike, err := s.storage.FirstLikeAt(ctx, articleID)
+	if err != nil {
+		log.Error("failed to get first like", sl.Error(err))
+		return models.ArticleStats{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	stats := models.ArticleStats{
+		ArticleID: articleID,
+		Views:     make([]models.DayViews, 0, Days),
+		Likes:     likes,
+	}
+
+	// Every day gets a point, so that charts don't have to fill the gaps
+	for day := since; !day.After(today); day = day.AddDate(0, 0, 1) {
+		date := day.Format(time.DateOnly)
+		stats.Views = append(stats.Views, models.DayViews{Date: date, Views: views[date]})
+	}
+
+	if firstLike != nil && art.PublishDate != nil {
+		// Likes are stored with second precision, one right after publishing may seem to precede it
+		after := int64(max(firstLike.Sub(*art.PublishDate), 0) / time.Second)
+		stats.FirstLikeAfter = &after
+	}
+
+	return stats, nil
+}
@@ -2,62 +2,150 @@ package user
 
 import (
 	"context"
+	"crypto/rand"
 	"errors"
 	"fmt"
 	"log/slog"
+	"net/mail"
+	"strings"
+	"sync"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"blog-api/internal/domain/models"
 	"blog-api/internal/lib/jwt"
 	"blog-api/internal/lib/logger/sl"
 	"blog-api/internal/storage"
 
-	"github.com/mattn/go-sqlite3"
 	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	// maxStatusLen is the limit of a user's status in characters
+	maxStatusLen = 140
+	// resetTokenTTL is how long a password reset token may be used
+	resetTokenTTL = time.Hour
 )
 
 var (
 	ErrUserExists   = errors.New("user name already taken")
 	ErrUserNotFound = errors.New("user not found")
 
-	ErrUserNameTaken = errors.New("user name already taken")
-	ErrTitleTaken    = errors.New("article title already taken")
+	// ErrInvalidCredentials doesn't tell an unknown user from a wrong password
+	ErrInvalidCredentials = errors.New("invalid credentials")
+
+	ErrUserNameTaken   = errors.New("user name already taken")
+	ErrEmailTaken      = errors.New("email already taken")
+	ErrInvalidEmail    = errors.New("invalid email")
+	ErrInvalidUserName = errors.New(`user name must not contain "@"`)
+	ErrNothingToCheck  = errors.New("user name or email is required")
+	ErrTitleTaken      = errors.New("article title already taken")
+	ErrStatusTooLong   = fmt.Errorf("status is longer than %d characters", maxStatusLen)
+	ErrInvalidStatus   = errors.New("status must not contain control characters")
+
+	ErrEmptyPassword = errors.New("password is empty")
+	// ErrInvalidResetToken doesn't tell an unknown token from an expired or used one
+	ErrInvalidResetToken = errors.New("invalid or expired reset token")
+	ErrAccountLocked     = errors.New("account is locked after too many failed logins")
 )
 
+// LockedError is returned by Login while the account is locked, it wraps ErrAccountLocked
+type LockedError struct {
+	Until time.Time
+}
+
+func (e *LockedError) Error() string {
+	return ErrAccountLocked.Error() + " until " + e.Until.Format(time.RFC3339)
+}
+
+func (e *LockedError) Unwrap() error {
+	return ErrAccountLocked
+}
+
+// Lockout locks an account for Duration after MaxFailures wrong passwords in a row.
+// Zero MaxFailures turns it off
+type Lockout struct {
+	MaxFailures int
+	Duration    time.Duration
+}
+
 type Storage interface {
-	GetAllUsers(ctx context.Context) ([]models.User, error)
+	GetAllUsers(ctx context.Context, limit, offset int) ([]models.User, error)
 	RemoveUser(ctx context.Context, id int) error
 	UpdateUserName(ctx context.Context, id int, userName string) error
 	UpdateStatus(ctx context.Context, id int, status string) error
+	UpdateEmail(ctx context.Context, id int, email string) error
+	UpdatePassHash(ctx context.Context, id int64, passHash []byte) error
 	UserByID(ctx context.Context, id int) (models.User, error)
-	UserByName(ctx context.Context, userName string) (models.User, error)
-	Register(ctx context.Context, userName string, passHash []byte, regestrationDate time.Time) error
+	UserStats(ctx context.Context, id int) (models.UserStats, error)
+	GetUserByUsername(ctx context.Context, userName string) (models.User, error)
+	GetTotalLikesForAuthor(ctx context.Context, authorID int) (int, error)
+	UserByIdentifier(ctx context.Context, identifier string) (models.User, error)
+	UserNameExists(ctx context.Context, userName string) (bool, error)
+	EmailExists(ctx context.Context, email string) (bool, error)
+	Register(ctx context.Context, userName, email string, passHash []byte, regestrationDate time.Time) (int64, error)
+	AddLoginEvent(ctx context.Context, e models.LoginEvent) error
+	AddLoginFailure(ctx context.Context, userID int64) (int, error)
+	LockUser(ctx context.Context, userID int64, until time.Time) error
+	ResetLoginFailures(ctx context.Context, userID int64) error
+	GetLoginHistory(ctx context.Context, userID, limit, offset int) ([]models.LoginEvent, error)
+	CreateSession(ctx context.Context, sess models.Session) (int64, error)
+	RevokeOldestSessions(ctx context.Context, userID int64, keep int) error
+	UserByEmail(ctx context.Context, email string) (models.User, error)
+	CreatePasswordReset(ctx context.Context, token string, userID int64, expiresAt time.Time) error
+	ResetPassword(ctx context.Context, token string, passHash []byte, now time.Time) (int64, error)
+}
+
+// Mailer emails password reset tokens
+type Mailer interface {
+	SendPasswordReset(to, userName, token string, ttl time.Duration) error
 }
 
 type Service struct {
-	log      *slog.Logger
-	storage  Storage
-	tokenTTL time.Duration
+	log          *slog.Logger
+	storage      Storage
+	tokenTTL     time.Duration
+	rememberTTL  time.Duration
+	keys         *jwt.KeyProvider
+	sessionLimit int
+	bcryptCost   int
+	lockout      Lockout
+	mailer       Mailer
+	// unknownUserHash is compared against on logins of unknown users,
+	// so that they take as long as a wrong password
+	unknownUserHash func() []byte
 }
 
-func New(log *slog.Logger, storage Storage, ttl time.Duration) *Service {
+// New creates the service. Tokens last ttl, or rememberTTL when the login asks to be remembered. Logging in beyond sessionLimit active sessions
+// revokes the oldest ones, 0 means no limit. Passwords are hashed with bcryptCost,
+// hashes with a lower cost are upgraded on login. mailer may be nil when password resets aren't served
+func New(log *slog.Logger, storage Storage, ttl, rememberTTL time.Duration, keys *jwt.KeyProvider, sessionLimit, bcryptCost int, lockout Lockout, mailer Mailer) *Service {
 	return &Service{
-		log:      log,
-		storage:  storage,
-		tokenTTL: ttl,
+		log:          log,
+		storage:      storage,
+		tokenTTL:     ttl,
+		rememberTTL:  rememberTTL,
+		keys:         keys,
+		sessionLimit: sessionLimit,
+		bcryptCost:   bcryptCost,
+		lockout:      lockout,
+		mailer:       mailer,
+		unknownUserHash: sync.OnceValue(func() []byte {
+			hash, _ := bcrypt.GenerateFromPassword([]byte("unknown user"), bcryptCost)
+			return hash
+		}),
 	}
 }
 
-func (s *Service) GetAll() ([]models.User, error) {
-	const op = "service.user.GetAllUsers"
+func (s *Service) GetAll(ctx context.Context, limit, offset int) ([]models.User, error) {
+	const op = "service.user.GetAll"
 
 	log := s.log.With(slog.String("op", op))
 
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-
 	// Send to storage layer
-	users, err := s.storage.GetAllUsers(ctx)
+	users, err := s.storage.GetAllUsers(ctx, limit, offset)
 	if err != nil {
 		log.Error("failed to get all users", sl.Error(err))
 		return nil, fmt.Errorf("%s: %w", op, err)
@@ -66,121 +154,510 @@ func (s *Service) GetAll() ([]models.User, error) {
 	return users, nil
 }
 
-func (s *Service) Register(userName, password string) error {
+// Register creates a user and returns its id, email is optional
+func (s *Service) Register(ctx context.Context, userName, email, password string) (int64, error) {
 	const op = "service.user.Register"
 
 	log := s.log.With(slog.String("op", op))
 
+	if !validUserName(userName) {
+		return 0, fmt.Errorf("%s: %w", op, ErrInvalidUserName)
+	}
+	if email != "" && !validEmail(email) {
+		return 0, fmt.Errorf("%s: %w", op, ErrInvalidEmail)
+	}
+
 	// Hashing password
-	passHash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	passHash, err := bcrypt.GenerateFromPassword([]byte(password), s.bcryptCost)
 	if err != nil {
 		log.Error("failed to generate hash from password", sl.Error(err))
-		return fmt.Errorf("%s: %w", op, err)
+		return 0, fmt.Errorf("%s: %w", op, err)
 	}
 
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-
 	// Send to data layer
-	err = s.storage.Register(ctx, userName, passHash, time.Now())
+	id, err := s.storage.Register(ctx, userName, email, passHash, time.Now().UTC())
 	if err != nil {
+		if errors.Is(err, storage.ErrEmailTaken) {
+			log.Debug("email already taken", sl.Error(err))
+			return 0, fmt.Errorf("%s: %w", op, ErrEmailTaken)
+		}
 		if errors.Is(err, storage.ErrUserExists) {
 			log.Error("failed to register user", sl.Error(ErrUserExists))
-			return fmt.Errorf("%s: %w", op, ErrUserExists)
+			return 0, fmt.Errorf("%s: %w", op, ErrUserExists)
 		}
 		log.Error("failed to register user", sl.Error(err))
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return id, nil
+}
+
+// upgradePassHash re-hashes the password if its hash was made with a lower cost than the current one.
+// Failing to do so doesn't fail the login, the upgrade is retried on the next one
+func (s *Service) upgradePassHash(ctx context.Context, user models.User, password string) {
+	const op = "service.user.upgradePassHash"
+
+	log := s.log.With(slog.String("op", op))
+
+	cost, err := bcrypt.Cost(user.PassHash)
+	if err != nil {
+		log.Error("failed to get password hash cost", sl.Error(err))
+		return
+	}
+	if cost >= s.bcryptCost {
+		return
+	}
+
+	passHash, err := bcrypt.GenerateFromPassword([]byte(password), s.bcryptCost)
+	if err != nil {
+		log.Error("failed to generate hash from password", sl.Error(err))
+		return
+	}
+
+	// Send to data layer
+	if err := s.storage.UpdatePassHash(ctx, user.ID, passHash); err != nil {
+		log.Error("failed to upgrade password hash", sl.Error(err))
+		return
+	}
+
+	log.Info("password hash upgraded", slog.Int64("user_id", user.ID), slog.Int("from_cost", cost), slog.Int("to_cost", s.bcryptCost))
+}
+
+// ForgotPassword emails a reset token to the user with the given email. Unknown emails
+// are not reported, so that the endpoint can't tell which emails are registered
+func (s *Service) ForgotPassword(ctx context.Context, email string) error {
+	const op = "service.user.ForgotPassword"
+
+	log := s.log.With(slog.String("op", op))
+
+	if !validEmail(email) {
+		return fmt.Errorf("%s: %w", op, ErrInvalidEmail)
+	}
+
+	// Send to data layer
+	user, err := s.storage.UserByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Debug("password reset asked for unknown email")
+			return nil
+		}
+		log.Error("failed to get user by email", sl.Error(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	token, err := newResetToken()
+	if err != nil {
+		log.Error("failed to generate reset token", sl.Error(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	// Send to data layer
+	err = s.storage.CreatePasswordReset(ctx, token, user.ID, time.Now().UTC().Add(resetTokenTTL))
+	if err != nil {
+		log.Error("failed to save reset token", sl.Error(err))
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
+	// Sent in the background, so that the response time doesn't tell known emails either
+	go func() {
+		if err := s.mailer.SendPasswordReset(email, user.UserName, token, resetTokenTTL); err != nil {
+			log.Warn("failed to send password reset email", slog.Int64("user_id", user.ID), sl.Error(err))
+		}
+	}()
+
 	return nil
 }
 
-func (s *Service) Login(userName, password, secret string) (token string, err error) {
-	const op = "service.user.Login"
+// ResetPassword sets a new password for the owner of the token. The token works once,
+// and all of the user's sessions are revoked
+func (s *Service) ResetPassword(ctx context.Context, token, password string) error {
+	const op = "service.user.ResetPassword"
+
+	log := s.log.With(slog.String("op", op))
+
+	if password == "" {
+		return fmt.Errorf("%s: %w", op, ErrEmptyPassword)
+	}
+
+	passHash, err := bcrypt.GenerateFromPassword([]byte(password), s.bcryptCost)
+	if err != nil {
+		log.Error("failed to generate hash from password", sl.Error(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	// Send to data layer
+	userID, err := s.storage.ResetPassword(ctx, token, passHash, time.Now().UTC())
+	if err != nil {
+		if errors.Is(err, storage.ErrResetTokenNotFound) ||
+			errors.Is(err, storage.ErrResetTokenExpired) ||
+			errors.Is(err, storage.ErrResetTokenUsed) {
+			log.Debug("invalid reset token", sl.Error(err))
+			return fmt.Errorf("%s: %w", op, ErrInvalidResetToken)
+		}
+		log.Error("failed to reset password", sl.Error(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("password reset", slog.Int64("user_id", userID))
+
+	return nil
+}
+
+// newResetToken returns a random version 4 UUID
+func newResetToken() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
+
+// Available reports whether the user name and email may be used to register, empty ones aren't checked
+func (s *Service) Available(ctx context.Context, userName, email string) (bool, error) {
+	const op = "service.user.Available"
 
 	log := s.log.With(slog.String("op", op))
 
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+	// Validation
+	if userName == "" && email == "" {
+		return false, fmt.Errorf("%s: %w", op, ErrNothingToCheck)
+	}
+	if !validUserName(userName) {
+		return false, fmt.Errorf("%s: %w", op, ErrInvalidUserName)
+	}
+	if email != "" && !validEmail(email) {
+		return false, fmt.Errorf("%s: %w", op, ErrInvalidEmail)
+	}
+
+	if userName != "" {
+		// Send to data layer
+		taken, err := s.storage.UserNameExists(ctx, userName)
+		if err != nil {
+			log.Error("failed to check user name", sl.Error(err))
+			return false, fmt.Errorf("%s: %w", op, err)
+		}
+		if taken {
+			return false, nil
+		}
+	}
+
+	if email != "" {
+		// Send to data layer
+		taken, err := s.storage.EmailExists(ctx, email)
+		if err != nil {
+			log.Error("failed to check email", sl.Error(err))
+			return false, fmt.Errorf("%s: %w", op, err)
+		}
+		if taken {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// validUserName rejects names with "@", so that a name never matches someone else's email on login
+func validUserName(userName string) bool {
+	return !strings.Contains(userName, "@")
+}
+
+// validEmail accepts a bare address like "bob@example.com", without a display name
+func validEmail(email string) bool {
+	addr, err := mail.ParseAddress(email)
+	return err == nil && addr.Address == email
+}
+
+// Login checks the credentials and issues a token, identifier is the user name or email.
+// A remembered login gets a token that lasts rememberTTL instead of the usual ttl.
+// Every attempt on an existing account is recorded in its login history with the client ip and user agent.
+// Too many wrong passwords in a row lock the account, Login then returns a *LockedError
+func (s *Service) Login(ctx context.Context, identifier, password string, rememberMe bool, ip, userAgent string) (token string, err error) {
+	const op = "service.user.Login"
+
+	log := s.log.With(slog.String("op", op))
 
 	// Send to data layer
-	user, err := s.storage.UserByName(ctx, userName)
+	user, err := s.storage.UserByIdentifier(ctx, identifier)
 	if err != nil {
-		if errors.As(err, &storage.ErrUserNotFound) {
-			log.Error("failed to get user by name", sl.Error(ErrUserNotFound))
-			return "", fmt.Errorf("%s: %w", op, ErrUserNotFound)
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Debug("user not found", sl.Error(err))
+			// Answering faster than for a wrong password would tell which names exist
+			_ = bcrypt.CompareHashAndPassword(s.unknownUserHash(), []byte(password))
+			return "", fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
 		}
 		return "", fmt.Errorf("%s: %w", op, err)
 	}
 
+	// The password isn't checked while locked, guesses are refused either way
+	if user.LockedUntil != nil && time.Now().Before(*user.LockedUntil) {
+		log.Debug("account is locked", slog.Int64("user_id", user.ID))
+		s.recordLogin(ctx, user.ID, ip, userAgent, false)
+		return "", fmt.Errorf("%s: %w", op, &LockedError{Until: *user.LockedUntil})
+	}
+
 	// Checking if password correct
 	err = bcrypt.CompareHashAndPassword(user.PassHash, []byte(password))
 	if err != nil {
-		log.Error("incorrect password", sl.Error(err))
-		return "", fmt.Errorf("%s: incorrect password: %w", op, err)
+		log.Debug("incorrect password", sl.Error(err))
+		s.recordLogin(ctx, user.ID, ip, userAgent, false)
+		if until, locked := s.countFailure(ctx, user.ID); locked {
+			return "", fmt.Errorf("%s: %w", op, &LockedError{Until: until})
+		}
+		return "", fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
+	}
+
+	// Send to data layer
+	if err := s.storage.ResetLoginFailures(ctx, user.ID); err != nil {
+		log.Error("failed to reset failed logins", sl.Error(err))
+	}
+
+	s.upgradePassHash(ctx, user, password)
+
+	now := time.Now().UTC()
+
+	// Send to data layer
+	sid, err := s.storage.CreateSession(ctx, models.Session{
+		UserID:    user.ID,
+		UserAgent: userAgent,
+		IP:        ip,
+		CreatedAt: now,
+		LastSeen:  now,
+	})
+	if err != nil {
+		log.Error("failed to create session", sl.Error(err))
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	if s.sessionLimit > 0 {
+		// Send to data layer
+		err = s.storage.RevokeOldestSessions(ctx, user.ID, s.sessionLimit)
+		if err != nil {
+			log.Error("failed to revoke old sessions", sl.Error(err))
+			return "", fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	ttl := s.tokenTTL
+	if rememberMe {
+		ttl = s.rememberTTL
 	}
 
 	// Generating token
-	token, err = jwt.NewToken(user, s.tokenTTL, secret)
+	token, err = jwt.NewToken(user, sid, ttl, s.keys)
 	if err != nil {
 		log.Error("failed to create new token", sl.Error(err))
 		return "", fmt.Errorf("%s: failed to create new token: %w", op, err)
 	}
 
+	s.recordLogin(ctx, user.ID, ip, userAgent, true)
+
 	return token, nil
 }
 
-func (s *Service) UserByID(id int) (models.User, error) {
-	const op = "service.user.UserByID"
+// recordLogin adds a login attempt to the history, failing to do so doesn't fail the login
+func (s *Service) recordLogin(ctx context.Context, userID int64, ip, userAgent string, success bool) {
+	const op = "service.user.recordLogin"
+
+	log := s.log.With(slog.String("op", op))
+
+	// Send to data layer
+	err := s.storage.AddLoginEvent(ctx, models.LoginEvent{
+		UserID:     userID,
+		IP:         ip,
+		UserAgent:  userAgent,
+		LoggedInAt: time.Now().UTC(),
+		Success:    success,
+	})
+	if err != nil {
+		log.Error("failed to record login", sl.Error(err))
+	}
+}
+
+// countFailure counts a wrong password and locks the account once there are too many in a row.
+// It reports whether the account got locked and until when. Errors only lose the count
+func (s *Service) countFailure(ctx context.Context, userID int64) (time.Time, bool) {
+	const op = "service.user.countFailure"
+
+	if s.lockout.MaxFailures <= 0 {
+		return time.Time{}, false
+	}
+
+	log := s.log.With(slog.String("op", op))
+
+	// Send to data layer
+	failures, err := s.storage.AddLoginFailure(ctx, userID)
+	if err != nil {
+		log.Error("failed to count failed login", sl.Error(err))
+		return time.Time{}, false
+	}
+	if failures < s.lockout.MaxFailures {
+		return time.Time{}, false
+	}
+
+	until := time.Now().UTC().Add(s.lockout.Duration)
+
+	// Send to data layer
+	if err := s.storage.LockUser(ctx, userID, until); err != nil {
+		log.Error("failed to lock user", sl.Error(err))
+		return time.Time{}, false
+	}
+	log.Warn("account locked after failed logins", slog.Int64("user_id", userID), slog.Int("failures", failures), slog.Time("until", until))
+
+	return until, true
+}
+
+func (s *Service) LoginHistory(ctx context.Context, userID, limit, offset int) ([]models.LoginEvent, error) {
+	const op = "service.user.LoginHistory"
 
 	log := s.log.With(slog.String("op", op))
 
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+	// Send to data layer
+	history, err := s.storage.GetLoginHistory(ctx, userID, limit, offset)
+	if err != nil {
+		log.Error("failed to get login history", sl.Error(err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return history, nil
+}
+
+func (s *Service) UserByName(ctx context.Context, userName string) (models.User, error) {
+	const op = "service.user.UserByName"
+
+	log := s.log.With(slog.String("op", op))
 
 	// Send to data layer
-	user, err := s.storage.UserByID(ctx, id)
+	user, err := s.storage.GetUserByUsername(ctx, userName)
 	if err != nil {
-		var sqliteErr sqlite3.Error
-		if errors.As(err, &sqliteErr) && sqliteErr.Code == sqlite3.ErrNotFound {
-			log.Error("user not found", ErrUserNotFound)
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Debug("user not found", sl.Error(err))
+			return models.User{}, fmt.Errorf("%s: %w", op, ErrUserNotFound)
 		}
 		log.Error("failed get user", sl.Error(err))
-		return models.User{}, err
+		return models.User{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// Send to data layer
+	user.TotalLikesReceived, err = s.storage.GetTotalLikesForAuthor(ctx, int(user.ID))
+	if err != nil {
+		log.Error("failed to count likes received", sl.Error(err))
+		return models.User{}, fmt.Errorf("%s: %w", op, err)
 	}
 
 	return user, nil
 }
 
-func (s *Service) Remove(id int) error {
-	const op = "service.user.RemoveUser"
+func (s *Service) UserByID(ctx context.Context, id int) (models.User, error) {
+	const op = "service.user.UserByID"
 
 	log := s.log.With(slog.String("op", op))
 
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+	// The stats don't depend on the user row, so all queries run at once.
+	// The first one to fail cancels the others
+	g, gctx := errgroup.WithContext(ctx)
+
+	var (
+		user  models.User
+		stats userStats
+	)
+	g.Go(func() error {
+		var err error
+		// Send to data layer
+		user, err = s.storage.UserByID(gctx, id)
+		return err
+	})
+	g.Go(func() error {
+		var err error
+		// Send to data layer
+		stats.likesReceived, err = s.storage.GetTotalLikesForAuthor(gctx, id)
+		return err
+	})
+
+	if err := g.Wait(); err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Debug("user not found", sl.Error(err))
+			return models.User{}, fmt.Errorf("%s: %w", op, ErrUserNotFound)
+		}
+		log.Error("failed get user", sl.Error(err))
+		return models.User{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	stats.merge(&user)
+
+	return user, nil
+}
+
+// userStats are the profile counts UserByID fetches next to the user row
+type userStats struct {
+	likesReceived int
+}
+
+func (st userStats) merge(user *models.User) {
+	user.TotalLikesReceived = st.likesReceived
+}
+
+// Stats returns the counts shown on the user's profile
+func (s *Service) Stats(ctx context.Context, id int) (models.UserStats, error) {
+	const op = "service.user.Stats"
+
+	log := s.log.With(slog.String("op", op))
+
+	// Send to data layer
+	stats, err := s.storage.UserStats(ctx, id)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Debug("user not found", sl.Error(err))
+			return models.UserStats{}, fmt.Errorf("%s: %w", op, ErrUserNotFound)
+		}
+		log.Error("failed to get user stats", sl.Error(err))
+		return models.UserStats{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return stats, nil
+}
+
+func (s *Service) Remove(ctx context.Context, id int) error {
+	const op = "service.user.RemoveUser"
+
+	log := s.log.With(slog.String("op", op))
 
 	// Send to data layer
 	err := s.storage.RemoveUser(ctx, id)
 	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Debug("user not found", sl.Error(err))
+			return fmt.Errorf("%s: %w", op, ErrUserNotFound)
+		}
 		log.Error("failed to remove user", sl.Error(err))
-		return err
+		return fmt.Errorf("%s: %w", op, err)
 	}
 
 	return nil
 }
 
-func (s *Service) UpdateUserName(id int, userName string) error {
+func (s *Service) UpdateUserName(ctx context.Context, id int, userName string) error {
 	const op = "service.user.UpdateUserName"
 
 	log := s.log.With(slog.String("op", op))
 
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+	if !validUserName(userName) {
+		return fmt.Errorf("%s: %w", op, ErrInvalidUserName)
+	}
 
 	// Send to data layer
 	err := s.storage.UpdateUserName(ctx, id, userName)
 	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Debug("user not found", sl.Error(err))
+			return fmt.Errorf("%s: %w", op, ErrUserNotFound)
+		}
+		if errors.Is(err, storage.ErrUserNameTaken) {
+			log.Debug("user name already taken", sl.Error(err))
+			return fmt.Errorf("%s: %w", op, ErrUserNameTaken)
+		}
 		log.Error("failed to update user name", sl.Error(err))
 		return fmt.Errorf("%s: %w", op, err)
 	}
@@ -188,16 +665,56 @@ func (s *Service) UpdateUserName(id int, userName string) error {
 	return nil
 }
 
-func (s *Service) UpdateStatus(id int, userName string) error {
+// UpdateEmail sets the user's email, an empty one removes it
+func (s *Service) UpdateEmail(ctx context.Context, id int, email string) error {
+	const op = "service.user.UpdateEmail"
+
+	log := s.log.With(slog.String("op", op))
+
+	if email != "" && !validEmail(email) {
+		return fmt.Errorf("%s: %w", op, ErrInvalidEmail)
+	}
+
+	// Send to data layer
+	err := s.storage.UpdateEmail(ctx, id, email)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Debug("user not found", sl.Error(err))
+			return fmt.Errorf("%s: %w", op, ErrUserNotFound)
+		}
+		if errors.Is(err, storage.ErrEmailTaken) {
+			log.Debug("email already taken", sl.Error(err))
+			return fmt.Errorf("%s: %w", op, ErrEmailTaken)
+		}
+		log.Error("failed to update email", sl.Error(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// UpdateStatus sets the user's status with surrounding spaces trimmed, an empty one clears it
+func (s *Service) UpdateStatus(ctx context.Context, id int, status string) error {
 	const op = "service.user.UpdateStatus"
 
 	log := s.log.With(slog.String("op", op))
 
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+	// Validation
+	status = strings.TrimSpace(status)
+	if utf8.RuneCountInString(status) > maxStatusLen {
+		return fmt.Errorf("%s: %w", op, ErrStatusTooLong)
+	}
+	if strings.IndexFunc(status, unicode.IsControl) >= 0 {
+		return fmt.Errorf("%s: %w", op, ErrInvalidStatus)
+	}
 
-	err := s.storage.UpdateStatus(ctx, id, userName)
+	// Send to data layer
+	err := s.storage.UpdateStatus(ctx, id, status)
 	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Debug("user not found", sl.Error(err))
+			return fmt.Errorf("%s: %w", op, ErrUserNotFound)
+		}
 		log.Error("failed to update status", sl.Error(err))
 		return fmt.Errorf("%s: %w", op, err)
 	}
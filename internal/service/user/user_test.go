@@ -0,0 +1,134 @@
+package user_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"blog-api/internal/domain/models"
+	"blog-api/internal/service/user"
+	"blog-api/internal/storage"
+	"blog-api/internal/testutil"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// errDB stands for any storage failure that isn't a domain error
+var errDB = errors.New("database is locked")
+
+func newTestService(s user.Storage) *user.Service {
+	return user.New(slog.New(slog.NewTextHandler(io.Discard, nil)), s, time.Hour, time.Hour, nil, 0, bcrypt.MinCost, user.Lockout{}, nil)
+}
+
+func TestLoginUnknownUser(t *testing.T) {
+	tests := []struct {
+		name       string
+		storageErr error
+		want       error
+		notWant    error
+	}{
+		{name: "not found", storageErr: fmt.Errorf("storage.sqlite.UserByIdentifier: %w", storage.ErrUserNotFound), want: user.ErrInvalidCredentials},
+		// Only a missing user is reported as bad credentials, a failing database is an error of its own
+		{name: "storage failure", storageErr: errDB, want: errDB, notWant: user.ErrInvalidCredentials},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(&testutil.UserStorage{
+				UserByIdentifierFunc: func(context.Context, string) (models.User, error) {
+					return models.User{}, tt.storageErr
+				},
+			})
+
+			_, err := s.Login(context.Background(), "nobody", "password", false, "127.0.0.1", "test")
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Login() error = %v, want %v", err, tt.want)
+			}
+			if tt.notWant != nil && errors.Is(err, tt.notWant) {
+				t.Errorf("Login() error = %v, must not be %v", err, tt.notWant)
+			}
+		})
+	}
+
+	// Matching must not overwrite the sentinel, as errors.As on its address used to
+	if storage.ErrUserNotFound.Error() != "user not found" {
+		t.Errorf("storage.ErrUserNotFound = %q, it was overwritten", storage.ErrUserNotFound)
+	}
+}
+
+func TestUpdateUserNameErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		storageErr error
+		want       error
+		notWant    []error
+	}{
+		{name: "not found", storageErr: fmt.Errorf("storage.sqlite.UpdateUserName: %w", storage.ErrUserNotFound), want: user.ErrUserNotFound, notWant: []error{user.ErrUserNameTaken}},
+		{name: "name taken", storageErr: fmt.Errorf("storage.sqlite.UpdateUserName: %w", storage.ErrUserNameTaken), want: user.ErrUserNameTaken, notWant: []error{user.ErrUserNotFound}},
+		{name: "storage failure", storageErr: errDB, want: errDB, notWant: []error{user.ErrUserNotFound, user.ErrUserNameTaken}},
+		{name: "renamed", storageErr: nil, want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(&testutil.UserStorage{
+				UpdateUserNameFunc: func(context.Context, int, string) error {
+					return tt.storageErr
+				},
+			})
+
+			err := s.UpdateUserName(context.Background(), 1, "alice")
+			if !errors.Is(err, tt.want) {
+				t.Errorf("UpdateUserName() error = %v, want %v", err, tt.want)
+			}
+			for _, notWant := range tt.notWant {
+				if errors.Is(err, notWant) {
+					t.Errorf("UpdateUserName() error = %v, must not be %v", err, notWant)
+				}
+			}
+		})
+	}
+}
+
+func TestUserByIDStats(t *testing.T) {
+	tests := []struct {
+		name      string
+		userErr   error
+		likesErr  error
+		want      error
+		wantLikes int
+	}{
+		{name: "found", wantLikes: 7},
+		{name: "not found", userErr: fmt.Errorf("storage.sqlite.UserByID: %w", storage.ErrUserNotFound), want: user.ErrUserNotFound},
+		{name: "user query fails", userErr: errDB, want: errDB},
+		{name: "likes query fails", likesErr: errDB, want: errDB},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(&testutil.UserStorage{
+				UserByIDFunc: func(_ context.Context, id int) (models.User, error) {
+					if tt.userErr != nil {
+						return models.User{}, tt.userErr
+					}
+					return models.User{ID: int64(id), Credentials: models.Credentials{UserName: "alice"}, CurrentStreak: 3}, nil
+				},
+				GetTotalLikesForAuthorFunc: func(context.Context, int) (int, error) {
+					return 7, tt.likesErr
+				},
+			})
+
+			got, err := s.UserByID(context.Background(), 1)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("UserByID() error = %v, want %v", err, tt.want)
+			}
+			if tt.want != nil {
+				return
+			}
+			if got.ID != 1 || got.CurrentStreak != 3 || got.TotalLikesReceived != tt.wantLikes {
+				t.Errorf("UserByID() = %+v, want user 1 with streak 3 and %d likes", got, tt.wantLikes)
+			}
+		})
+	}
+}
@@ -0,0 +1,382 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"sync"
+	"time"
+
+	"blog-api/internal/domain/models"
+	"blog-api/internal/lib/logger/sl"
+	"blog-api/internal/storage"
+)
+
+const (
+	// queueSize bounds events waiting to be dispatched, more are dropped
+	queueSize = 64
+
+	// maxRetries is how many times a failed delivery is retried, waiting retryDelay, then twice as long and so on
+	maxRetries = 3
+	retryDelay = time.Second
+
+	deliveryTimeout = 10 * time.Second
+	minSecretLen    = 16
+)
+
+// Events lists the events webhooks may subscribe to
+var Events = []string{models.WebhookArticlePublished}
+
+var (
+	ErrWebhookNotFound = errors.New("webhook not found")
+	ErrForbidden       = errors.New("not enough rights")
+
+	ErrInvalidURL    = errors.New("invalid url, expected an absolute http or https url")
+	ErrInvalidSecret = fmt.Errorf("secret must be at least %d characters long", minSecretLen)
+	ErrInvalidEvents = fmt.Errorf("invalid events, supported: %s", models.WebhookArticlePublished)
+)
+
+type Storage interface {
+	CreateWebhook(ctx context.Context, w models.Webhook) (int64, error)
+	WebhookByID(ctx context.Context, id int64) (models.Webhook, error)
+	Webhooks(ctx context.Context, ownerID int) ([]models.Webhook, error)
+	ActiveWebhooks(ctx context.Context, event string) ([]models.Webhook, error)
+	UpdateWebhook(ctx context.Context, w models.Webhook) error
+	RemoveWebhook(ctx context.Context, id int64) error
+}
+
+// Delivery is the body POSTed to webhooks
+type Delivery struct {
+	Event     string    `json:"event"`
+	CreatedAt time.Time `json:"created_at"`
+	Data      any       `json:"data"`
+}
+
+type Service struct {
+	log     *slog.Logger
+	storage Storage
+	client  *http.Client
+	queue   chan Delivery
+	stop    chan struct{}
+	wg      sync.WaitGroup
+}
+
+func New(log *slog.Logger, storage Storage) *Service {
+	return &Service{
+		log:     log,
+		storage: storage,
+		client:  &http.Client{Timeout: deliveryTimeout},
+		queue:   make(chan Delivery, queueSize),
+		stop:    make(chan struct{}),
+	}
+}
+
+// Start runs the dispatcher that sends queued events to webhooks
+func (s *Service) Start() {
+	s.wg.Add(1)
+	go s.dispatch()
+}
+
+// Stop sends already queued events, cancelling pending retries,
+// and waits for deliveries in flight or for ctx to expire
+func (s *Service) Stop(ctx context.Context) error {
+	const op = "service.webhook.Stop"
+
+	close(s.stop)
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("%s: %w", op, ctx.Err())
+	}
+}
+
+// Dispatch queues the event for the webhooks subscribed to it without waiting for delivery.
+// Failed deliveries must not fail the operation that caused them, so errors are only logged
+func (s *Service) Dispatch(event string, data any) {
+	const op = "service.webhook.Dispatch"
+
+	d := Delivery{
+		Event:     event,
+		CreatedAt: time.Now().UTC(),
+		Data:      data,
+	}
+
+	select {
+	case s.queue <- d:
+	default:
+		s.log.Warn("webhook queue is full, dropping event", slog.String("op", op), slog.String("event", event))
+	}
+}
+
+func (s *Service) dispatch() {
+	defer s.wg.Done()
+
+	for {
+		select {
+		case d := <-s.queue:
+			s.fanOut(d)
+		case <-s.stop:
+			// Send what is already queued
+			for {
+				select {
+				case d := <-s.queue:
+					s.fanOut(d)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// fanOut delivers the event to every subscribed webhook concurrently
+func (s *Service) fanOut(d Delivery) {
+	const op = "service.webhook.fanOut"
+
+	log := s.log.With(slog.String("op", op), slog.String("event", d.Event))
+
+	body, err := json.Marshal(d)
+	if err != nil {
+		log.Error("failed to encode delivery", sl.Error(err))
+		return
+	}
+
+	// Send to storage layer
+	webhooks, err := s.storage.ActiveWebhooks(context.Background(), d.Event)
+	if err != nil {
+		log.Error("failed to get webhooks", sl.Error(err))
+		return
+	}
+
+	for _, w := range webhooks {
+		s.wg.Add(1)
+		go func(w models.Webhook) {
+			defer s.wg.Done()
+			s.deliver(w, d.Event, body)
+		}(w)
+	}
+}
+
+func (s *Service) deliver(w models.Webhook, event string, body []byte) {
+	const op = "service.webhook.deliver"
+
+	log := s.log.With(slog.String("op", op), slog.Int64("webhook_id", w.ID), slog.String("event", event))
+
+	delay := retryDelay
+	for attempt := 0; ; attempt++ {
+		retry, err := s.post(w, event, body)
+		if err == nil {
+			return
+		}
+		if !retry || attempt == maxRetries {
+			log.Warn("failed to deliver webhook", slog.Int("attempts", attempt+1), sl.Error(err))
+			return
+		}
+
+		select {
+		case <-time.After(delay):
+			delay *= 2
+		case <-s.stop:
+			log.Warn("webhook retry cancelled on shutdown", sl.Error(err))
+			return
+		}
+	}
+}
+
+// post sends the body once and reports whether a failure is worth retrying:
+// network errors and 5xx are, other statuses mean the receiver rejected it
+func (s *Service) post(w models.Webhook, event string, body []byte) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Webhook-Event", event)
+	req.Header.Set("X-Signature", Sign(w.Secret, body))
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	res.Body.Close()
+
+	switch {
+	case res.StatusCode >= 500:
+		return true, fmt.Errorf("unexpected status %d", res.StatusCode)
+	case res.StatusCode >= 300:
+		return false, fmt.Errorf("unexpected status %d", res.StatusCode)
+	}
+
+	return false, nil
+}
+
+// Sign returns the X-Signature header of a delivery: "sha256=" and the hex encoded HMAC-SHA256 of the body
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// List returns the owner's webhooks
+func (s *Service) List(ctx context.Context, ownerID int) ([]models.Webhook, error) {
+	const op = "service.webhook.List"
+
+	log := s.log.With(slog.String("op", op))
+
+	// Send to storage layer
+	webhooks, err := s.storage.Webhooks(ctx, ownerID)
+	if err != nil {
+		log.Error("failed to get webhooks", sl.Error(err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return webhooks, nil
+}
+
+// Create registers an active webhook of w.OwnerID and returns it
+func (s *Service) Create(ctx context.Context, w models.Webhook) (models.Webhook, error) {
+	const op = "service.webhook.Create"
+
+	log := s.log.With(slog.String("op", op))
+
+	w.Active = true
+	w.CreatedAt = time.Now().UTC()
+
+	if err := validate(w); err != nil {
+		return models.Webhook{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// Send to storage layer
+	id, err := s.storage.CreateWebhook(ctx, w)
+	if err != nil {
+		log.Error("failed to create webhook", sl.Error(err))
+		return models.Webhook{}, fmt.Errorf("%s: %w", op, err)
+	}
+	w.ID = id
+
+	return w, nil
+}
+
+// Update changes the webhook's url, secret and events when they are not empty,
+// and its active flag when active is not nil. Only the owner may update it
+func (s *Service) Update(ctx context.Context, ownerID int, id int64, rawURL, secret string, events []string, active *bool) (models.Webhook, error) {
+	const op = "service.webhook.Update"
+
+	log := s.log.With(slog.String("op", op))
+
+	w, err := s.owned(ctx, ownerID, id)
+	if err != nil {
+		return models.Webhook{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if rawURL != "" {
+		w.URL = rawURL
+	}
+	if secret != "" {
+		w.Secret = secret
+	}
+	if events != nil {
+		w.Events = events
+	}
+	if active != nil {
+		w.Active = *active
+	}
+
+	if err := validate(w); err != nil {
+		return models.Webhook{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// Send to storage layer
+	err = s.storage.UpdateWebhook(ctx, w)
+	if err != nil {
+		if errors.Is(err, storage.ErrWebhookNotFound) {
+			return models.Webhook{}, fmt.Errorf("%s: %w", op, ErrWebhookNotFound)
+		}
+		log.Error("failed to update webhook", sl.Error(err))
+		return models.Webhook{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return w, nil
+}
+
+// Remove deletes the webhook, only the owner may remove it
+func (s *Service) Remove(ctx context.Context, ownerID int, id int64) error {
+	const op = "service.webhook.Remove"
+
+	log := s.log.With(slog.String("op", op))
+
+	if _, err := s.owned(ctx, ownerID, id); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	// Send to storage layer
+	err := s.storage.RemoveWebhook(ctx, id)
+	if err != nil {
+		if errors.Is(err, storage.ErrWebhookNotFound) {
+			return fmt.Errorf("%s: %w", op, ErrWebhookNotFound)
+		}
+		log.Error("failed to remove webhook", sl.Error(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// owned returns the webhook if it belongs to ownerID
+func (s *Service) owned(ctx context.Context, ownerID int, id int64) (models.Webhook, error) {
+	const op = "service.webhook.owned"
+
+	// Send to storage layer
+	w, err := s.storage.WebhookByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, storage.ErrWebhookNotFound) {
+			return models.Webhook{}, ErrWebhookNotFound
+		}
+		s.log.Error("failed to get webhook", slog.String("op", op), sl.Error(err))
+		return models.Webhook{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if w.OwnerID != ownerID {
+		return models.Webhook{}, ErrForbidden
+	}
+
+	return w, nil
+}
+
+func validate(w models.Webhook) error {
+	u, err := url.Parse(w.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ErrInvalidURL
+	}
+	if len(w.Secret) < minSecretLen {
+		return ErrInvalidSecret
+	}
+	if len(w.Events) == 0 {
+		return ErrInvalidEvents
+	}
+	for _, e := range w.Events {
+		if !slices.Contains(Events, e) {
+			return ErrInvalidEvents
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,386 @@
+package sqlite_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"blog-api/internal/domain/models"
+	"blog-api/internal/storage"
+)
+
+func TestCreateArticle(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	authorID := mustRegister(t, s, "author")
+
+	tests := []struct {
+		name    string
+		title   string
+		wantErr error
+	}{
+		{name: "success", title: "First"},
+		{name: "duplicate title", title: "First", wantErr: storage.ErrArticleExists},
+		{name: "another title", title: "Second"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, err := s.CreateArticle(ctx, authorID, tt.title, "content", "en", "", models.ArticlePublished, &now)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CreateArticle(%q) error = %v, want %v", tt.title, err, tt.wantErr)
+			}
+			if tt.wantErr == nil && id <= 0 {
+				t.Errorf("CreateArticle(%q) id = %d, want a positive one", tt.title, id)
+			}
+		})
+	}
+}
+
+func TestGetArticleByID(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+
+	authorID := mustRegister(t, s, "author")
+	id := mustCreateArticle(t, s, authorID, "First", models.ArticlePublished)
+
+	tests := []struct {
+		name    string
+		id      int
+		wantErr error
+	}{
+		{name: "found", id: id},
+		{name: "not found", id: id + 1, wantErr: storage.ErrArticleNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			art, err := s.GetArticleByID(ctx, tt.id, false, 0)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("GetArticleByID(%d) error = %v, want %v", tt.id, err, tt.wantErr)
+			}
+			if tt.wantErr == nil && (art.ID != id || art.Title != "First" || art.AuthorID != authorID) {
+				t.Errorf("GetArticleByID(%d) = %+v, want the created article", tt.id, art)
+			}
+		})
+	}
+}
+
+func TestGetAllArticles(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+
+	authorID := mustRegister(t, s, "author")
+	readerID := mustRegister(t, s, "reader")
+	first := mustCreateArticle(t, s, authorID, "First", models.ArticlePublished)
+	draft := mustCreateArticle(t, s, authorID, "Draft", models.ArticleDraft)
+	third := mustCreateArticle(t, s, authorID, "Third", models.ArticlePublished)
+
+	tests := []struct {
+		name        string
+		visibleOnly bool
+		viewerID    int
+		limit       int
+		offset      int
+		want        []int
+	}{
+		{name: "all", limit: 10, want: []int{first, draft, third}},
+		{name: "published to a reader", visibleOnly: true, viewerID: readerID, limit: 10, want: []int{first, third}},
+		{name: "own drafts to the author", visibleOnly: true, viewerID: authorID, limit: 10, want: []int{first, draft, third}},
+		{name: "limit", limit: 2, want: []int{first, draft}},
+		{name: "offset", limit: 10, offset: 2, want: []int{third}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			arts, err := s.GetAllArticles(ctx, "", "", models.PopularityFilter{}, tt.visibleOnly, tt.viewerID, tt.limit, tt.offset)
+			if err != nil {
+				t.Fatalf("GetAllArticles() error = %v", err)
+			}
+
+			if got := articleIDs(arts); !slices.Equal(got, tt.want) {
+				t.Errorf("GetAllArticles() ids = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdateArticle(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+
+	authorID := mustRegister(t, s, "author")
+	id := mustCreateArticle(t, s, authorID, "First", models.ArticlePublished)
+	mustCreateArticle(t, s, authorID, "Other", models.ArticlePublished)
+
+	tests := []struct {
+		name        string
+		id          int
+		title       string
+		content     string
+		version     int
+		wantVersion int
+		wantErr     error
+	}{
+		{name: "title", id: id, title: "Renamed", version: 1, wantVersion: 2},
+		{name: "content", id: id, content: "new content", version: 2, wantVersion: 3},
+		{name: "without version", id: id, title: "Again", wantVersion: 4},
+		{name: "stale version", id: id, title: "Stale", version: 1, wantErr: storage.ErrVersionConflict},
+		{name: "taken title", id: id, title: "Other", wantErr: storage.ErrArticleExists},
+		{name: "not found", id: 999, title: "Missing", wantErr: storage.ErrArticleNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			version, err := s.UpdateArticle(ctx, tt.id, tt.title, tt.content, "", "", tt.version)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("UpdateArticle() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && version != tt.wantVersion {
+				t.Errorf("UpdateArticle() version = %d, want %d", version, tt.wantVersion)
+			}
+		})
+	}
+
+	art, err := s.GetArticleByID(ctx, id, false, 0)
+	if err != nil {
+		t.Fatalf("GetArticleByID() error = %v", err)
+	}
+	if art.Title != "Again" || art.Content != "new content" {
+		t.Errorf("title, content = %q, %q, want %q, %q", art.Title, art.Content, "Again", "new content")
+	}
+}
+
+func TestRemoveArticle(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+
+	authorID := mustRegister(t, s, "author")
+	id := mustCreateArticle(t, s, authorID, "First", models.ArticlePublished)
+
+	tests := []struct {
+		name    string
+		id      int
+		wantErr error
+	}{
+		{name: "success", id: id},
+		{name: "already removed", id: id, wantErr: storage.ErrArticleNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := s.RemoveArticle(ctx, tt.id); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("RemoveArticle(%d) error = %v, want %v", tt.id, err, tt.wantErr)
+			}
+			if _, err := s.GetArticleByID(ctx, tt.id, false, 0); !errors.Is(err, storage.ErrArticleNotFound) {
+				t.Errorf("GetArticleByID() after remove error = %v, want %v", err, storage.ErrArticleNotFound)
+			}
+		})
+	}
+}
+
+func TestArticleReactions(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+
+	authorID := mustRegister(t, s, "author")
+	readers := []int{mustRegister(t, s, "first"), mustRegister(t, s, "second"), mustRegister(t, s, "third")}
+	quiet := mustCreateArticle(t, s, authorID, "Quiet", models.ArticlePublished)
+	liked := mustCreateArticle(t, s, authorID, "Liked", models.ArticlePublished)
+
+	for _, id := range readers[:2] {
+		if err := s.LikeArticle(ctx, id, liked); err != nil {
+			t.Fatalf("LikeArticle() error = %v", err)
+		}
+	}
+	if err := s.DislikeArticle(ctx, readers[2], liked); err != nil {
+		t.Fatalf("DislikeArticle() error = %v", err)
+	}
+
+	tests := []struct {
+		name                   string
+		id                     int
+		likes, dislikes, score int
+	}{
+		{name: "without reactions", id: quiet},
+		{name: "with reactions", id: liked, likes: 2, dislikes: 1, score: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			art, err := s.GetArticleByID(ctx, tt.id, false, 0)
+			if err != nil {
+				t.Fatalf("GetArticleByID() error = %v", err)
+			}
+			if art.Likes != tt.likes || art.Dislikes != tt.dislikes || art.Score != tt.score {
+				t.Errorf("likes, dislikes, score = %d, %d, %d, want %d, %d, %d", art.Likes, art.Dislikes, art.Score, tt.likes, tt.dislikes, tt.score)
+			}
+		})
+	}
+
+	arts, err := s.GetAllArticles(ctx, "", models.ArticleSortLikes, models.PopularityFilter{MinLikes: 1}, false, 0, 10, 0)
+	if err != nil {
+		t.Fatalf("GetAllArticles() error = %v", err)
+	}
+	if len(arts) != 1 || arts[0].ID != liked {
+		t.Errorf("GetAllArticles() with a like = %d articles, want the liked one", len(arts))
+	}
+}
+
+func TestDraftVisibility(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+
+	authorID := mustRegister(t, s, "author")
+	readerID := mustRegister(t, s, "reader")
+	published := mustCreateArticle(t, s, authorID, "Published", models.ArticlePublished)
+	draft := mustCreateArticle(t, s, authorID, "Draft", models.ArticleDraft)
+
+	from, to := time.Now().UTC().Add(-time.Hour), time.Now().UTC().Add(time.Hour)
+
+	// Each path returns the ids of the author's articles it finds
+	paths := []struct {
+		name string
+		get  func(visibleOnly bool, viewerID int) ([]int, error)
+	}{
+		{
+			name: "GetArticleByID",
+			get: func(visibleOnly bool, viewerID int) ([]int, error) {
+				var ids []int
+				for _, id := range []int{published, draft} {
+					art, err := s.GetArticleByID(ctx, id, visibleOnly, viewerID)
+					if errors.Is(err, storage.ErrArticleNotFound) {
+						continue
+					}
+					if err != nil {
+						return nil, err
+					}
+					ids = append(ids, art.ID)
+				}
+				return ids, nil
+			},
+		},
+		{
+			name: "GetArticlesByIDs",
+			get: func(visibleOnly bool, viewerID int) ([]int, error) {
+				arts, err := s.GetArticlesByIDs(ctx, []int{published, draft}, visibleOnly, viewerID)
+				return articleIDs(arts), err
+			},
+		},
+		{
+			name: "GetArticlesByAuthorID",
+			get: func(visibleOnly bool, viewerID int) ([]int, error) {
+				arts, err := s.GetArticlesByAuthorID(ctx, authorID, visibleOnly, viewerID, 10, 0)
+				return articleIDs(arts), err
+			},
+		},
+		{
+			name: "GetArticlesInRange",
+			get: func(visibleOnly bool, viewerID int) ([]int, error) {
+				arts, err := s.GetArticlesInRange(ctx, from, to, "", "", visibleOnly, viewerID, 10, 0)
+				return articleIDs(arts), err
+			},
+		},
+	}
+
+	viewers := []struct {
+		name        string
+		visibleOnly bool
+		viewerID    int
+		want        []int
+	}{
+		{name: "anonymous", visibleOnly: true, want: []int{published}},
+		{name: "another user", visibleOnly: true, viewerID: readerID, want: []int{published}},
+		{name: "author", visibleOnly: true, viewerID: authorID, want: []int{published, draft}},
+		{name: "unchecked", want: []int{published, draft}},
+	}
+
+	for _, path := range paths {
+		for _, v := range viewers {
+			t.Run(path.name+"/"+v.name, func(t *testing.T) {
+				got, err := path.get(v.visibleOnly, v.viewerID)
+				if err != nil {
+					t.Fatalf("error = %v", err)
+				}
+
+				slices.Sort(got)
+				if !slices.Equal(got, v.want) {
+					t.Errorf("ids = %v, want %v", got, v.want)
+				}
+			})
+		}
+	}
+}
+
+func articleIDs(arts []models.Article) []int {
+	ids := make([]int, 0, len(arts))
+	for _, art := range arts {
+		ids = append(ids, art.ID)
+	}
+	return ids
+}
+
+func TestRemoveArticlesBulk(t *testing.T) {
+	tests := []struct {
+		name        string
+		byAnyAuthor bool
+	}{
+		{name: "own articles"},
+		{name: "any author, as for an admin", byAnyAuthor: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestStorage(t)
+			ctx := context.Background()
+
+			authorID := mustRegister(t, s, "author")
+			otherID := mustRegister(t, s, "other")
+			own := mustCreateArticle(t, s, authorID, "Own", models.ArticlePublished)
+			foreign := mustCreateArticle(t, s, otherID, "Foreign", models.ArticlePublished)
+
+			requester, wantRemoved, wantForeign := authorID, []int{own}, []int{foreign}
+			if tt.byAnyAuthor {
+				requester, wantRemoved, wantForeign = 0, []int{own, foreign}, nil
+			}
+
+			removed, kept, err := s.RemoveArticlesBulk(ctx, requester, []int{own, foreign, 999})
+			if err != nil {
+				t.Fatalf("RemoveArticlesBulk() error = %v", err)
+			}
+
+			slices.Sort(removed)
+			if !slices.Equal(removed, wantRemoved) || !slices.Equal(kept, wantForeign) {
+				t.Errorf("RemoveArticlesBulk() = %v, %v, want %v, %v", removed, kept, wantRemoved, wantForeign)
+			}
+		})
+	}
+}
+
+func TestLastArticleUpdate(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+
+	last, err := s.LastArticleUpdate(ctx)
+	if err != nil {
+		t.Fatalf("LastArticleUpdate() error = %v", err)
+	}
+	if !last.IsZero() {
+		t.Errorf("LastArticleUpdate() without articles = %v, want the zero time", last)
+	}
+
+	authorID := mustRegister(t, s, "author")
+	id := mustCreateArticle(t, s, authorID, "Scheduled", models.ArticlePublished)
+	future := time.Now().UTC().Add(48 * time.Hour)
+	if _, err := s.DB().Exec(`UPDATE articles SET updated_at = ?, publish_date = ? WHERE id = ?`, future, future, id); err != nil {
+		t.Fatalf("failed to move the article into the future: %v", err)
+	}
+
+	last, err = s.LastArticleUpdate(ctx)
+	if err != nil {
+		t.Fatalf("LastArticleUpdate() error = %v", err)
+	}
+	if now := time.Now().UTC(); last.After(now) || now.Sub(last) > time.Minute {
+		t.Errorf("LastArticleUpdate() with a future date = %v, want about now %v", last, now)
+	}
+	if last.Location() != time.UTC || last.Nanosecond() != 0 {
+		t.Errorf("LastArticleUpdate() = %v, want whole seconds in UTC", last)
+	}
+}
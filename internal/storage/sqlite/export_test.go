@@ -0,0 +1,8 @@
+package sqlite
+
+import "database/sql"
+
+// DB lets tests check rows no storage method reads
+func (s *Storage) DB() *sql.DB {
+	return s.db
+}
@@ -0,0 +1,112 @@
+package sqlite_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+
+	"blog-api/internal/domain/models"
+	"blog-api/internal/storage/sqlite"
+)
+
+func TestArticleOfMissingAuthor(t *testing.T) {
+	s := newTestStorage(t)
+	now := time.Now().UTC()
+
+	_, err := s.DB().Exec(`
+		INSERT INTO articles (title, content, publish_date, created_at, updated_at, status, author_id)
+		VALUES ('Orphan', 'content', ?, ?, ?, ?, 99999)`, now, now, now, models.ArticlePublished)
+
+	var sqliteErr sqlite3.Error
+	if !errors.As(err, &sqliteErr) || sqliteErr.ExtendedCode != sqlite3.ErrConstraintForeignKey {
+		t.Errorf("insert with author_id 99999 error = %v, want a foreign key constraint error", err)
+	}
+	if n := countRows(t, s, "articles", "author_id = 99999"); n != 0 {
+		t.Errorf("articles of the missing author = %d, want 0", n)
+	}
+}
+
+func TestRemoveUserCascades(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	userID := mustRegister(t, s, "leaving")
+	keptID := mustRegister(t, s, "staying")
+
+	for name, id := range map[string]int{"leaving": userID, "staying": keptID} {
+		articleID := mustCreateArticle(t, s, id, "Article of "+name, models.ArticlePublished)
+		if err := s.LikeArticle(ctx, id, articleID); err != nil {
+			t.Fatalf("LikeArticle() error = %v", err)
+		}
+		if _, err := s.CreateSession(ctx, models.Session{UserID: int64(id), CreatedAt: now, LastSeen: now}); err != nil {
+			t.Fatalf("CreateSession() error = %v", err)
+		}
+		if err := s.AddLoginEvent(ctx, models.LoginEvent{UserID: int64(id), LoggedInAt: now, Success: true}); err != nil {
+			t.Fatalf("AddLoginEvent() error = %v", err)
+		}
+		if err := s.AddNotification(ctx, models.Notification{UserID: id, Type: "test", Payload: []byte(`{}`), CreatedAt: now}); err != nil {
+			t.Fatalf("AddNotification() error = %v", err)
+		}
+		if _, err := s.CreateCollection(ctx, models.Collection{OwnerID: id, Title: "List", CreatedAt: now}); err != nil {
+			t.Fatalf("CreateCollection() error = %v", err)
+		}
+	}
+
+	if err := s.RemoveUser(ctx, userID); err != nil {
+		t.Fatalf("RemoveUser() error = %v", err)
+	}
+
+	tables := []struct {
+		table  string
+		column string
+	}{
+		{table: "articles", column: "author_id"},
+		{table: "reactions", column: "user_id"},
+		{table: "sessions", column: "user_id"},
+		{table: "login_history", column: "user_id"},
+		{table: "notifications", column: "user_id"},
+		{table: "collections", column: "owner_id"},
+	}
+	for _, tt := range tables {
+		t.Run(tt.table, func(t *testing.T) {
+			if n := countRows(t, s, tt.table, tt.column+" = ?", userID); n != 0 {
+				t.Errorf("rows of the removed user = %d, want 0", n)
+			}
+			if n := countRows(t, s, tt.table, tt.column+" = ?", keptID); n != 1 {
+				t.Errorf("rows of the other user = %d, want 1", n)
+			}
+		})
+	}
+}
+
+func TestForeignKeysOnEveryConnection(t *testing.T) {
+	s, err := sqlite.New(filepath.Join(t.TempDir(), "storage.db"))
+	if err != nil {
+		t.Fatalf("failed to open storage: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+
+	ctx := context.Background()
+
+	// Held at once, so the pool opens new ones next to the one migrations ran on
+	for i := 0; i < 3; i++ {
+		conn, err := s.DB().Conn(ctx)
+		if err != nil {
+			t.Fatalf("failed to get connection %d: %v", i, err)
+		}
+		defer conn.Close()
+
+		var on int
+		if err := conn.QueryRowContext(ctx, `PRAGMA foreign_keys`).Scan(&on); err != nil {
+			t.Fatalf("PRAGMA foreign_keys on connection %d error = %v", i, err)
+		}
+		if on != 1 {
+			t.Errorf("foreign_keys on connection %d = %d, want 1", i, on)
+		}
+	}
+}
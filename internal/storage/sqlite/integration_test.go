@@ -0,0 +1,169 @@
+package sqlite_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"blog-api/internal/domain/models"
+	"blog-api/internal/storage"
+	"blog-api/internal/storage/sqlite"
+)
+
+// countRows counts the rows of the table matching the where clause
+func countRows(t *testing.T, s *sqlite.Storage, table, where string, args ...any) int {
+	t.Helper()
+
+	var n int
+	if err := s.DB().QueryRow(`SELECT COUNT(*) FROM `+table+` WHERE `+where, args...).Scan(&n); err != nil {
+		t.Fatalf("failed to count %s: %v", table, err)
+	}
+
+	return n
+}
+
+func TestErrorMapping(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	if _, err := s.Register(ctx, "alice", "alice@example.com", []byte("hash"), now); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	bobID := mustRegister(t, s, "bob")
+	articleID := mustCreateArticle(t, s, bobID, "Taken", models.ArticlePublished)
+	otherID := mustCreateArticle(t, s, bobID, "Other", models.ArticlePublished)
+
+	tests := []struct {
+		name string
+		call func() error
+		want error
+	}{
+		{
+			name: "duplicate name",
+			call: func() error { _, err := s.Register(ctx, "alice", "", []byte("hash"), now); return err },
+			want: storage.ErrUserExists,
+		},
+		{
+			name: "duplicate name in other case",
+			call: func() error { _, err := s.Register(ctx, "ALICE", "", []byte("hash"), now); return err },
+			want: storage.ErrUserExists,
+		},
+		{
+			name: "duplicate email",
+			call: func() error { _, err := s.Register(ctx, "carol", "Alice@Example.com", []byte("hash"), now); return err },
+			want: storage.ErrEmailTaken,
+		},
+		{
+			name: "rename to a taken name",
+			call: func() error { return s.UpdateUserName(ctx, bobID, "Alice") },
+			want: storage.ErrUserNameTaken,
+		},
+		{
+			name: "missing user id",
+			call: func() error { _, err := s.UserByID(ctx, 99999); return err },
+			want: storage.ErrUserNotFound,
+		},
+		{
+			name: "duplicate title",
+			call: func() error {
+				_, err := s.CreateArticle(ctx, bobID, "Taken", "content", "en", "", models.ArticlePublished, &now)
+				return err
+			},
+			want: storage.ErrArticleExists,
+		},
+		{
+			name: "retitle to a taken title",
+			call: func() error { _, err := s.UpdateArticle(ctx, otherID, "Taken", "", "", "", 0); return err },
+			want: storage.ErrArticleExists,
+		},
+		{
+			name: "stale version",
+			call: func() error { _, err := s.UpdateArticle(ctx, articleID, "Fresh", "", "", "", 7); return err },
+			want: storage.ErrVersionConflict,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.call(); !errors.Is(err, tt.want) {
+				t.Errorf("error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestTimesRoundTripInUTC(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+
+	tokyo := time.FixedZone("JST", 9*60*60)
+	at := time.Date(2024, time.March, 1, 8, 30, 15, 0, tokyo)
+
+	id, err := s.Register(ctx, "alice", "", []byte("hash"), at)
+	if err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	user, err := s.UserByID(ctx, int(id))
+	if err != nil {
+		t.Fatalf("UserByID() error = %v", err)
+	}
+	if user.RegistrationDate == nil || !user.RegistrationDate.Equal(at) || user.RegistrationDate.Location() != time.UTC {
+		t.Errorf("registration date = %v, want %v in UTC", user.RegistrationDate, at.UTC())
+	}
+
+	articleID, err := s.CreateArticle(ctx, int(id), "Dated", "content", "en", "", models.ArticlePublished, &at)
+	if err != nil {
+		t.Fatalf("CreateArticle() error = %v", err)
+	}
+	art, err := s.GetArticleByID(ctx, int(articleID), false, 0)
+	if err != nil {
+		t.Fatalf("GetArticleByID() error = %v", err)
+	}
+	if art.PublishDate == nil || !art.PublishDate.Equal(at) || art.PublishDate.Location() != time.UTC {
+		t.Errorf("publish date = %v, want %v in UTC", art.PublishDate, at.UTC())
+	}
+}
+
+func TestRemoveArticleCascades(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	authorID := mustRegister(t, s, "author")
+	readerID := mustRegister(t, s, "reader")
+	articleID := mustCreateArticle(t, s, authorID, "Doomed", models.ArticlePublished)
+	keptID := mustCreateArticle(t, s, authorID, "Kept", models.ArticlePublished)
+
+	collectionID, err := s.CreateCollection(ctx, models.Collection{OwnerID: readerID, Title: "Reading list", CreatedAt: now})
+	if err != nil {
+		t.Fatalf("CreateCollection() error = %v", err)
+	}
+	for _, id := range []int{articleID, keptID} {
+		if err := s.AddArticleView(ctx, id, "fingerprint", now, now.Add(-24*time.Hour)); err != nil {
+			t.Fatalf("AddArticleView() error = %v", err)
+		}
+		if err := s.LikeArticle(ctx, readerID, id); err != nil {
+			t.Fatalf("LikeArticle() error = %v", err)
+		}
+		if err := s.AddCollectionArticle(ctx, collectionID, id, now); err != nil {
+			t.Fatalf("AddCollectionArticle() error = %v", err)
+		}
+		if _, err := s.AddAttachment(ctx, models.Attachment{ArticleID: id, SHA256: "sum", MimeType: "image/png", Size: 1, CreatedAt: now}); err != nil {
+			t.Fatalf("AddAttachment() error = %v", err)
+		}
+	}
+
+	if err := s.RemoveArticle(ctx, articleID); err != nil {
+		t.Fatalf("RemoveArticle() error = %v", err)
+	}
+
+	for _, table := range []string{"article_views", "reactions", "collection_articles", "attachments"} {
+		if n := countRows(t, s, table, "article_id = ?", articleID); n != 0 {
+			t.Errorf("%s rows of the removed article = %d, want 0", table, n)
+		}
+		if n := countRows(t, s, table, "article_id = ?", keptID); n != 1 {
+			t.Errorf("%s rows of the kept article = %d, want 1", table, n)
+		}
+	}
+}
@@ -0,0 +1,398 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// migrations are applied in order, PRAGMA user_version holds the number of already applied ones
+var migrations = []string{
+	// Initial schema
+	`
+	CREATE TABLE IF NOT EXISTS users (
+		id INTEGER PRIMARY KEY,
+		name TEXT UNIQUE NOT NULL,
+		pass_hash BLOB NOT NULL,
+		registration_date DATETIME NOT NULL,
+		status TEXT DEFAULT ''
+	);
+
+	CREATE TABLE IF NOT EXISTS articles (
+		id INTEGER PRIMARY KEY,
+		title TEXT NOT NULL,
+		content TEXT NOT NULL,
+		publish_date DATETIME NOT NULL,
+		author_id INTEGER REFERENCES users(id)
+	);
+
+	CREATE TABLE IF NOT EXISTS users_articles (
+		article_d INTEGER REFERENCES articles(id)
+	);
+	`,
+
+	// Remove user's articles together with the user.
+	// SQLite can't alter a foreign key, so the table is re-created
+	`
+	CREATE TABLE articles_new (
+		id INTEGER PRIMARY KEY,
+		title TEXT NOT NULL,
+		content TEXT NOT NULL,
+		publish_date DATETIME NOT NULL,
+		author_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE
+	);
+
+	INSERT INTO articles_new (id, title, content, publish_date, author_id)
+	SELECT id, title, content, publish_date, author_id FROM articles
+	WHERE author_id IN (SELECT id FROM users);
+
+	DROP TABLE articles;
+
+	ALTER TABLE articles_new RENAME TO articles;
+	`,
+
+	// Article status, drafts have no publish date
+	`
+	CREATE TABLE articles_new (
+		id INTEGER PRIMARY KEY,
+		title TEXT NOT NULL,
+		content TEXT NOT NULL,
+		publish_date DATETIME,
+		status TEXT NOT NULL DEFAULT 'published' CHECK (status IN ('draft', 'published')),
+		author_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE
+	);
+
+	INSERT INTO articles_new (id, title, content, publish_date, author_id)
+	SELECT id, title, content, publish_date, author_id FROM articles;
+
+	DROP TABLE articles;
+
+	ALTER TABLE articles_new RENAME TO articles;
+	`,
+
+	// Article views, one row per viewer per day
+	`
+	CREATE TABLE article_views (
+		article_id INTEGER NOT NULL REFERENCES articles(id) ON DELETE CASCADE,
+		viewer_fingerprint TEXT NOT NULL,
+		viewed_at DATETIME NOT NULL
+	);
+
+	CREATE INDEX article_views_article_viewer ON article_views (article_id, viewer_fingerprint, viewed_at);
+	`,
+
+	// User roles
+	`
+	ALTER TABLE users ADD COLUMN role TEXT NOT NULL DEFAULT 'user' CHECK (role IN ('user', 'admin'));
+	`,
+
+	// Article reactions, a user either likes or dislikes an article
+	`
+	CREATE TABLE reactions (
+		article_id INTEGER NOT NULL REFERENCES articles(id) ON DELETE CASCADE,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		reaction_type TEXT NOT NULL CHECK (reaction_type IN ('like', 'dislike')),
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (article_id, user_id)
+	);
+	`,
+
+	// User notifications
+	`
+	CREATE TABLE notifications (
+		id INTEGER PRIMARY KEY,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		type TEXT NOT NULL,
+		payload TEXT NOT NULL DEFAULT '{}',
+		is_read BOOLEAN NOT NULL DEFAULT false,
+		created_at DATETIME NOT NULL
+	);
+
+	CREATE INDEX notifications_user_read ON notifications (user_id, is_read);
+	`,
+
+	// Keep when a notification was read instead of a flag
+	`
+	CREATE TABLE notifications_new (
+		id INTEGER PRIMARY KEY,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		type TEXT NOT NULL,
+		payload TEXT NOT NULL DEFAULT '{}',
+		read_at DATETIME,
+		created_at DATETIME NOT NULL
+	);
+
+	INSERT INTO notifications_new (id, user_id, type, payload, read_at, created_at)
+	SELECT id, user_id, type, payload, CASE WHEN is_read THEN created_at END, created_at FROM notifications;
+
+	DROP TABLE notifications;
+	ALTER TABLE notifications_new RENAME TO notifications;
+
+	CREATE INDEX notifications_user_read ON notifications (user_id, read_at);
+	`,
+
+	// Login history, both successful and failed attempts
+	`
+	CREATE TABLE login_history (
+		id INTEGER PRIMARY KEY,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		ip TEXT NOT NULL,
+		user_agent TEXT NOT NULL,
+		logged_in_at DATETIME NOT NULL,
+		success BOOLEAN NOT NULL
+	);
+
+	CREATE INDEX login_history_user ON login_history (user_id, logged_in_at);
+	`,
+
+	// Login sessions, tokens carry the session id and stop working once it's revoked
+	`
+	CREATE TABLE sessions (
+		id INTEGER PRIMARY KEY,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		user_agent TEXT NOT NULL,
+		ip TEXT NOT NULL,
+		created_at DATETIME NOT NULL,
+		last_seen DATETIME NOT NULL,
+		revoked BOOLEAN NOT NULL DEFAULT false
+	);
+
+	CREATE INDEX sessions_user ON sessions (user_id, revoked);
+	`,
+
+	// Titles are unique per author, earlier duplicates get their id appended
+	`
+	UPDATE articles SET title = title || ' (' || id || ')'
+	WHERE id NOT IN (SELECT MIN(id) FROM articles GROUP BY author_id, title);
+
+	CREATE UNIQUE INDEX articles_author_title ON articles (author_id, title);
+	`,
+
+	// Authors may pin a few articles to the top of their list
+	`
+	ALTER TABLE articles ADD COLUMN is_pinned BOOLEAN NOT NULL DEFAULT 0;
+	`,
+
+	// Track when users and articles were last changed, articles also keep their creation time
+	// apart from publish_date. Existing rows take the dates they already have
+	`
+	ALTER TABLE users ADD COLUMN updated_at DATETIME;
+	UPDATE users SET updated_at = registration_date;
+
+	ALTER TABLE articles ADD COLUMN created_at DATETIME;
+	ALTER TABLE articles ADD COLUMN updated_at DATETIME;
+	UPDATE articles SET created_at = COALESCE(publish_date, CURRENT_TIMESTAMP), updated_at = COALESCE(publish_date, CURRENT_TIMESTAMP);
+
+	CREATE INDEX articles_updated_at ON articles (updated_at);
+	`,
+
+	// Article language as a BCP-47 tag
+	`
+	ALTER TABLE articles ADD COLUMN language TEXT NOT NULL DEFAULT 'en';
+
+	CREATE INDEX articles_language ON articles (language);
+	`,
+
+	// User names are unique regardless of case, earlier duplicates get their id appended
+	`
+	UPDATE users SET name = name || '_' || id
+	WHERE id NOT IN (SELECT MIN(id) FROM users GROUP BY name COLLATE NOCASE);
+
+	CREATE UNIQUE INDEX users_name_nocase ON users (name COLLATE NOCASE);
+	`,
+
+	// Article version for optimistic locking, bumped on every edit
+	`
+	ALTER TABLE articles ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+	`,
+
+	// Optional user email, users may log in with it instead of the name
+	`
+	ALTER TABLE users ADD COLUMN email TEXT;
+
+	CREATE UNIQUE INDEX users_email ON users (email COLLATE NOCASE);
+	`,
+
+	// Hourly view and like counts per article, rolled up from article_views and reactions for trending
+	`
+	CREATE TABLE article_stats_hourly (
+		article_id INTEGER NOT NULL REFERENCES articles(id) ON DELETE CASCADE,
+		hour DATETIME NOT NULL,
+		views INTEGER NOT NULL DEFAULT 0,
+		likes INTEGER NOT NULL DEFAULT 0,
+		PRIMARY KEY (article_id, hour)
+	);
+
+	CREATE INDEX article_stats_hourly_hour ON article_stats_hourly (hour);
+	`,
+
+	// Removed articles leave no updated_at behind, remember when the last one was removed
+	// so that the list's Last-Modified moves forward on deletes as well
+	`
+	CREATE TABLE articles_meta (
+		id INTEGER PRIMARY KEY CHECK (id = 1),
+		last_deleted_at DATETIME
+	);
+
+	INSERT INTO articles_meta (id) VALUES (1);
+
+	CREATE TRIGGER articles_track_delete AFTER DELETE ON articles
+	BEGIN
+		UPDATE articles_meta SET last_deleted_at = CURRENT_TIMESTAMP;
+	END;
+	`,
+
+	// Canonical URL of articles cross-posted from elsewhere
+	`
+	ALTER TABLE articles ADD COLUMN canonical_url TEXT NOT NULL DEFAULT '';
+	`,
+
+	// Timestamps written in the server's time zone are converted to UTC,
+	// so that comparing them as text works regardless of the zone they were written in
+	`
+	UPDATE users SET registration_date = strftime('%Y-%m-%d %H:%M:%f+00:00', registration_date) WHERE registration_date GLOB '*[+-][0-9][0-9]:[0-9][0-9]';
+	UPDATE users SET updated_at = strftime('%Y-%m-%d %H:%M:%f+00:00', updated_at) WHERE updated_at GLOB '*[+-][0-9][0-9]:[0-9][0-9]';
+	UPDATE articles SET publish_date = strftime('%Y-%m-%d %H:%M:%f+00:00', publish_date) WHERE publish_date GLOB '*[+-][0-9][0-9]:[0-9][0-9]';
+	UPDATE articles SET created_at = strftime('%Y-%m-%d %H:%M:%f+00:00', created_at) WHERE created_at GLOB '*[+-][0-9][0-9]:[0-9][0-9]';
+	UPDATE articles SET updated_at = strftime('%Y-%m-%d %H:%M:%f+00:00', updated_at) WHERE updated_at GLOB '*[+-][0-9][0-9]:[0-9][0-9]';
+	UPDATE article_views SET viewed_at = strftime('%Y-%m-%d %H:%M:%f+00:00', viewed_at) WHERE viewed_at GLOB '*[+-][0-9][0-9]:[0-9][0-9]';
+	UPDATE reactions SET created_at = strftime('%Y-%m-%d %H:%M:%f+00:00', created_at) WHERE created_at GLOB '*[+-][0-9][0-9]:[0-9][0-9]';
+	UPDATE notifications SET read_at = strftime('%Y-%m-%d %H:%M:%f+00:00', read_at) WHERE read_at GLOB '*[+-][0-9][0-9]:[0-9][0-9]';
+	UPDATE notifications SET created_at = strftime('%Y-%m-%d %H:%M:%f+00:00', created_at) WHERE created_at GLOB '*[+-][0-9][0-9]:[0-9][0-9]';
+	UPDATE login_history SET logged_in_at = strftime('%Y-%m-%d %H:%M:%f+00:00', logged_in_at) WHERE logged_in_at GLOB '*[+-][0-9][0-9]:[0-9][0-9]';
+	UPDATE sessions SET created_at = strftime('%Y-%m-%d %H:%M:%f+00:00', created_at) WHERE created_at GLOB '*[+-][0-9][0-9]:[0-9][0-9]';
+	UPDATE sessions SET last_seen = strftime('%Y-%m-%d %H:%M:%f+00:00', last_seen) WHERE last_seen GLOB '*[+-][0-9][0-9]:[0-9][0-9]';
+	`,
+
+	// HTTP callbacks users register for article events, events is a comma separated list
+	`
+	CREATE TABLE webhooks (
+		id INTEGER PRIMARY KEY,
+		owner_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		url TEXT NOT NULL,
+		secret TEXT NOT NULL,
+		events TEXT NOT NULL,
+		active BOOL NOT NULL DEFAULT true,
+		created_at DATETIME NOT NULL
+	);
+
+	CREATE INDEX webhooks_owner_id ON webhooks (owner_id);
+	`,
+
+	// Reading lists users curate from any authors' articles, ordered by position
+	`
+	CREATE TABLE collections (
+		id INTEGER PRIMARY KEY,
+		owner_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		title TEXT NOT NULL,
+		description TEXT NOT NULL DEFAULT '',
+		is_public BOOL NOT NULL DEFAULT 1,
+		created_at DATETIME NOT NULL
+	);
+
+	CREATE INDEX collections_owner_id ON collections (owner_id);
+
+	CREATE TABLE collection_articles (
+		collection_id INTEGER NOT NULL REFERENCES collections(id) ON DELETE CASCADE,
+		article_id INTEGER NOT NULL REFERENCES articles(id) ON DELETE CASCADE,
+		position INTEGER NOT NULL,
+		added_at DATETIME NOT NULL,
+		PRIMARY KEY (collection_id, article_id)
+	);
+
+	CREATE INDEX collection_articles_article_id ON collection_articles (article_id);
+	`,
+
+	// One-time tokens users reset a forgotten password with
+	`
+	CREATE TABLE password_reset_tokens (
+		token TEXT PRIMARY KEY,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		expires_at DATETIME NOT NULL,
+		used_at DATETIME
+	);
+
+	CREATE INDEX password_reset_tokens_user_id ON password_reset_tokens (user_id);
+	`,
+
+	// Files uploaded for articles, the content is stored on disk by its SHA256
+	`
+	CREATE TABLE attachments (
+		id INTEGER PRIMARY KEY,
+		article_id INTEGER NOT NULL REFERENCES articles(id) ON DELETE CASCADE,
+		sha256 TEXT NOT NULL,
+		mime_type TEXT NOT NULL,
+		size INTEGER NOT NULL,
+		created_at DATETIME NOT NULL,
+		UNIQUE (article_id, sha256)
+	);
+
+	CREATE INDEX attachments_sha256 ON attachments (sha256);
+	`,
+
+	// Writing streaks: consecutive days with at least one published article
+	`
+	ALTER TABLE users ADD COLUMN current_streak INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE users ADD COLUMN longest_streak INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE users ADD COLUMN last_published_date DATE;
+	`,
+
+	// Account lockout: wrong passwords in a row and until when logins are refused
+	`
+	ALTER TABLE users ADD COLUMN failed_logins INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE users ADD COLUMN locked_until DATETIME;
+	`,
+}
+
+// migrate brings the schema up to date. Foreign keys are off while it runs,
+// since re-creating a referenced table would violate them. It holds one connection
+// for that and turns them back on before the connection returns to the pool
+func migrate(db *sql.DB) (err error) {
+	const op = "storage.sqlite.migrate"
+
+	ctx := context.Background()
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `PRAGMA foreign_keys = OFF`); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() {
+		if _, fkErr := conn.ExecContext(ctx, `PRAGMA foreign_keys = ON`); fkErr != nil && err == nil {
+			err = fmt.Errorf("%s: %w", op, fkErr)
+		}
+	}()
+
+	var version int
+	if err := conn.QueryRowContext(ctx, `PRAGMA user_version`).Scan(&version); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	for i := version; i < len(migrations); i++ {
+		tx, err := conn.BeginTx(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		if _, err := tx.Exec(migrations[i]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("%s: migration %d: %w", op, i+1, err)
+		}
+
+		// PRAGMA doesn't accept bound parameters
+		if _, err := tx.Exec(fmt.Sprintf(`PRAGMA user_version = %d`, i+1)); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("%s: migration %d: %w", op, i+1, err)
+		}
+
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("%s: migration %d: %w", op, i+1, err)
+		}
+	}
+
+	return nil
+}
@@ -3,8 +3,12 @@ package sqlite
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"net/mail"
+	"os"
+	"strings"
 	"time"
 
 	"blog-api/internal/domain/models"
@@ -17,115 +21,324 @@ type Storage struct {
 	db *sql.DB
 }
 
+// New opens the database at storagePath and migrates it. ":memory:" opens a fresh in-memory
+// database, which lives as long as the storage since it keeps a single connection
 func New(storagePath string) (*Storage, error) {
 	const op = "storage.sqlite.New"
 
-	db, err := sql.Open("sqlite3", storagePath)
+	// _foreign_keys makes the driver enable them on every connection it opens,
+	// including one reopened after the previous went bad. Writers wait for each other
+	// instead of failing with "database is locked"
+	db, err := sql.Open("sqlite3", "file:"+storagePath+"?_foreign_keys=on&_loc=UTC&_busy_timeout=5000&_txlock=immediate")
 	if err != nil {
-		return nil, fmt.Errorf("%s, %w", op, err)
+		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	stmt, err := db.Prepare(`
-		CREATE TABLE IF NOT EXISTS users (
-			id INTEGER PRIMARY KEY,
-			name TEXT UNIQUE NOT NULL,
-			pass_hash BLOB NOT NULL,
-			registration_date DATETIME NOT NULL,
-			status TEXT DEFAULT ''
-		);
-		
-		CREATE TABLE IF NOT EXISTS articles (
-			id INTEGER PRIMARY KEY,
-			title TEXT NOT NULL,
-			content TEXT NOT NULL,
-			publish_date DATETIME NOT NULL,
-			author_id INTEGER REFERENCES users(id)
-		);
+	// Every connection to ":memory:" opens its own empty database
+	if storagePath == ":memory:" {
+		db.SetMaxOpenConns(1)
+	}
 
-		CREATE TABLE IF NOT EXISTS users_articles (
-			article_d INTEGER REFERENCES articles(id)
-		);
-`)
-	if err != nil {
+	if err := migrate(db); err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
-	defer stmt.Close()
 
-	_, err = stmt.Exec()
+	return &Storage{db: db}, nil
+}
+
+// Close closes the database. With ":memory:" as the path its content is gone afterwards
+func (s *Storage) Close() error {
+	const op = "storage.sqlite.Close"
+
+	if err := s.db.Close(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// ### Maintenance ### //
+
+// Backup writes a consistent copy of the database to path
+func (s *Storage) Backup(ctx context.Context, path string) error {
+	const op = "storage.sqlite.Backup"
+
+	if _, err := s.db.ExecContext(ctx, `VACUUM INTO ?`, path); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Integrity runs an integrity check of the database
+func (s *Storage) Integrity(ctx context.Context) error {
+	const op = "storage.sqlite.Integrity"
+
+	if err := integrityCheck(ctx, s.db); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Optimize lets sqlite refresh the statistics used by the query planner,
+// it's meant to be run periodically by long-lived connections
+func (s *Storage) Optimize(ctx context.Context) error {
+	const op = "storage.sqlite.Optimize"
+
+	if _, err := s.db.ExecContext(ctx, "PRAGMA optimize"); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Restore replaces the database content with the backup at path.
+// The backup is checked for integrity before anything is overwritten
+func (s *Storage) Restore(ctx context.Context, path string) error {
+	const op = "storage.sqlite.Restore"
+
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	src, err := sql.Open("sqlite3", "file:"+path+"?mode=ro")
 	if err != nil {
-		return nil, fmt.Errorf("%s: %w", op, err)
+		return fmt.Errorf("%s: %w", op, err)
 	}
+	defer src.Close()
 
-	return &Storage{db: db}, nil
+	if err := integrityCheck(ctx, src); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	srcConn, err := src.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer srcConn.Close()
+
+	dstConn, err := s.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	// Copy pages with the sqlite3 online backup API
+	err = dstConn.Raw(func(dst any) error {
+		return srcConn.Raw(func(src any) error {
+			backup, err := dst.(*sqlite3.SQLiteConn).Backup("main", src.(*sqlite3.SQLiteConn), "main")
+			if err != nil {
+				return err
+			}
+
+			if _, err := backup.Step(-1); err != nil {
+				backup.Finish()
+				return err
+			}
+
+			return backup.Finish()
+		})
+	})
+	dstConn.Close()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	// The backup may come from an older schema
+	if err := migrate(s.db); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func integrityCheck(ctx context.Context, db *sql.DB) error {
+	rows, err := db.QueryContext(ctx, `PRAGMA integrity_check`)
+	if err != nil {
+		var sqliteErr sqlite3.Error
+		if errors.As(err, &sqliteErr) && (sqliteErr.Code == sqlite3.ErrNotADB || sqliteErr.Code == sqlite3.ErrCorrupt) {
+			return fmt.Errorf("%w: %w", storage.ErrCorrupted, err)
+		}
+		return err
+	}
+	defer rows.Close()
+
+	var problems []string
+	for rows.Next() {
+		var res string
+		if err := rows.Scan(&res); err != nil {
+			return err
+		}
+
+		if res != "ok" {
+			problems = append(problems, res)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s", storage.ErrCorrupted, strings.Join(problems, "; "))
+	}
+
+	return nil
 }
 
 // ### User ### //
 
-func (s *Storage) GetAllUsers(ctx context.Context) ([]models.User, error) {
+func (s *Storage) GetAllUsers(ctx context.Context, limit, offset int) ([]models.User, error) {
 	const op = "storage.sqlite.GetAllUsers"
 
-	stmt, err := s.db.PrepareContext(ctx, `SELECT id, name, registration_date, status FROM users`)
+	stmt, err := s.db.PrepareContext(ctx, `
+		SELECT id, name, registration_date, updated_at, status FROM users
+		ORDER BY registration_date, id
+		LIMIT ? OFFSET ?`)
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 	defer stmt.Close()
 
-	rows, err := stmt.QueryContext(ctx)
+	rows, err := stmt.QueryContext(ctx, limit, offset)
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
+	defer rows.Close()
 
-	var users []models.User
+	users := []models.User{}
 	for rows.Next() {
 		var user models.User
-		err := rows.Scan(&user.ID, &user.UserName, &user.RegistrationDate, &user.Status)
+		err := rows.Scan(&user.ID, &user.UserName, &user.RegistrationDate, &user.UpdatedAt, &user.Status)
 		if err != nil {
 			return nil, fmt.Errorf("%s: %w", op, err)
 		}
 
 		users = append(users, user)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
 
 	return users, nil
 }
 
-func (s *Storage) Register(ctx context.Context, username string, passHash []byte, regestrationDate time.Time) error {
+func (s *Storage) UsersCount(ctx context.Context) (int, error) {
+	const op = "storage.sqlite.UsersCount"
+
+	var count int
+	err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM users`).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return count, nil
+}
+
+// Register creates a user, an empty email is stored as NULL
+func (s *Storage) Register(ctx context.Context, username, email string, passHash []byte, regestrationDate time.Time) (int64, error) {
 	const op = "storage.sqlite.Register"
 
-	stmt, err := s.db.PrepareContext(ctx, `INSERT INTO users (name, pass_hash, registration_date) VALUES (?, ?, ?)`)
+	stmt, err := s.db.PrepareContext(ctx, `INSERT INTO users (name, email, pass_hash, registration_date, updated_at) VALUES (?, NULLIF(?, ''), ?, ?, ?)`)
 	if err != nil {
-		return fmt.Errorf("%s: %w", op, err)
+		return 0, fmt.Errorf("%s: %w", op, err)
 	}
 	defer stmt.Close()
 
-	_, err = stmt.ExecContext(ctx, username, passHash, regestrationDate)
+	res, err := stmt.ExecContext(ctx, username, email, passHash, regestrationDate, regestrationDate)
 	if err != nil {
 		var sqliteErr sqlite3.Error
 		if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
-			return fmt.Errorf("%s: %w", op, storage.ErrUserExists)
+			if isEmailConstraint(sqliteErr) {
+				return 0, fmt.Errorf("%s: %w", op, storage.ErrEmailTaken)
+			}
+			return 0, fmt.Errorf("%s: %w", op, storage.ErrUserExists)
 		}
-		return fmt.Errorf("%s: %w", op, err)
+		return 0, fmt.Errorf("%s: %w", op, err)
 	}
 
-	return nil
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return id, nil
+}
+
+func (s *Storage) UserNameExists(ctx context.Context, username string) (bool, error) {
+	const op = "storage.sqlite.UserNameExists"
+
+	var exists bool
+	err := s.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM users WHERE name = ? COLLATE NOCASE)`, username).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return exists, nil
+}
+
+func (s *Storage) EmailExists(ctx context.Context, email string) (bool, error) {
+	const op = "storage.sqlite.EmailExists"
+
+	var exists bool
+	err := s.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM users WHERE email = ? COLLATE NOCASE)`, email).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return exists, nil
 }
 
-func (s *Storage) UserByName(ctx context.Context, username string) (models.User, error) {
-	const op = "storage.sqlite.UserByName"
+// isEmailConstraint tells a violated email uniqueness from the user name one
+func isEmailConstraint(err sqlite3.Error) bool {
+	return strings.Contains(err.Error(), "users.email")
+}
+
+// UserByIdentifier finds a user by email when the identifier is an email address and by name otherwise,
+// both compared case-insensitively. An email never matches a name, so a name can't shadow someone's email
+func (s *Storage) UserByIdentifier(ctx context.Context, identifier string) (models.User, error) {
+	const op = "storage.sqlite.UserByIdentifier"
+
+	column := "name"
+	if addr, err := mail.ParseAddress(identifier); err == nil && addr.Address == identifier {
+		column = "email"
+	}
 
-	stmt, err := s.db.PrepareContext(ctx, `SELECT id, name, pass_hash FROM users WHERE name = ?`)
+	stmt, err := s.db.PrepareContext(ctx, `
+		SELECT id, name, pass_hash, role, locked_until FROM users
+		WHERE `+column+` = ? COLLATE NOCASE`)
 	if err != nil {
 		return models.User{}, fmt.Errorf("%s: %w", op, err)
 	}
 	defer stmt.Close()
 
-	res := stmt.QueryRowContext(ctx, username)
+	res := stmt.QueryRowContext(ctx, identifier)
 
 	var user models.User
-	err = res.Scan(&user.ID, &user.UserName, &user.PassHash)
+	err = res.Scan(&user.ID, &user.UserName, &user.PassHash, &user.Role, &user.LockedUntil)
 	if err != nil {
-		var sqliteErr sqlite3.Error
-		if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sql.ErrNoRows {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.User{}, fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+		}
+		return models.User{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return user, nil
+}
+
+// UserByEmail finds a user by email, compared case-insensitively
+func (s *Storage) UserByEmail(ctx context.Context, email string) (models.User, error) {
+	const op = "storage.sqlite.UserByEmail"
+
+	stmt, err := s.db.PrepareContext(ctx, `SELECT id, name FROM users WHERE email = ? COLLATE NOCASE`)
+	if err != nil {
+		return models.User{}, fmt.Errorf("%s: %w", op, err)
+	}
+	defer stmt.Close()
+
+	var user models.User
+	err = stmt.QueryRowContext(ctx, email).Scan(&user.ID, &user.UserName)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
 			return models.User{}, fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
 		}
 		return models.User{}, fmt.Errorf("%s: %w", op, err)
@@ -137,7 +350,7 @@ func (s *Storage) UserByName(ctx context.Context, username string) (models.User,
 func (s *Storage) UserByID(ctx context.Context, id int) (models.User, error) {
 	const op = "storage.sqlite.UserByID"
 
-	stmt, err := s.db.PrepareContext(ctx, `SELECT id, name, registration_date, status FROM users WHERE id = ?`)
+	stmt, err := s.db.PrepareContext(ctx, `SELECT id, name, registration_date, updated_at, status, `+currentStreak+`, longest_streak FROM users WHERE id = ?`)
 	if err != nil {
 		return models.User{}, fmt.Errorf("%s: %w", op, err)
 	}
@@ -146,10 +359,9 @@ func (s *Storage) UserByID(ctx context.Context, id int) (models.User, error) {
 	res := stmt.QueryRowContext(ctx, id)
 
 	var user models.User
-	err = res.Scan(&user.ID, &user.UserName, &user.RegistrationDate, &user.Status)
+	err = res.Scan(&user.ID, &user.UserName, &user.RegistrationDate, &user.UpdatedAt, &user.Status, &user.CurrentStreak, &user.LongestStreak)
 	if err != nil {
-		var sqliteErr sqlite3.Error
-		if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sql.ErrNoRows {
+		if errors.Is(err, sql.ErrNoRows) {
 			return models.User{}, fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
 		}
 		return models.User{}, fmt.Errorf("%s: %w", op, err)
@@ -158,212 +370,1946 @@ func (s *Storage) UserByID(ctx context.Context, id int) (models.User, error) {
 	return user, nil
 }
 
-func (s *Storage) RemoveUser(ctx context.Context, id int) error {
-	const op = "storage.sqlite.RemoveUser"
-
-	stmt, err := s.db.PrepareContext(ctx, `DELETE FROM users WHERE id = ?`)
+// currentStreak selects the user's current streak, which is broken once a day passes without an article
+const currentStreak = `CASE WHEN last_published_date >= date('now', '-1 day') THEN current_streak ELSE 0 END`
+
+// UpdateStreak counts today as a writing day of the user. The streak grows if the user
+// also published yesterday and starts over at 1 after a gap, dates are in UTC
+func (s *Storage) UpdateStreak(ctx context.Context, authorID int) error {
+	const op = "storage.sqlite.UpdateStreak"
+
+	// SET expressions see the old values, so the new streak is computed twice
+	const streak = `CASE
+		WHEN last_published_date = date('now') THEN current_streak
+		WHEN last_published_date = date('now', '-1 day') THEN current_streak + 1
+		ELSE 1
+	END`
+
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE users SET
+			current_streak = `+streak+`,
+			longest_streak = MAX(longest_streak, `+streak+`),
+			last_published_date = date('now')
+		WHERE id = ?`, authorID)
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
+
+	if err := checkAffected(res, storage.ErrUserNotFound); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// GetTotalLikesForAuthor counts likes on all articles of the author
+func (s *Storage) GetTotalLikesForAuthor(ctx context.Context, authorID int) (int, error) {
+	const op = "storage.sqlite.GetTotalLikesForAuthor"
+
+	var likes int
+	err := s.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM reactions r
+		JOIN articles a ON r.article_id = a.id
+		WHERE a.author_id = ? AND r.reaction_type = 'like'`, authorID).Scan(&likes)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return likes, nil
+}
+
+// GetUserByUsername returns the public profile of the user, the name is matched case-insensitively
+func (s *Storage) GetUserByUsername(ctx context.Context, username string) (models.User, error) {
+	const op = "storage.sqlite.GetUserByUsername"
+
+	stmt, err := s.db.PrepareContext(ctx, `SELECT id, name, registration_date, updated_at, status, `+currentStreak+`, longest_streak FROM users WHERE name = ? COLLATE NOCASE`)
+	if err != nil {
+		return models.User{}, fmt.Errorf("%s: %w", op, err)
+	}
 	defer stmt.Close()
 
-	_, err = stmt.ExecContext(ctx, id)
+	res := stmt.QueryRowContext(ctx, username)
+
+	var user models.User
+	err = res.Scan(&user.ID, &user.UserName, &user.RegistrationDate, &user.UpdatedAt, &user.Status, &user.CurrentStreak, &user.LongestStreak)
 	if err != nil {
-		var sqliteErr sqlite3.Error
-		if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sql.ErrNoRows {
-			return fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.User{}, fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
 		}
-		return fmt.Errorf("%s: %w", op, err)
+		return models.User{}, fmt.Errorf("%s: %w", op, err)
 	}
 
-	return nil
+	return user, nil
 }
 
-func (s *Storage) UpdateUserName(ctx context.Context, id int, username string) error {
-	const op = "storage.service.UpdateUserName"
+func (s *Storage) RemoveUser(ctx context.Context, id int) error {
+	const op = "storage.sqlite.RemoveUser"
 
-	stmt, err := s.db.PrepareContext(ctx, `UPDATE users SET name = ? WHERE id = ?`)
+	stmt, err := s.db.PrepareContext(ctx, `DELETE FROM users WHERE id = ?`)
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
 	defer stmt.Close()
 
-	_, err = stmt.ExecContext(ctx, username, id)
+	res, err := stmt.ExecContext(ctx, id)
 	if err != nil {
-		var sqliteErr sqlite3.Error
-		if errors.As(err, &sqliteErr) {
-			if sqliteErr.ExtendedCode == sql.ErrNoRows {
-				return fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
-			}
-			if sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
-				return fmt.Errorf("%s: %w", op, storage.ErrUserNameTaken)
-			}
-		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := checkAffected(res, storage.ErrUserNotFound); err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
 	return nil
 }
 
-func (s *Storage) UpdateStatus(ctx context.Context, id int, status string) error {
-	const op = "storage.sqlite.UpdateStatus"
+func (s *Storage) UpdateUserName(ctx context.Context, id int, username string) error {
+	const op = "storage.sqlite.UpdateUserName"
 
-	stmt, err := s.db.PrepareContext(ctx, `UPDATE users SET status = ? WHERE id = ?`)
+	stmt, err := s.db.PrepareContext(ctx, `UPDATE users SET name = ?, updated_at = ? WHERE id = ?`)
 	if err != nil {
-		return err
+		return fmt.Errorf("%s: %w", op, err)
 	}
 	defer stmt.Close()
 
-	_, err = stmt.ExecContext(ctx, status, id)
+	res, err := stmt.ExecContext(ctx, username, time.Now().UTC(), id)
 	if err != nil {
 		var sqliteErr sqlite3.Error
-		if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sql.ErrNoRows {
-			return fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+		if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+			return fmt.Errorf("%s: %w", op, storage.ErrUserNameTaken)
 		}
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
+	if err := checkAffected(res, storage.ErrUserNotFound); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
 	return nil
 }
 
-// ### Article ### //
+// EmailsByUserNames returns the emails of the named users by their ids.
+// Names are compared case-insensitively, users without an email are left out
+func (s *Storage) EmailsByUserNames(ctx context.Context, names []string) (map[int]string, error) {
+	const op = "storage.sqlite.EmailsByUserNames"
 
-func (s *Storage) GetAllArticles(ctx context.Context) ([]models.Article, error) {
-	const op = "storage.sqlite.GetAllArticles"
+	emails := make(map[int]string)
+	if len(names) == 0 {
+		return emails, nil
+	}
+
+	args := make([]any, 0, len(names))
+	for _, name := range names {
+		args = append(args, name)
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(names)), ", ")
 
-	stmt, err := s.db.PrepareContext(ctx, `SELECT * FROM articles`)
+	stmt, err := s.db.PrepareContext(ctx, `SELECT id, email FROM users WHERE email IS NOT NULL AND name COLLATE NOCASE IN (`+placeholders+`)`)
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 	defer stmt.Close()
 
-	rows, err := stmt.QueryContext(ctx)
+	rows, err := stmt.QueryContext(ctx, args...)
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
+	defer rows.Close()
 
-	var arts []models.Article
 	for rows.Next() {
-		var art models.Article
-
-		err = rows.Scan(&art.ID, &art.Title, &art.Content, &art.PublishDate, &art.AuthorID)
-		if err != nil {
+		var (
+			id    int
+			email string
+		)
+		if err := rows.Scan(&id, &email); err != nil {
 			return nil, fmt.Errorf("%s: %w", op, err)
 		}
 
-		arts = append(arts, art)
+		emails[id] = email
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	return arts, nil
+	return emails, nil
 }
 
-func (s *Storage) GetArticleByID(ctx context.Context, id int) (*models.Article, error) {
-	const op = "storage.sqlite.GetArticleByID"
+// UpdateEmail sets the user's email, an empty one removes it
+func (s *Storage) UpdateEmail(ctx context.Context, id int, email string) error {
+	const op = "storage.sqlite.UpdateEmail"
 
-	stmt, err := s.db.PrepareContext(ctx, `SELECT title, content, publish_date, author_id FROM articles WHERE id = ?`)
+	stmt, err := s.db.PrepareContext(ctx, `UPDATE users SET email = NULLIF(?, ''), updated_at = ? WHERE id = ?`)
 	if err != nil {
-		return nil, fmt.Errorf("%s: %w", op, err)
+		return fmt.Errorf("%s: %w", op, err)
 	}
 	defer stmt.Close()
 
-	row := stmt.QueryRowContext(ctx, id)
+	res, err := stmt.ExecContext(ctx, email, time.Now().UTC(), id)
 	if err != nil {
 		var sqliteErr sqlite3.Error
-		if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sql.ErrNoRows {
-			return nil, fmt.Errorf("%s: %w", op, storage.ErrArticleNotFound)
+		if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+			return fmt.Errorf("%s: %w", op, storage.ErrEmailTaken)
 		}
-		return nil, fmt.Errorf("%s: %w", op, err)
+		return fmt.Errorf("%s: %w", op, err)
 	}
 
-	var art models.Article
-	err = row.Scan(&art.Title, &art.Content, &art.PublishDate, &art.AuthorID)
-	if err != nil {
-		return nil, fmt.Errorf("%s: %w", op, err)
+	if err := checkAffected(res, storage.ErrUserNotFound); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
 	}
 
-	return &art, nil
+	return nil
 }
 
-func (s *Storage) CreateArticle(ctx context.Context, userID int, title, content string, publishDate time.Time) error {
-	const op = "storage.sqlite.CreateArticle"
+func (s *Storage) UpdatePassHash(ctx context.Context, id int64, passHash []byte) error {
+	const op = "storage.sqlite.UpdatePassHash"
 
-	stmt, err := s.db.PrepareContext(ctx, `INSERT INTO articles (title, content, publish_date, author_id) VALUES (?, ?, ?, ?)`)
+	stmt, err := s.db.PrepareContext(ctx, `UPDATE users SET pass_hash = ?, updated_at = ? WHERE id = ?`)
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
 	defer stmt.Close()
 
-	_, err = stmt.ExecContext(ctx, title, content, publishDate, userID)
+	res, err := stmt.ExecContext(ctx, passHash, time.Now().UTC(), id)
 	if err != nil {
-		var sqliteErr sqlite3.Error
-		if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
-			return fmt.Errorf("%s: %w", op, storage.ErrArticleExists)
-		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := checkAffected(res, storage.ErrUserNotFound); err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
 	return nil
 }
 
-func (s *Storage) UpdateArticleTitle(ctx context.Context, id int, title string) error {
-	const op = "storage.sqlite.UpdateArticleTitle"
+func (s *Storage) UpdateStatus(ctx context.Context, id int, status string) error {
+	const op = "storage.sqlite.UpdateStatus"
 
-	stmt, err := s.db.PrepareContext(ctx, `UPDATE articles SET title = ? WHERE id = ?`)
+	stmt, err := s.db.PrepareContext(ctx, `UPDATE users SET status = ?, updated_at = ? WHERE id = ?`)
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
 	defer stmt.Close()
 
-	_, err = stmt.ExecContext(ctx, title, id)
+	res, err := stmt.ExecContext(ctx, status, time.Now().UTC(), id)
 	if err != nil {
-		/* var sqliteErr sqlite3.Error
-		if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sql.ErrNoRows {
-			return fmt.Errorf("%s: %w", op, storage.ErrArticleNotFound)
-		} */
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := checkAffected(res, storage.ErrUserNotFound); err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
 	return nil
 }
 
-func (s *Storage) UpdateArticleContent(ctx context.Context, id int, content string) error {
-	const op = "storage.sqlite.UpdateArticleContent"
+// AddLoginFailure counts a wrong password for the user and returns how many came in a row
+func (s *Storage) AddLoginFailure(ctx context.Context, userID int64) (int, error) {
+	const op = "storage.sqlite.AddLoginFailure"
+
+	var failures int
+	err := s.db.QueryRowContext(ctx, `
+		UPDATE users SET failed_logins = failed_logins + 1
+		WHERE id = ?
+		RETURNING failed_logins`, userID).Scan(&failures)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+		}
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return failures, nil
+}
+
+// LockUser refuses the user's logins until the given time and starts counting wrong passwords over
+func (s *Storage) LockUser(ctx context.Context, userID int64, until time.Time) error {
+	const op = "storage.sqlite.LockUser"
 
-	stmt, err := s.db.PrepareContext(ctx, `UPDATE articles SET content = ? WHERE id = ?`)
+	res, err := s.db.ExecContext(ctx, `UPDATE users SET locked_until = ?, failed_logins = 0 WHERE id = ?`, until, userID)
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
-	defer stmt.Close()
+	if err := checkAffected(res, storage.ErrUserNotFound); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
 
-	_, err = stmt.ExecContext(ctx, content, id)
+// ResetLoginFailures forgets the user's wrong passwords after a successful login
+func (s *Storage) ResetLoginFailures(ctx context.Context, userID int64) error {
+	const op = "storage.sqlite.ResetLoginFailures"
+
+	// Most logins have nothing to reset, skip the write for them
+	_, err := s.db.ExecContext(ctx,
+		`UPDATE users SET failed_logins = 0, locked_until = NULL WHERE id = ? AND (failed_logins > 0 OR locked_until IS NOT NULL)`,
+		userID,
+	)
 	if err != nil {
-		/* var sqliteErr sqlite3.Error
-		if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sql.ErrNoRows {
-			return fmt.Errorf("%s: %w", op, storage.ErrArticleNotFound)
-		} */
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
 	return nil
 }
 
-func (s *Storage) RemoveArticle(ctx context.Context, id int) error {
-	const op = "storage.sqlite.RemoveArticle"
+func (s *Storage) AddLoginEvent(ctx context.Context, e models.LoginEvent) error {
+	const op = "storage.sqlite.AddLoginEvent"
 
-	stmt, err := s.db.PrepareContext(ctx, `DELETE FROM articles WHERE id = ?`)
+	stmt, err := s.db.PrepareContext(ctx, `
+		INSERT INTO login_history (user_id, ip, user_agent, logged_in_at, success) VALUES (?, ?, ?, ?, ?)`)
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
 	defer stmt.Close()
 
-	_, err = stmt.ExecContext(ctx, id)
+	_, err = stmt.ExecContext(ctx, e.UserID, e.IP, e.UserAgent, e.LoggedInAt, e.Success)
 	if err != nil {
-		var sqliteErr sqlite3.Error
-		if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sql.ErrNoRows {
-			return fmt.Errorf("%s: %w", op, storage.ErrArticleNotFound)
-		}
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
 	return nil
 }
+
+// GetLoginHistory returns the user's login attempts, most recent first
+func (s *Storage) GetLoginHistory(ctx context.Context, userID, limit, offset int) ([]models.LoginEvent, error) {
+	const op = "storage.sqlite.GetLoginHistory"
+
+	stmt, err := s.db.PrepareContext(ctx, `
+		SELECT id, user_id, ip, user_agent, logged_in_at, success FROM login_history
+		WHERE user_id = ?
+		ORDER BY logged_in_at DESC, id DESC
+		LIMIT ? OFFSET ?`)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, userID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	history := []models.LoginEvent{}
+	for rows.Next() {
+		var e models.LoginEvent
+		err := rows.Scan(&e.ID, &e.UserID, &e.IP, &e.UserAgent, &e.LoggedInAt, &e.Success)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		history = append(history, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return history, nil
+}
+
+// ### Session ### //
+
+func (s *Storage) CreateSession(ctx context.Context, sess models.Session) (int64, error) {
+	const op = "storage.sqlite.CreateSession"
+
+	stmt, err := s.db.PrepareContext(ctx, `
+		INSERT INTO sessions (user_id, user_agent, ip, created_at, last_seen) VALUES (?, ?, ?, ?, ?)`)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer stmt.Close()
+
+	res, err := stmt.ExecContext(ctx, sess.UserID, sess.UserAgent, sess.IP, sess.CreatedAt, sess.LastSeen)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return id, nil
+}
+
+func (s *Storage) SessionByID(ctx context.Context, id int64) (models.Session, error) {
+	const op = "storage.sqlite.SessionByID"
+
+	stmt, err := s.db.PrepareContext(ctx, `
+		SELECT id, user_id, user_agent, ip, created_at, last_seen, revoked FROM sessions WHERE id = ?`)
+	if err != nil {
+		return models.Session{}, fmt.Errorf("%s: %w", op, err)
+	}
+	defer stmt.Close()
+
+	var sess models.Session
+	err = stmt.QueryRowContext(ctx, id).Scan(
+		&sess.ID, &sess.UserID, &sess.UserAgent, &sess.IP, &sess.CreatedAt, &sess.LastSeen, &sess.Revoked)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Session{}, fmt.Errorf("%s: %w", op, storage.ErrSessionNotFound)
+		}
+		return models.Session{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return sess, nil
+}
+
+// ActiveSessions returns the user's sessions that aren't revoked, most recently used first
+func (s *Storage) ActiveSessions(ctx context.Context, userID int) ([]models.Session, error) {
+	const op = "storage.sqlite.ActiveSessions"
+
+	stmt, err := s.db.PrepareContext(ctx, `
+		SELECT id, user_id, user_agent, ip, created_at, last_seen, revoked FROM sessions
+		WHERE user_id = ? AND revoked = false
+		ORDER BY last_seen DESC, id DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	sessions := []models.Session{}
+	for rows.Next() {
+		var sess models.Session
+		err := rows.Scan(&sess.ID, &sess.UserID, &sess.UserAgent, &sess.IP, &sess.CreatedAt, &sess.LastSeen, &sess.Revoked)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		sessions = append(sessions, sess)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return sessions, nil
+}
+
+func (s *Storage) TouchSession(ctx context.Context, id int64, lastSeen time.Time) error {
+	const op = "storage.sqlite.TouchSession"
+
+	stmt, err := s.db.PrepareContext(ctx, `UPDATE sessions SET last_seen = ? WHERE id = ?`)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer stmt.Close()
+
+	_, err = stmt.ExecContext(ctx, lastSeen, id)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// RevokeSession revokes the user's active session, sessions of other users are not found
+func (s *Storage) RevokeSession(ctx context.Context, userID int, id int64) error {
+	const op = "storage.sqlite.RevokeSession"
+
+	stmt, err := s.db.PrepareContext(ctx, `UPDATE sessions SET revoked = true WHERE id = ? AND user_id = ? AND revoked = false`)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer stmt.Close()
+
+	res, err := stmt.ExecContext(ctx, id, userID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := checkAffected(res, storage.ErrSessionNotFound); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// RevokeOldestSessions revokes the user's active sessions except the keep newest ones
+func (s *Storage) RevokeOldestSessions(ctx context.Context, userID int64, keep int) error {
+	const op = "storage.sqlite.RevokeOldestSessions"
+
+	stmt, err := s.db.PrepareContext(ctx, `
+		UPDATE sessions SET revoked = true
+		WHERE user_id = ? AND revoked = false AND id NOT IN (
+			SELECT id FROM sessions
+			WHERE user_id = ? AND revoked = false
+			ORDER BY created_at DESC, id DESC
+			LIMIT ?
+		)`)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer stmt.Close()
+
+	_, err = stmt.ExecContext(ctx, userID, userID, keep)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// checkAffected returns notFound when the statement changed no rows
+func checkAffected(res sql.Result, notFound error) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return notFound
+	}
+
+	return nil
+}
+
+// ### Password reset ### //
+
+func (s *Storage) CreatePasswordReset(ctx context.Context, token string, userID int64, expiresAt time.Time) error {
+	const op = "storage.sqlite.CreatePasswordReset"
+
+	stmt, err := s.db.PrepareContext(ctx, `INSERT INTO password_reset_tokens (token, user_id, expires_at) VALUES (?, ?, ?)`)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer stmt.Close()
+
+	_, err = stmt.ExecContext(ctx, token, userID, expiresAt)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// ResetPassword sets the password hash of the token's user and returns the user's id.
+// The token and any other unused ones of the user are spent, and the user's sessions are revoked
+func (s *Storage) ResetPassword(ctx context.Context, token string, passHash []byte, now time.Time) (int64, error) {
+	const op = "storage.sqlite.ResetPassword"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	var (
+		userID    int64
+		expiresAt time.Time
+		usedAt    sql.NullTime
+	)
+	err = tx.QueryRowContext(ctx, `SELECT user_id, expires_at, used_at FROM password_reset_tokens WHERE token = ?`, token).
+		Scan(&userID, &expiresAt, &usedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, fmt.Errorf("%s: %w", op, storage.ErrResetTokenNotFound)
+		}
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	if usedAt.Valid {
+		return 0, fmt.Errorf("%s: %w", op, storage.ErrResetTokenUsed)
+	}
+	if !now.Before(expiresAt) {
+		return 0, fmt.Errorf("%s: %w", op, storage.ErrResetTokenExpired)
+	}
+
+	// Checked again in case a concurrent reset spent the token first
+	res, err := tx.ExecContext(ctx, `UPDATE password_reset_tokens SET used_at = ? WHERE token = ? AND used_at IS NULL`, now, token)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	if err := checkAffected(res, storage.ErrResetTokenUsed); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE password_reset_tokens SET used_at = ? WHERE user_id = ? AND used_at IS NULL`, now, userID); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	res, err = tx.ExecContext(ctx, `UPDATE users SET pass_hash = ?, updated_at = ? WHERE id = ?`, passHash, now, userID)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	if err := checkAffected(res, storage.ErrUserNotFound); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE sessions SET revoked = true WHERE user_id = ? AND revoked = false`, userID); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return userID, nil
+}
+
+// ### Article ### //
+
+// Views and reactions are counted on read instead of keeping counter columns.
+// Likes and dislikes come from one pass over the reactions, joined in by articlesFrom
+const (
+	articleViews    = `(SELECT COUNT(*) FROM article_views WHERE article_id = articles.id)`
+	articleLikes    = `COALESCE(article_reactions.likes, 0)`
+	articleDislikes = `COALESCE(article_reactions.dislikes, 0)`
+)
+
+// articlesFrom replaces the articles table in every query selecting articleColumns
+const articlesFrom = `articles
+	LEFT JOIN (
+		SELECT article_id,
+			COUNT(*) FILTER (WHERE reaction_type = 'like') AS likes,
+			COUNT(*) FILTER (WHERE reaction_type = 'dislike') AS dislikes
+		FROM reactions
+		GROUP BY article_id
+	) AS article_reactions ON article_reactions.article_id = articles.id`
+
+// articleColumns are selected by every article query, in the order scanArticle reads them
+const articleColumns = `id, title, content, language, canonical_url, publish_date, created_at, updated_at, status, author_id, is_pinned, version,
+	` + articleViews + `,
+	` + articleLikes + `,
+	` + articleDislikes
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanArticle(row scanner) (models.Article, error) {
+	var art models.Article
+	err := row.Scan(&art.ID, &art.Title, &art.Content, &art.Language, &art.CanonicalURL, &art.PublishDate, &art.CreatedAt, &art.UpdatedAt, &art.Status, &art.AuthorID, &art.Pinned, &art.Version,
+		&art.Views, &art.Likes, &art.Dislikes)
+	art.Score = art.Likes - art.Dislikes
+	return art, err
+}
+
+// articleVisible leaves out drafts unless visibility isn't checked or they are the viewer's own,
+// its arguments are visibleOnly, models.ArticlePublished and viewerID
+const articleVisible = `(NOT ? OR status = ? OR author_id = ?)`
+
+// articleOrder returns the ORDER BY clause for the sort, def is used when no sort is given
+func articleOrder(sort, def string) string {
+	switch sort {
+	case models.ArticleSortUpdated:
+		return "updated_at DESC, id DESC"
+	case models.ArticleSortLikes:
+		return articleLikes + " DESC, id DESC"
+	case models.ArticleSortViews:
+		return articleViews + " DESC, id DESC"
+	default:
+		return def
+	}
+}
+
+// GetAllArticles returns all articles, an empty language matches any. With visibleOnly drafts
+// are left out, except those of viewerID, which is 0 for anonymous callers.
+// A negative limit lifts it
+func (s *Storage) GetAllArticles(ctx context.Context, language, sort string, popular models.PopularityFilter, visibleOnly bool, viewerID, limit, offset int) ([]models.Article, error) {
+	const op = "storage.sqlite.GetAllArticles"
+
+	stmt, err := s.db.PrepareContext(ctx, `
+		SELECT `+articleColumns+` FROM `+articlesFrom+`
+		WHERE (? = '' OR language = ? COLLATE NOCASE)
+		AND `+articleVisible+`
+		AND (? = 0 OR `+articleLikes+` >= ?)
+		AND (? = 0 OR `+articleViews+` >= ?)
+		ORDER BY `+articleOrder(sort, "id")+`
+		LIMIT ? OFFSET ?`)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, language, language, visibleOnly, models.ArticlePublished, viewerID,
+		popular.MinLikes, popular.MinLikes, popular.MinViews, popular.MinViews, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var arts []models.Article
+	for rows.Next() {
+		art, err := scanArticle(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		arts = append(arts, art)
+	}
+
+	return arts, nil
+}
+
+// GetArticlesInRange returns the articles published between from and to inclusive,
+// visibleOnly and viewerID leave out drafts as in GetAllArticles
+func (s *Storage) GetArticlesInRange(ctx context.Context, from, to time.Time, language, sort string, visibleOnly bool, viewerID, limit, offset int) ([]models.Article, error) {
+	const op = "storage.sqlite.GetArticlesInRange"
+
+	stmt, err := s.db.PrepareContext(ctx, `
+		SELECT `+articleColumns+` FROM `+articlesFrom+`
+		WHERE publish_date BETWEEN ? AND ?
+		AND (? = '' OR language = ? COLLATE NOCASE)
+		AND `+articleVisible+`
+		ORDER BY `+articleOrder(sort, "publish_date, id")+`
+		LIMIT ? OFFSET ?`)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, from, to, language, language, visibleOnly, models.ArticlePublished, viewerID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	arts := []models.Article{}
+	for rows.Next() {
+		art, err := scanArticle(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		arts = append(arts, art)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return arts, nil
+}
+
+// GetArticlesByAuthorID returns the author's articles, pinned ones first and then the newest.
+// visibleOnly and viewerID leave out drafts as in GetAllArticles
+func (s *Storage) GetArticlesByAuthorID(ctx context.Context, authorID int, visibleOnly bool, viewerID, limit, offset int) ([]models.Article, error) {
+	const op = "storage.sqlite.GetArticlesByAuthorID"
+
+	stmt, err := s.db.PrepareContext(ctx, `
+		SELECT `+articleColumns+` FROM `+articlesFrom+`
+		WHERE author_id = ? AND `+articleVisible+`
+		ORDER BY is_pinned DESC, publish_date DESC, id DESC
+		LIMIT ? OFFSET ?`)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, authorID, visibleOnly, models.ArticlePublished, viewerID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	arts := []models.Article{}
+	for rows.Next() {
+		art, err := scanArticle(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		arts = append(arts, art)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return arts, nil
+}
+
+// AggregateArticleStats rolls views and likes up into article_stats_hourly.
+// Hours from the last aggregated one on are recomputed, as it may have been incomplete
+func (s *Storage) AggregateArticleStats(ctx context.Context) error {
+	const op = "storage.sqlite.AggregateArticleStats"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	var since string
+	err = tx.QueryRowContext(ctx, `SELECT COALESCE(MAX(hour), '') FROM article_stats_hourly`).Scan(&since)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM article_stats_hourly WHERE hour >= ?`, since); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO article_stats_hourly (article_id, hour, views, likes)
+		SELECT article_id, hour, SUM(views), SUM(likes) FROM (
+			SELECT article_id, strftime('%Y-%m-%d %H:00:00', viewed_at) AS hour, 1 AS views, 0 AS likes
+			FROM article_views WHERE viewed_at >= ?1
+			UNION ALL
+			SELECT article_id, strftime('%Y-%m-%d %H:00:00', created_at), 0, 1
+			FROM reactions WHERE reaction_type = 'like' AND created_at >= ?1
+		)
+		GROUP BY article_id, hour`, since)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// GetTrendingArticles returns published articles by their score since the given time,
+// a view counts once and a like three times. Articles without activity follow, newest first
+func (s *Storage) GetTrendingArticles(ctx context.Context, since time.Time, limit int) ([]models.Article, error) {
+	const op = "storage.sqlite.GetTrendingArticles"
+
+	stmt, err := s.db.PrepareContext(ctx, `
+		WITH trending AS (
+			SELECT article_id, SUM(views) + 3 * SUM(likes) AS score
+			FROM article_stats_hourly
+			WHERE hour >= ?
+			GROUP BY article_id
+		)
+		SELECT `+articleColumns+` FROM `+articlesFrom+`
+		LEFT JOIN trending ON trending.article_id = articles.id
+		WHERE status = ?
+		ORDER BY COALESCE(trending.score, 0) DESC, publish_date DESC, id DESC
+		LIMIT ?`)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer stmt.Close()
+
+	// Hours are stored in UTC as text, see AggregateArticleStats
+	rows, err := stmt.QueryContext(ctx, since.UTC().Format(time.DateTime), models.ArticlePublished, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	arts := []models.Article{}
+	for rows.Next() {
+		art, err := scanArticle(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		arts = append(arts, art)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return arts, nil
+}
+
+// LastArticleUpdate returns when any article was last created, changed or removed,
+// with second precision. It's the zero time if that never happened.
+// Dates in the future, like those of scheduled articles, count as now
+func (s *Storage) LastArticleUpdate(ctx context.Context) (time.Time, error) {
+	const op = "storage.sqlite.LastArticleUpdate"
+
+	// Timestamps are stored as text in different formats, strftime reads all of them
+	var unix int64
+	err := s.db.QueryRowContext(ctx, `
+		SELECT MAX(
+			COALESCE((SELECT MAX(CAST(strftime('%s', COALESCE(updated_at, publish_date)) AS INTEGER)) FROM articles), 0),
+			COALESCE((SELECT CAST(strftime('%s', last_deleted_at) AS INTEGER) FROM articles_meta), 0)
+		)`).Scan(&unix)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if unix == 0 {
+		return time.Time{}, nil
+	}
+
+	if now := time.Now().UTC(); unix > now.Unix() {
+		return now.Truncate(time.Second), nil
+	}
+
+	return time.Unix(unix, 0).UTC(), nil
+}
+
+// GetArticlesByIDs returns the articles with the given ids in no particular order, unknown ids are skipped
+// and so are drafts left out by visibleOnly and viewerID as in GetAllArticles
+func (s *Storage) GetArticlesByIDs(ctx context.Context, ids []int, visibleOnly bool, viewerID int) ([]models.Article, error) {
+	const op = "storage.sqlite.GetArticlesByIDs"
+
+	if len(ids) == 0 {
+		return []models.Article{}, nil
+	}
+
+	args := make([]any, 0, len(ids)+3)
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	args = append(args, visibleOnly, models.ArticlePublished, viewerID)
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", ")
+
+	stmt, err := s.db.PrepareContext(ctx, `SELECT `+articleColumns+` FROM `+articlesFrom+` WHERE id IN (`+placeholders+`) AND `+articleVisible)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	arts := []models.Article{}
+	for rows.Next() {
+		art, err := scanArticle(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		arts = append(arts, art)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return arts, nil
+}
+
+// GetArticleByID returns the article, a draft left out by visibleOnly and viewerID
+// as in GetAllArticles is reported as not found
+func (s *Storage) GetArticleByID(ctx context.Context, id int, visibleOnly bool, viewerID int) (*models.Article, error) {
+	const op = "storage.sqlite.GetArticleByID"
+
+	stmt, err := s.db.PrepareContext(ctx, `SELECT `+articleColumns+` FROM `+articlesFrom+` WHERE id = ? AND `+articleVisible)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer stmt.Close()
+
+	row := stmt.QueryRowContext(ctx, id, visibleOnly, models.ArticlePublished, viewerID)
+
+	art, err := scanArticle(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%s: %w", op, storage.ErrArticleNotFound)
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &art, nil
+}
+
+func (s *Storage) PublishedArticlesCount(ctx context.Context) (int, error) {
+	const op = "storage.sqlite.PublishedArticlesCount"
+
+	var count int
+	err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM articles WHERE status = ?`, models.ArticlePublished).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return count, nil
+}
+
+// PublishedArticleDates returns only the id and publish date of published articles,
+// which is all a sitemap needs
+func (s *Storage) PublishedArticleDates(ctx context.Context, limit, offset int) ([]models.Article, error) {
+	const op = "storage.sqlite.PublishedArticleDates"
+
+	stmt, err := s.db.PrepareContext(ctx, `
+		SELECT id, publish_date FROM articles
+		WHERE status = ?
+		ORDER BY id
+		LIMIT ? OFFSET ?`)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, models.ArticlePublished, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	articles := []models.Article{}
+	for rows.Next() {
+		var art models.Article
+		if err := rows.Scan(&art.ID, &art.PublishDate); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		articles = append(articles, art)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return articles, nil
+}
+
+// AddArticleView records a view unless the same viewer has already seen the article since the given time
+func (s *Storage) AddArticleView(ctx context.Context, articleID int, fingerprint string, viewedAt, since time.Time) error {
+	const op = "storage.sqlite.AddArticleView"
+
+	stmt, err := s.db.PrepareContext(ctx, `
+		INSERT INTO article_views (article_id, viewer_fingerprint, viewed_at)
+		SELECT ?, ?, ?
+		WHERE NOT EXISTS (
+			SELECT 1 FROM article_views
+			WHERE article_id = ? AND viewer_fingerprint = ? AND viewed_at > ?
+		)`)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer stmt.Close()
+
+	_, err = stmt.ExecContext(ctx, articleID, fingerprint, viewedAt, articleID, fingerprint, since)
+	if err != nil {
+		var sqliteErr sqlite3.Error
+		if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintForeignKey {
+			return fmt.Errorf("%s: %w", op, storage.ErrArticleNotFound)
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// LikeArticle sets the user's reaction to the article to a like, replacing a dislike
+func (s *Storage) LikeArticle(ctx context.Context, userID, articleID int) error {
+	const op = "storage.sqlite.LikeArticle"
+
+	if err := s.react(ctx, userID, articleID, models.ReactionLike); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// DislikeArticle sets the user's reaction to the article to a dislike, replacing a like
+func (s *Storage) DislikeArticle(ctx context.Context, userID, articleID int) error {
+	const op = "storage.sqlite.DislikeArticle"
+
+	if err := s.react(ctx, userID, articleID, models.ReactionDislike); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) react(ctx context.Context, userID, articleID int, reaction string) error {
+	stmt, err := s.db.PrepareContext(ctx, `
+		INSERT INTO reactions (article_id, user_id, reaction_type) VALUES (?, ?, ?)
+		ON CONFLICT (article_id, user_id) DO UPDATE SET reaction_type = excluded.reaction_type`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.ExecContext(ctx, articleID, userID, reaction)
+	if err != nil {
+		var sqliteErr sqlite3.Error
+		if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintForeignKey {
+			return storage.ErrArticleNotFound
+		}
+		return err
+	}
+
+	return nil
+}
+
+// RemoveReaction drops the user's reaction to the article, if any
+func (s *Storage) RemoveReaction(ctx context.Context, userID, articleID int) error {
+	const op = "storage.sqlite.RemoveReaction"
+
+	stmt, err := s.db.PrepareContext(ctx, `DELETE FROM reactions WHERE article_id = ? AND user_id = ?`)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer stmt.Close()
+
+	_, err = stmt.ExecContext(ctx, articleID, userID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) CreateArticle(ctx context.Context, userID int, title, content, language, canonicalURL, status string, publishDate *time.Time) (int64, error) {
+	const op = "storage.sqlite.CreateArticle"
+
+	stmt, err := s.db.PrepareContext(ctx, `INSERT INTO articles (title, content, language, canonical_url, publish_date, created_at, updated_at, status, author_id) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer stmt.Close()
+
+	now := time.Now().UTC()
+	res, err := stmt.ExecContext(ctx, title, content, language, canonicalURL, publishDate, now, now, status, userID)
+	if err != nil {
+		var sqliteErr sqlite3.Error
+		if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+			return 0, fmt.Errorf("%s: %w", op, storage.ErrArticleExists)
+		}
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return id, nil
+}
+
+// UpdateArticle sets the non-empty title, content, language and canonical URL of the article and bumps its version.
+// A non-zero version must match the stored one, otherwise storage.ErrVersionConflict is returned.
+// The returned version is the new one, or the stored one on a conflict
+func (s *Storage) UpdateArticle(ctx context.Context, id int, title, content, language, canonicalURL string, version int) (int, error) {
+	const op = "storage.sqlite.UpdateArticle"
+
+	stmt, err := s.db.PrepareContext(ctx, `
+		UPDATE articles SET
+			title = COALESCE(NULLIF(?, ''), title),
+			content = COALESCE(NULLIF(?, ''), content),
+			language = COALESCE(NULLIF(?, ''), language),
+			canonical_url = COALESCE(NULLIF(?, ''), canonical_url),
+			updated_at = ?,
+			version = version + 1
+		WHERE id = ? AND (? = 0 OR version = ?)
+		RETURNING version`)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer stmt.Close()
+
+	var newVersion int
+	err = stmt.QueryRowContext(ctx, title, content, language, canonicalURL, time.Now().UTC(), id, version, version).Scan(&newVersion)
+	if err == nil {
+		return newVersion, nil
+	}
+
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+		return 0, fmt.Errorf("%s: %w", op, storage.ErrArticleExists)
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// Nothing was updated, either there's no such article or its version moved on
+	var current int
+	err = s.db.QueryRowContext(ctx, `SELECT version FROM articles WHERE id = ?`, id).Scan(&current)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, fmt.Errorf("%s: %w", op, storage.ErrArticleNotFound)
+		}
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return current, fmt.Errorf("%s: %w", op, storage.ErrVersionConflict)
+}
+
+func (s *Storage) UpdateArticlePublishDate(ctx context.Context, id int64, publishDate time.Time) error {
+	const op = "storage.sqlite.UpdateArticlePublishDate"
+
+	stmt, err := s.db.PrepareContext(ctx, `UPDATE articles SET publish_date = ?, updated_at = ? WHERE id = ?`)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer stmt.Close()
+
+	_, err = stmt.ExecContext(ctx, publishDate, time.Now().UTC(), id)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) PinArticle(ctx context.Context, id int) error {
+	const op = "storage.sqlite.PinArticle"
+
+	if err := s.setArticlePinned(ctx, id, true); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) UnpinArticle(ctx context.Context, id int) error {
+	const op = "storage.sqlite.UnpinArticle"
+
+	if err := s.setArticlePinned(ctx, id, false); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) setArticlePinned(ctx context.Context, id int, pinned bool) error {
+	const op = "storage.sqlite.setArticlePinned"
+
+	stmt, err := s.db.PrepareContext(ctx, `UPDATE articles SET is_pinned = ?, updated_at = ? WHERE id = ?`)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer stmt.Close()
+
+	res, err := stmt.ExecContext(ctx, pinned, time.Now().UTC(), id)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := checkAffected(res, storage.ErrArticleNotFound); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) CountPinnedByAuthor(ctx context.Context, authorID int) (int, error) {
+	const op = "storage.sqlite.CountPinnedByAuthor"
+
+	var count int
+	err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM articles WHERE author_id = ? AND is_pinned`, authorID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return count, nil
+}
+
+func (s *Storage) RemoveArticle(ctx context.Context, id int) error {
+	const op = "storage.sqlite.RemoveArticle"
+
+	stmt, err := s.db.PrepareContext(ctx, `DELETE FROM articles WHERE id = ?`)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer stmt.Close()
+
+	res, err := stmt.ExecContext(ctx, id)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := checkAffected(res, storage.ErrArticleNotFound); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// RemoveArticlesBulk removes the articles with the given ids written by authorID, by any author when authorID is 0.
+// Besides the removed ids it returns the ids of articles that were kept because someone else wrote them
+func (s *Storage) RemoveArticlesBulk(ctx context.Context, authorID int, ids []int) (removed, foreign []int, err error) {
+	const op = "storage.sqlite.RemoveArticlesBulk"
+
+	if len(ids) == 0 {
+		return nil, nil, nil
+	}
+
+	args := make([]any, 0, len(ids))
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", ")
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	removed, err = queryIDs(ctx, tx, `DELETE FROM articles WHERE id IN (`+placeholders+`) AND (? = 0 OR author_id = ?) RETURNING id`,
+		append(args, authorID, authorID)...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// Whatever is left of the requested articles belongs to other authors
+	foreign, err = queryIDs(ctx, tx, `SELECT id FROM articles WHERE id IN (`+placeholders+`)`, args...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return removed, foreign, nil
+}
+
+// queryIDs runs a query returning a single integer column
+func queryIDs(ctx context.Context, tx *sql.Tx, query string, args ...any) ([]int, error) {
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+func (s *Storage) AddNotification(ctx context.Context, n models.Notification) error {
+	const op = "storage.sqlite.AddNotification"
+
+	stmt, err := s.db.PrepareContext(ctx, `INSERT INTO notifications (user_id, type, payload, created_at) VALUES (?, ?, ?, ?)`)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer stmt.Close()
+
+	_, err = stmt.ExecContext(ctx, n.UserID, n.Type, string(n.Payload), n.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Notifications returns the user's notifications, newest first
+func (s *Storage) Notifications(ctx context.Context, userID int, unreadOnly bool, limit, offset int) ([]models.Notification, error) {
+	const op = "storage.sqlite.Notifications"
+
+	stmt, err := s.db.PrepareContext(ctx, `
+		SELECT id, user_id, type, payload, read_at, created_at FROM notifications
+		WHERE user_id = ? AND (? = false OR read_at IS NULL)
+		ORDER BY created_at DESC, id DESC
+		LIMIT ? OFFSET ?`)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, userID, unreadOnly, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	notifications := []models.Notification{}
+	for rows.Next() {
+		var n models.Notification
+		var payload string
+		err := rows.Scan(&n.ID, &n.UserID, &n.Type, &payload, &n.ReadAt, &n.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		n.Payload = json.RawMessage(payload)
+		notifications = append(notifications, n)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return notifications, nil
+}
+
+func (s *Storage) UnreadNotificationsCount(ctx context.Context, userID int) (int, error) {
+	const op = "storage.sqlite.UnreadNotificationsCount"
+
+	stmt, err := s.db.PrepareContext(ctx, `SELECT COUNT(*) FROM notifications WHERE user_id = ? AND read_at IS NULL`)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer stmt.Close()
+
+	var count int
+	err = stmt.QueryRowContext(ctx, userID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return count, nil
+}
+
+// MarkAllNotificationsRead marks every unread notification of the user as read
+// and returns how many were marked
+func (s *Storage) MarkAllNotificationsRead(ctx context.Context, userID int, readAt time.Time) (int64, error) {
+	const op = "storage.sqlite.MarkAllNotificationsRead"
+
+	stmt, err := s.db.PrepareContext(ctx, `UPDATE notifications SET read_at = ? WHERE user_id = ? AND read_at IS NULL`)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer stmt.Close()
+
+	res, err := stmt.ExecContext(ctx, readAt, userID)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	marked, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return marked, nil
+}
+
+// MarkNotificationsRead marks the given unread notifications of the user as read
+// and returns how many were marked. Ids of other users' notifications are ignored
+func (s *Storage) MarkNotificationsRead(ctx context.Context, userID int, ids []int64, readAt time.Time) (int64, error) {
+	const op = "storage.sqlite.MarkNotificationsRead"
+
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	args := []any{readAt, userID}
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", ")
+
+	stmt, err := s.db.PrepareContext(ctx, `
+		UPDATE notifications SET read_at = ?
+		WHERE user_id = ? AND read_at IS NULL AND id IN (`+placeholders+`)`)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer stmt.Close()
+
+	res, err := stmt.ExecContext(ctx, args...)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	marked, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return marked, nil
+}
+
+// ### Webhook ### //
+
+func (s *Storage) CreateWebhook(ctx context.Context, w models.Webhook) (int64, error) {
+	const op = "storage.sqlite.CreateWebhook"
+
+	stmt, err := s.db.PrepareContext(ctx, `
+		INSERT INTO webhooks (owner_id, url, secret, events, active, created_at) VALUES (?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer stmt.Close()
+
+	res, err := stmt.ExecContext(ctx, w.OwnerID, w.URL, w.Secret, strings.Join(w.Events, ","), w.Active, w.CreatedAt)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return id, nil
+}
+
+func (s *Storage) WebhookByID(ctx context.Context, id int64) (models.Webhook, error) {
+	const op = "storage.sqlite.WebhookByID"
+
+	stmt, err := s.db.PrepareContext(ctx, `
+		SELECT id, owner_id, url, secret, events, active, created_at FROM webhooks WHERE id = ?`)
+	if err != nil {
+		return models.Webhook{}, fmt.Errorf("%s: %w", op, err)
+	}
+	defer stmt.Close()
+
+	w, err := scanWebhook(stmt.QueryRowContext(ctx, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Webhook{}, fmt.Errorf("%s: %w", op, storage.ErrWebhookNotFound)
+		}
+		return models.Webhook{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return w, nil
+}
+
+// Webhooks returns the owner's webhooks, oldest first
+func (s *Storage) Webhooks(ctx context.Context, ownerID int) ([]models.Webhook, error) {
+	const op = "storage.sqlite.Webhooks"
+
+	webhooks, err := s.queryWebhooks(ctx, `
+		SELECT id, owner_id, url, secret, events, active, created_at FROM webhooks
+		WHERE owner_id = ?
+		ORDER BY id`, ownerID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return webhooks, nil
+}
+
+// ActiveWebhooks returns the active webhooks subscribed to the event
+func (s *Storage) ActiveWebhooks(ctx context.Context, event string) ([]models.Webhook, error) {
+	const op = "storage.sqlite.ActiveWebhooks"
+
+	webhooks, err := s.queryWebhooks(ctx, `
+		SELECT id, owner_id, url, secret, events, active, created_at FROM webhooks
+		WHERE active = true AND ',' || events || ',' LIKE '%,' || ? || ',%'`, event)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return webhooks, nil
+}
+
+func (s *Storage) UpdateWebhook(ctx context.Context, w models.Webhook) error {
+	const op = "storage.sqlite.UpdateWebhook"
+
+	stmt, err := s.db.PrepareContext(ctx, `UPDATE webhooks SET url = ?, secret = ?, events = ?, active = ? WHERE id = ?`)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer stmt.Close()
+
+	res, err := stmt.ExecContext(ctx, w.URL, w.Secret, strings.Join(w.Events, ","), w.Active, w.ID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := checkAffected(res, storage.ErrWebhookNotFound); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) RemoveWebhook(ctx context.Context, id int64) error {
+	const op = "storage.sqlite.RemoveWebhook"
+
+	stmt, err := s.db.PrepareContext(ctx, `DELETE FROM webhooks WHERE id = ?`)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer stmt.Close()
+
+	res, err := stmt.ExecContext(ctx, id)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := checkAffected(res, storage.ErrWebhookNotFound); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) queryWebhooks(ctx context.Context, query string, args ...any) ([]models.Webhook, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	webhooks := []models.Webhook{}
+	for rows.Next() {
+		w, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, w)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return webhooks, nil
+}
+
+func scanWebhook(row scanner) (models.Webhook, error) {
+	var w models.Webhook
+	var events string
+	err := row.Scan(&w.ID, &w.OwnerID, &w.URL, &w.Secret, &events, &w.Active, &w.CreatedAt)
+	if err != nil {
+		return models.Webhook{}, err
+	}
+	w.Events = strings.Split(events, ",")
+
+	return w, nil
+}
+
+// ### Collection ### //
+
+func (s *Storage) CreateCollection(ctx context.Context, c models.Collection) (int64, error) {
+	const op = "storage.sqlite.CreateCollection"
+
+	stmt, err := s.db.PrepareContext(ctx, `
+		INSERT INTO collections (owner_id, title, description, is_public, created_at) VALUES (?, ?, ?, ?, ?)`)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer stmt.Close()
+
+	res, err := stmt.ExecContext(ctx, c.OwnerID, c.Title, c.Description, c.IsPublic, c.CreatedAt)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return id, nil
+}
+
+func (s *Storage) CollectionByID(ctx context.Context, id int64) (models.Collection, error) {
+	const op = "storage.sqlite.CollectionByID"
+
+	stmt, err := s.db.PrepareContext(ctx, `
+		SELECT id, owner_id, title, description, is_public, created_at FROM collections WHERE id = ?`)
+	if err != nil {
+		return models.Collection{}, fmt.Errorf("%s: %w", op, err)
+	}
+	defer stmt.Close()
+
+	c, err := scanCollection(stmt.QueryRowContext(ctx, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Collection{}, fmt.Errorf("%s: %w", op, storage.ErrCollectionNotFound)
+		}
+		return models.Collection{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return c, nil
+}
+
+// Collections returns the owner's collections, oldest first. With publicOnly private ones are left out
+func (s *Storage) Collections(ctx context.Context, ownerID int, publicOnly bool) ([]models.Collection, error) {
+	const op = "storage.sqlite.Collections"
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, owner_id, title, description, is_public, created_at FROM collections
+		WHERE owner_id = ? AND (is_public OR NOT ?)
+		ORDER BY id`, ownerID, publicOnly)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	collections := []models.Collection{}
+	for rows.Next() {
+		c, err := scanCollection(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		collections = append(collections, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return collections, nil
+}
+
+func (s *Storage) UpdateCollection(ctx context.Context, c models.Collection) error {
+	const op = "storage.sqlite.UpdateCollection"
+
+	stmt, err := s.db.PrepareContext(ctx, `UPDATE collections SET title = ?, description = ?, is_public = ? WHERE id = ?`)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer stmt.Close()
+
+	res, err := stmt.ExecContext(ctx, c.Title, c.Description, c.IsPublic, c.ID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := checkAffected(res, storage.ErrCollectionNotFound); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) RemoveCollection(ctx context.Context, id int64) error {
+	const op = "storage.sqlite.RemoveCollection"
+
+	stmt, err := s.db.PrepareContext(ctx, `DELETE FROM collections WHERE id = ?`)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer stmt.Close()
+
+	res, err := stmt.ExecContext(ctx, id)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := checkAffected(res, storage.ErrCollectionNotFound); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// AddCollectionArticle appends the article to the end of the collection
+func (s *Storage) AddCollectionArticle(ctx context.Context, collectionID int64, articleID int, addedAt time.Time) error {
+	const op = "storage.sqlite.AddCollectionArticle"
+
+	stmt, err := s.db.PrepareContext(ctx, `
+		INSERT INTO collection_articles (collection_id, article_id, position, added_at)
+		SELECT ?1, ?2, COALESCE(MAX(position), 0) + 1, ?3 FROM collection_articles WHERE collection_id = ?1`)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer stmt.Close()
+
+	_, err = stmt.ExecContext(ctx, collectionID, articleID, addedAt)
+	if err != nil {
+		var sqliteErr sqlite3.Error
+		if errors.As(err, &sqliteErr) {
+			switch sqliteErr.ExtendedCode {
+			case sqlite3.ErrConstraintPrimaryKey:
+				return fmt.Errorf("%s: %w", op, storage.ErrArticleInCollection)
+			case sqlite3.ErrConstraintForeignKey:
+				return fmt.Errorf("%s: %w", op, storage.ErrArticleNotFound)
+			}
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) RemoveCollectionArticle(ctx context.Context, collectionID int64, articleID int) error {
+	const op = "storage.sqlite.RemoveCollectionArticle"
+
+	stmt, err := s.db.PrepareContext(ctx, `DELETE FROM collection_articles WHERE collection_id = ? AND article_id = ?`)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer stmt.Close()
+
+	res, err := stmt.ExecContext(ctx, collectionID, articleID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := checkAffected(res, storage.ErrArticleNotInCollection); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// CollectionArticles returns the published articles of the collection in their order
+func (s *Storage) CollectionArticles(ctx context.Context, collectionID int64) ([]models.Article, error) {
+	const op = "storage.sqlite.CollectionArticles"
+
+	stmt, err := s.db.PrepareContext(ctx, `
+		SELECT `+articleColumns+` FROM `+articlesFrom+`
+		JOIN collection_articles ON collection_articles.article_id = articles.id
+		WHERE collection_articles.collection_id = ? AND status = ?
+		ORDER BY collection_articles.position`)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, collectionID, models.ArticlePublished)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	arts := []models.Article{}
+	for rows.Next() {
+		art, err := scanArticle(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		arts = append(arts, art)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return arts, nil
+}
+
+func scanCollection(row scanner) (models.Collection, error) {
+	var c models.Collection
+	err := row.Scan(&c.ID, &c.OwnerID, &c.Title, &c.Description, &c.IsPublic, &c.CreatedAt)
+	if err != nil {
+		return models.Collection{}, err
+	}
+
+	return c, nil
+}
+
+// ### Attachment ### //
+
+// AddAttachment records the attachment of a.ArticleID and returns it.
+// Attaching the same content to the article again returns the existing attachment
+func (s *Storage) AddAttachment(ctx context.Context, a models.Attachment) (models.Attachment, error) {
+	const op = "storage.sqlite.AddAttachment"
+
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO attachments (article_id, sha256, mime_type, size, created_at) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (article_id, sha256) DO NOTHING`,
+		a.ArticleID, a.SHA256, a.MimeType, a.Size, a.CreatedAt)
+	if err != nil {
+		var sqliteErr sqlite3.Error
+		if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintForeignKey {
+			return models.Attachment{}, fmt.Errorf("%s: %w", op, storage.ErrArticleNotFound)
+		}
+		return models.Attachment{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	row := s.db.QueryRowContext(ctx, `
+		SELECT id, article_id, sha256, mime_type, size, created_at FROM attachments
+		WHERE article_id = ? AND sha256 = ?`, a.ArticleID, a.SHA256)
+
+	a, err = scanAttachment(row)
+	if err != nil {
+		return models.Attachment{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return a, nil
+}
+
+// AttachmentBySHA256 returns the first attachment with the content
+func (s *Storage) AttachmentBySHA256(ctx context.Context, sum string) (models.Attachment, error) {
+	const op = "storage.sqlite.AttachmentBySHA256"
+
+	row := s.db.QueryRowContext(ctx, `
+		SELECT id, article_id, sha256, mime_type, size, created_at FROM attachments
+		WHERE sha256 = ? ORDER BY id LIMIT 1`, sum)
+
+	a, err := scanAttachment(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Attachment{}, fmt.Errorf("%s: %w", op, storage.ErrAttachmentNotFound)
+		}
+		return models.Attachment{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return a, nil
+}
+
+func scanAttachment(row scanner) (models.Attachment, error) {
+	var a models.Attachment
+	err := row.Scan(&a.ID, &a.ArticleID, &a.SHA256, &a.MimeType, &a.Size, &a.CreatedAt)
+	if err != nil {
+		return models.Attachment{}, err
+	}
+
+	return a, nil
+}
+
+// ### Stats ### //
+
+// ViewsByDay counts the article's views per day (UTC) since the given time, keyed by 2006-01-02.
+// Days without views are left out
+func (s *Storage) ViewsByDay(ctx context.Context, articleID int, since time.Time) (map[string]int, error) {
+	const op = "storage.sqlite.ViewsByDay"
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT date(viewed_at), COUNT(*) FROM article_views
+		WHERE article_id = ? AND viewed_at >= ?
+		GROUP BY date(viewed_at)`, articleID, since)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	views := make(map[string]int)
+	for rows.Next() {
+		var (
+			day   string
+			count int
+		)
+		if err := rows.Scan(&day, &count); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		views[day] = count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return views, nil
+}
+
+// CountLikes counts the article's likes
+func (s *Storage) CountLikes(ctx context.Context, articleID int) (int, error) {
+	const op = "storage.sqlite.CountLikes"
+
+	var likes int
+	err := s.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM reactions
+		WHERE article_id = ? AND reaction_type = 'like'`, articleID).Scan(&likes)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return likes, nil
+}
+
+// FirstLikeAt returns when the earliest of the article's current likes was given, nil without likes
+func (s *Storage) FirstLikeAt(ctx context.Context, articleID int) (*time.Time, error) {
+	const op = "storage.sqlite.FirstLikeAt"
+
+	// Not MIN(), the driver only parses times of columns declared as DATETIME
+	var at time.Time
+	err := s.db.QueryRowContext(ctx, `
+		SELECT created_at FROM reactions
+		WHERE article_id = ? AND reaction_type = 'like'
+		ORDER BY created_at LIMIT 1`, articleID).Scan(&at)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &at, nil
+}
+
+// UserStats counts the user's published articles and the views and likes they got
+func (s *Storage) UserStats(ctx context.Context, id int) (models.UserStats, error) {
+	const op = "storage.sqlite.UserStats"
+
+	var stats models.UserStats
+	err := s.db.QueryRowContext(ctx, `
+		SELECT u.id, u.registration_date,
+			(SELECT COUNT(*) FROM articles a
+				WHERE a.author_id = u.id AND a.status = ?1),
+			(SELECT COUNT(*) FROM reactions r JOIN articles a ON r.article_id = a.id
+				WHERE a.author_id = u.id AND a.status = ?1 AND r.reaction_type = 'like'),
+			(SELECT COUNT(*) FROM article_views v JOIN articles a ON v.article_id = a.id
+				WHERE a.author_id = u.id AND a.status = ?1)
+		FROM users u WHERE u.id = ?2`, models.ArticlePublished, id).
+		Scan(&stats.UserID, &stats.JoinedAt, &stats.Articles, &stats.Likes, &stats.Views)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.UserStats{}, fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+		}
+		return models.UserStats{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return stats, nil
+}
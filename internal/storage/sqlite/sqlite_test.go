@@ -0,0 +1,195 @@
+package sqlite_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"blog-api/internal/domain/models"
+	"blog-api/internal/storage"
+	"blog-api/internal/storage/sqlite"
+	"blog-api/internal/storage/sqlite/sqlitetest"
+)
+
+// newTestStorage opens a fresh in-memory database with every migration applied,
+// it's closed when the test ends
+func newTestStorage(t testing.TB) *sqlite.Storage {
+	t.Helper()
+
+	return sqlitetest.New(t)
+}
+
+// mustRegister registers a user without an email and returns its id
+func mustRegister(t testing.TB, s *sqlite.Storage, name string) int {
+	t.Helper()
+
+	id, err := s.Register(context.Background(), name, "", []byte("hash of "+name), time.Now().UTC())
+	if err != nil {
+		t.Fatalf("failed to register %q: %v", name, err)
+	}
+
+	return int(id)
+}
+
+// mustCreateArticle creates an article of the author and returns its id
+func mustCreateArticle(t testing.TB, s *sqlite.Storage, authorID int, title, status string) int {
+	t.Helper()
+
+	now := time.Now().UTC()
+	id, err := s.CreateArticle(context.Background(), authorID, title, "content of "+title, "en", "", status, &now)
+	if err != nil {
+		t.Fatalf("failed to create article %q: %v", title, err)
+	}
+
+	return int(id)
+}
+
+func TestRegisterLoginRoundTrip(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+
+	hash := []byte("$2a$10$not-a-real-bcrypt-hash")
+	id, err := s.Register(ctx, "Alice", "alice@example.com", hash, time.Now().UTC())
+	if err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		identifier string
+	}{
+		{name: "name", identifier: "Alice"},
+		{name: "name in other case", identifier: "aLICE"},
+		{name: "email", identifier: "alice@example.com"},
+		{name: "email in other case", identifier: "Alice@Example.com"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user, err := s.UserByIdentifier(ctx, tt.identifier)
+			if err != nil {
+				t.Fatalf("UserByIdentifier(%q) error = %v", tt.identifier, err)
+			}
+			if user.ID != id || user.UserName != "Alice" {
+				t.Errorf("UserByIdentifier(%q) = user %d %q, want %d %q", tt.identifier, user.ID, user.UserName, id, "Alice")
+			}
+			if !bytes.Equal(user.PassHash, hash) {
+				t.Errorf("UserByIdentifier(%q) pass hash = %q, want %q", tt.identifier, user.PassHash, hash)
+			}
+			if user.Role != models.RoleUser {
+				t.Errorf("UserByIdentifier(%q) role = %q, want %q", tt.identifier, user.Role, models.RoleUser)
+			}
+		})
+	}
+}
+
+func TestArticleCRUD(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+
+	authorID := mustRegister(t, s, "author")
+	id := mustCreateArticle(t, s, authorID, "First", models.ArticlePublished)
+
+	art, err := s.GetArticleByID(ctx, id, false, 0)
+	if err != nil {
+		t.Fatalf("GetArticleByID() error = %v", err)
+	}
+	if art.Title != "First" || art.Content != "content of First" || art.AuthorID != authorID || art.Status != models.ArticlePublished || art.Version != 1 {
+		t.Errorf("GetArticleByID() = %+v, want the created article", art)
+	}
+
+	version, err := s.UpdateArticle(ctx, id, "Renamed", "", "", "", art.Version)
+	if err != nil {
+		t.Fatalf("UpdateArticle() error = %v", err)
+	}
+	if version != 2 {
+		t.Errorf("UpdateArticle() version = %d, want 2", version)
+	}
+
+	art, err = s.GetArticleByID(ctx, id, false, 0)
+	if err != nil {
+		t.Fatalf("GetArticleByID() after update error = %v", err)
+	}
+	if art.Title != "Renamed" || art.Content != "content of First" {
+		t.Errorf("after update title, content = %q, %q, want %q and the old content", art.Title, art.Content, "Renamed")
+	}
+
+	if err := s.RemoveArticle(ctx, id); err != nil {
+		t.Fatalf("RemoveArticle() error = %v", err)
+	}
+	if _, err := s.GetArticleByID(ctx, id, false, 0); !errors.Is(err, storage.ErrArticleNotFound) {
+		t.Errorf("GetArticleByID() after remove error = %v, want %v", err, storage.ErrArticleNotFound)
+	}
+}
+
+func TestNotFound(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+
+	const missing = 999
+
+	tests := []struct {
+		name string
+		call func() error
+		want error
+	}{
+		{
+			name: "UserByID",
+			call: func() error { _, err := s.UserByID(ctx, missing); return err },
+			want: storage.ErrUserNotFound,
+		},
+		{
+			name: "UserByIdentifier",
+			call: func() error { _, err := s.UserByIdentifier(ctx, "nobody"); return err },
+			want: storage.ErrUserNotFound,
+		},
+		{
+			name: "GetUserByUsername",
+			call: func() error { _, err := s.GetUserByUsername(ctx, "nobody"); return err },
+			want: storage.ErrUserNotFound,
+		},
+		{
+			name: "UpdateUserName",
+			call: func() error { return s.UpdateUserName(ctx, missing, "name") },
+			want: storage.ErrUserNotFound,
+		},
+		{
+			name: "UpdateStatus",
+			call: func() error { return s.UpdateStatus(ctx, missing, "status") },
+			want: storage.ErrUserNotFound,
+		},
+		{
+			name: "RemoveUser",
+			call: func() error { return s.RemoveUser(ctx, missing) },
+			want: storage.ErrUserNotFound,
+		},
+		{
+			name: "GetArticleByID",
+			call: func() error { _, err := s.GetArticleByID(ctx, missing, false, 0); return err },
+			want: storage.ErrArticleNotFound,
+		},
+		{
+			name: "UpdateArticle",
+			call: func() error { _, err := s.UpdateArticle(ctx, missing, "title", "", "", "", 0); return err },
+			want: storage.ErrArticleNotFound,
+		},
+		{
+			name: "PinArticle",
+			call: func() error { return s.PinArticle(ctx, missing) },
+			want: storage.ErrArticleNotFound,
+		},
+		{
+			name: "RemoveArticle",
+			call: func() error { return s.RemoveArticle(ctx, missing) },
+			want: storage.ErrArticleNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.call(); !errors.Is(err, tt.want) {
+				t.Errorf("error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
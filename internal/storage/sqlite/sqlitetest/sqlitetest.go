@@ -0,0 +1,26 @@
+// Package sqlitetest opens storages for the tests of packages built on top of sqlite
+package sqlitetest
+
+import (
+	"testing"
+
+	"blog-api/internal/storage/sqlite"
+)
+
+// New opens a fresh in-memory database with every migration applied,
+// it's closed when the test ends
+func New(t testing.TB) *sqlite.Storage {
+	t.Helper()
+
+	s, err := sqlite.New(":memory:")
+	if err != nil {
+		t.Fatalf("failed to open storage: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := s.Close(); err != nil {
+			t.Errorf("failed to close storage: %v", err)
+		}
+	})
+
+	return s
+}
@@ -0,0 +1,64 @@
+package sqlite_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+	_ "time/tzdata"
+
+	"blog-api/internal/domain/models"
+)
+
+// setLocal makes loc the local time zone until the test ends, like TZ does for a process
+func setLocal(t *testing.T, loc *time.Location) {
+	t.Helper()
+
+	old := time.Local
+	time.Local = loc
+	t.Cleanup(func() { time.Local = old })
+}
+
+func TestWrittenInTokyoReadInUTC(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Fatalf("failed to load Asia/Tokyo: %v", err)
+	}
+
+	s := newTestStorage(t)
+	ctx := context.Background()
+
+	setLocal(t, tokyo)
+	published := time.Now().UTC().Truncate(time.Second)
+	authorID := mustRegister(t, s, "author")
+	id, err := s.CreateArticle(ctx, authorID, "Written in Tokyo", "content", "en", "", models.ArticlePublished, &published)
+	if err != nil {
+		t.Fatalf("CreateArticle() error = %v", err)
+	}
+
+	setLocal(t, time.UTC)
+	art, err := s.GetArticleByID(ctx, int(id), false, 0)
+	if err != nil {
+		t.Fatalf("GetArticleByID() error = %v", err)
+	}
+	if art.PublishDate == nil || !art.PublishDate.Equal(published) || art.PublishDate.Location() != time.UTC {
+		t.Errorf("publish date = %v, want %v", art.PublishDate, published)
+	}
+
+	var stored string
+	if err := s.DB().QueryRow(`SELECT CAST(publish_date AS TEXT) FROM articles WHERE id = ?`, id).Scan(&stored); err != nil {
+		t.Fatalf("failed to read the stored publish date: %v", err)
+	}
+	if !strings.HasSuffix(stored, "+00:00") {
+		t.Errorf("stored publish date = %q, want it in UTC", stored)
+	}
+
+	// Dates compare as text, so the range must find the article by its UTC time
+	arts, err := s.GetArticlesInRange(ctx, published.Add(-time.Minute), published.Add(time.Minute), "", "", false, 0, 10, 0)
+	if err != nil {
+		t.Fatalf("GetArticlesInRange() error = %v", err)
+	}
+	if len(arts) != 1 || arts[0].ID != int(id) {
+		t.Errorf("GetArticlesInRange() = %d articles, want the one written in Tokyo", len(arts))
+	}
+}
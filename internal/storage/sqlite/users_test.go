@@ -0,0 +1,376 @@
+package sqlite_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"blog-api/internal/domain/models"
+	"blog-api/internal/storage"
+	"blog-api/internal/storage/sqlite"
+
+	"golang.org/x/sync/errgroup"
+)
+
+func TestRegister(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		username string
+		wantErr  error
+	}{
+		{name: "success", username: "alice"},
+		{name: "duplicate", username: "alice", wantErr: storage.ErrUserExists},
+		{name: "another user", username: "bob"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, err := s.Register(ctx, tt.username, "", []byte("hash"), time.Now().UTC())
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Register(%q) error = %v, want %v", tt.username, err, tt.wantErr)
+			}
+			if tt.wantErr == nil && id <= 0 {
+				t.Errorf("Register(%q) id = %d, want a positive one", tt.username, id)
+			}
+		})
+	}
+}
+
+func TestUserByName(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+
+	id := mustRegister(t, s, "alice")
+
+	tests := []struct {
+		name     string
+		username string
+		wantErr  error
+	}{
+		{name: "found", username: "alice"},
+		{name: "found in other case", username: "Alice"},
+		{name: "not found", username: "bob", wantErr: storage.ErrUserNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user, err := s.GetUserByUsername(ctx, tt.username)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("GetUserByUsername(%q) error = %v, want %v", tt.username, err, tt.wantErr)
+			}
+			if tt.wantErr == nil && (int(user.ID) != id || user.UserName != "alice") {
+				t.Errorf("GetUserByUsername(%q) = user %d %q, want %d %q", tt.username, user.ID, user.UserName, id, "alice")
+			}
+		})
+	}
+}
+
+func TestUserByID(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+
+	id := mustRegister(t, s, "alice")
+
+	tests := []struct {
+		name    string
+		id      int
+		wantErr error
+	}{
+		{name: "found", id: id},
+		{name: "not found", id: id + 1, wantErr: storage.ErrUserNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user, err := s.UserByID(ctx, tt.id)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("UserByID(%d) error = %v, want %v", tt.id, err, tt.wantErr)
+			}
+			if tt.wantErr == nil && (int(user.ID) != id || user.UserName != "alice" || user.RegistrationDate == nil) {
+				t.Errorf("UserByID(%d) = %+v, want the registered user", tt.id, user)
+			}
+		})
+	}
+}
+
+func TestUpdateUserName(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+
+	id := mustRegister(t, s, "alice")
+	mustRegister(t, s, "bob")
+
+	tests := []struct {
+		name     string
+		id       int
+		username string
+		wantErr  error
+	}{
+		{name: "success", id: id, username: "alicia"},
+		{name: "same name in other case", id: id, username: "Alicia"},
+		{name: "taken", id: id, username: "bob", wantErr: storage.ErrUserNameTaken},
+		{name: "not found", id: 999, username: "carol", wantErr: storage.ErrUserNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := s.UpdateUserName(ctx, tt.id, tt.username)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("UpdateUserName(%d, %q) error = %v, want %v", tt.id, tt.username, err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+
+			user, err := s.UserByID(ctx, tt.id)
+			if err != nil {
+				t.Fatalf("UserByID() error = %v", err)
+			}
+			if user.UserName != tt.username {
+				t.Errorf("name = %q, want %q", user.UserName, tt.username)
+			}
+		})
+	}
+}
+
+func TestUpdateStatus(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+
+	id := mustRegister(t, s, "alice")
+
+	tests := []struct {
+		name    string
+		id      int
+		status  string
+		wantErr error
+	}{
+		{name: "set", id: id, status: "writing"},
+		{name: "clear", id: id, status: ""},
+		{name: "not found", id: 999, status: "writing", wantErr: storage.ErrUserNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := s.UpdateStatus(ctx, tt.id, tt.status)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("UpdateStatus(%d, %q) error = %v, want %v", tt.id, tt.status, err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+
+			user, err := s.UserByID(ctx, tt.id)
+			if err != nil {
+				t.Fatalf("UserByID() error = %v", err)
+			}
+			if user.Status != tt.status {
+				t.Errorf("status = %q, want %q", user.Status, tt.status)
+			}
+		})
+	}
+}
+
+func TestRemoveUser(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+
+	id := mustRegister(t, s, "alice")
+
+	tests := []struct {
+		name    string
+		id      int
+		wantErr error
+	}{
+		{name: "success", id: id},
+		{name: "already removed", id: id, wantErr: storage.ErrUserNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := s.RemoveUser(ctx, tt.id); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("RemoveUser(%d) error = %v, want %v", tt.id, err, tt.wantErr)
+			}
+			if _, err := s.UserByID(ctx, tt.id); !errors.Is(err, storage.ErrUserNotFound) {
+				t.Errorf("UserByID() after remove error = %v, want %v", err, storage.ErrUserNotFound)
+			}
+		})
+	}
+}
+
+func TestRegisterNameIgnoresCase(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+
+	if _, err := s.Register(ctx, "Bob", "", []byte("hash"), time.Now().UTC()); err != nil {
+		t.Fatalf("Register(%q) error = %v", "Bob", err)
+	}
+	if _, err := s.Register(ctx, "bob", "", []byte("hash"), time.Now().UTC()); !errors.Is(err, storage.ErrUserExists) {
+		t.Errorf("Register(%q) error = %v, want %v", "bob", err, storage.ErrUserExists)
+	}
+}
+
+func TestRemoveUserRemovesArticles(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+
+	authorID := mustRegister(t, s, "author")
+	otherID := mustRegister(t, s, "other")
+	ids := []int{
+		mustCreateArticle(t, s, authorID, "First", models.ArticlePublished),
+		mustCreateArticle(t, s, authorID, "Draft", models.ArticleDraft),
+	}
+	keptID := mustCreateArticle(t, s, otherID, "Kept", models.ArticlePublished)
+	if err := s.LikeArticle(ctx, otherID, ids[0]); err != nil {
+		t.Fatalf("LikeArticle() error = %v", err)
+	}
+
+	if err := s.RemoveUser(ctx, authorID); err != nil {
+		t.Fatalf("RemoveUser() error = %v", err)
+	}
+
+	for _, id := range ids {
+		if _, err := s.GetArticleByID(ctx, id, false, 0); !errors.Is(err, storage.ErrArticleNotFound) {
+			t.Errorf("GetArticleByID(%d) error = %v, want %v", id, err, storage.ErrArticleNotFound)
+		}
+	}
+	if n := countRows(t, s, "reactions", "article_id = ?", ids[0]); n != 0 {
+		t.Errorf("reactions to the removed article = %d, want 0", n)
+	}
+	if _, err := s.GetArticleByID(ctx, keptID, false, 0); err != nil {
+		t.Errorf("GetArticleByID() of another author's article error = %v", err)
+	}
+}
+
+func TestGetAllUsers(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+
+	// Registered out of name order, users come back by registration date
+	start := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	names := []string{"carol", "alice", "bob"}
+	for i, name := range names {
+		if _, err := s.Register(ctx, name, "", []byte("hash"), start.Add(time.Duration(i)*time.Hour)); err != nil {
+			t.Fatalf("Register(%q) error = %v", name, err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		limit  int
+		offset int
+		want   []string
+	}{
+		{name: "all", limit: 10, want: []string{"carol", "alice", "bob"}},
+		{name: "first page", limit: 2, want: []string{"carol", "alice"}},
+		{name: "second page", limit: 2, offset: 2, want: []string{"bob"}},
+		{name: "past the end", limit: 2, offset: 4, want: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users, err := s.GetAllUsers(ctx, tt.limit, tt.offset)
+			if err != nil {
+				t.Fatalf("GetAllUsers() error = %v", err)
+			}
+
+			got := make([]string, 0, len(users))
+			for _, user := range users {
+				got = append(got, user.UserName)
+				if user.PassHash != nil {
+					t.Errorf("user %q has a pass hash", user.UserName)
+				}
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("GetAllUsers(%d, %d) = %v, want %v", tt.limit, tt.offset, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUserByIdentifierEmailMatchesEmailOnly(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+
+	aliceID, err := s.Register(ctx, "alice", "alice@example.com", []byte("hash"), time.Now().UTC())
+	if err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	// Registered before names with "@" were rejected
+	mustRegister(t, s, "Alice@Example.com")
+
+	tests := []struct {
+		name       string
+		identifier string
+		wantID     int64
+	}{
+		{name: "email", identifier: "alice@example.com", wantID: aliceID},
+		{name: "email in other case", identifier: "ALICE@example.com", wantID: aliceID},
+		{name: "name", identifier: "alice", wantID: aliceID},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user, err := s.UserByIdentifier(ctx, tt.identifier)
+			if err != nil {
+				t.Fatalf("UserByIdentifier(%q) error = %v", tt.identifier, err)
+			}
+			if user.ID != tt.wantID {
+				t.Errorf("UserByIdentifier(%q) = user %d %q, want %d", tt.identifier, user.ID, user.UserName, tt.wantID)
+			}
+		})
+	}
+
+	if _, err := s.UserByIdentifier(ctx, "bob@example.com"); !errors.Is(err, storage.ErrUserNotFound) {
+		t.Errorf("UserByIdentifier() of an unknown email error = %v, want %v", err, storage.ErrUserNotFound)
+	}
+}
+
+// BenchmarkUserByIDStats compares the queries behind the service's UserByID run one after
+// the other and side by side. It uses a file, since an in-memory database keeps one connection
+func BenchmarkUserByIDStats(b *testing.B) {
+	s, err := sqlite.New(filepath.Join(b.TempDir(), "storage.db"))
+	if err != nil {
+		b.Fatalf("failed to open storage: %v", err)
+	}
+	b.Cleanup(func() { s.Close() })
+
+	ctx := context.Background()
+
+	authorID := mustRegister(b, s, "author")
+	readerID := mustRegister(b, s, "reader")
+	for i := 0; i < 1000; i++ {
+		id := mustCreateArticle(b, s, authorID, fmt.Sprintf("Article %d", i), models.ArticlePublished)
+		if err := s.LikeArticle(ctx, readerID, id); err != nil {
+			b.Fatalf("LikeArticle() error = %v", err)
+		}
+	}
+
+	b.Run("sequential", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := s.UserByID(ctx, authorID); err != nil {
+				b.Fatalf("UserByID() error = %v", err)
+			}
+			if _, err := s.GetTotalLikesForAuthor(ctx, authorID); err != nil {
+				b.Fatalf("GetTotalLikesForAuthor() error = %v", err)
+			}
+		}
+	})
+
+	b.Run("concurrent", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			g, gctx := errgroup.WithContext(ctx)
+			g.Go(func() error {
+				_, err := s.UserByID(gctx, authorID)
+				return err
+			})
+			g.Go(func() error {
+				_, err := s.GetTotalLikesForAuthor(gctx, authorID)
+				return err
+			})
+
+			if err := g.Wait(); err != nil {
+				b.Fatalf("UserByID stats error = %v", err)
+			}
+		}
+	})
+}
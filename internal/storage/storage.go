@@ -8,7 +8,25 @@ var (
 
 	ErrArticleExists   = errors.New("article already exists")
 	ErrArticleNotFound = errors.New("article not found")
+	ErrVersionConflict = errors.New("article version conflict")
 
 	ErrUserNameTaken = errors.New("user name already taken")
+	ErrEmailTaken    = errors.New("email already taken")
 	ErrTitleTaken    = errors.New("article title already taken")
+
+	ErrSessionNotFound = errors.New("session not found")
+
+	ErrWebhookNotFound = errors.New("webhook not found")
+
+	ErrCollectionNotFound     = errors.New("collection not found")
+	ErrArticleInCollection    = errors.New("article already in collection")
+	ErrArticleNotInCollection = errors.New("article not in collection")
+
+	ErrResetTokenNotFound = errors.New("password reset token not found")
+	ErrResetTokenExpired  = errors.New("password reset token expired")
+	ErrResetTokenUsed     = errors.New("password reset token already used")
+
+	ErrAttachmentNotFound = errors.New("attachment not found")
+
+	ErrCorrupted = errors.New("database is corrupted")
 )
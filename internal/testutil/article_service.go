@@ -0,0 +1,40 @@
+package testutil
+
+import (
+	"context"
+
+	"blog-api/internal/domain/models"
+	"blog-api/internal/http-server/handlers/article"
+)
+
+// ArticleService is the service of the article handlers.
+// Methods without a func field panic on the nil embedded interface
+type ArticleService struct {
+	article.Service
+
+	GetByIDFunc func(ctx context.Context, id int, visibleOnly bool, viewerID int) (*models.Article, error)
+	ViewFunc    func(ctx context.Context, id int, fingerprint string) error
+	CreateFunc  func(ctx context.Context, art *models.Article) (int64, error)
+	UpdateFunc  func(ctx context.Context, art *models.Article, requesterID int, role string) (int, error)
+	RemoveFunc  func(ctx context.Context, id, requesterID int, role string) error
+}
+
+func (m *ArticleService) GetByID(ctx context.Context, id int, visibleOnly bool, viewerID int) (*models.Article, error) {
+	return m.GetByIDFunc(ctx, id, visibleOnly, viewerID)
+}
+
+func (m *ArticleService) View(ctx context.Context, id int, fingerprint string) error {
+	return m.ViewFunc(ctx, id, fingerprint)
+}
+
+func (m *ArticleService) Create(ctx context.Context, art *models.Article) (int64, error) {
+	return m.CreateFunc(ctx, art)
+}
+
+func (m *ArticleService) Update(ctx context.Context, art *models.Article, requesterID int, role string) (int, error) {
+	return m.UpdateFunc(ctx, art, requesterID, role)
+}
+
+func (m *ArticleService) Remove(ctx context.Context, id, requesterID int, role string) error {
+	return m.RemoveFunc(ctx, id, requesterID, role)
+}
@@ -0,0 +1,41 @@
+package testutil
+
+import (
+	"context"
+	"time"
+
+	"blog-api/internal/domain/models"
+	articleservice "blog-api/internal/service/article"
+)
+
+// ArticleStorage is the storage of the article service.
+// Methods without a func field panic on the nil embedded interface
+type ArticleStorage struct {
+	articleservice.Storage
+
+	GetArticleByIDFunc     func(ctx context.Context, id int, visibleOnly bool, viewerID int) (*models.Article, error)
+	CreateArticleFunc      func(ctx context.Context, authorID int, title, content, language, canonicalURL, status string, publishDate *time.Time) (int64, error)
+	UpdateArticleFunc      func(ctx context.Context, id int, title, content, language, canonicalURL string, version int) (int, error)
+	RemoveArticleFunc      func(ctx context.Context, id int) error
+	RemoveArticlesBulkFunc func(ctx context.Context, authorID int, ids []int) (removed, foreign []int, err error)
+}
+
+func (m *ArticleStorage) GetArticleByID(ctx context.Context, id int, visibleOnly bool, viewerID int) (*models.Article, error) {
+	return m.GetArticleByIDFunc(ctx, id, visibleOnly, viewerID)
+}
+
+func (m *ArticleStorage) CreateArticle(ctx context.Context, authorID int, title, content, language, canonicalURL, status string, publishDate *time.Time) (int64, error) {
+	return m.CreateArticleFunc(ctx, authorID, title, content, language, canonicalURL, status, publishDate)
+}
+
+func (m *ArticleStorage) UpdateArticle(ctx context.Context, id int, title, content, language, canonicalURL string, version int) (int, error) {
+	return m.UpdateArticleFunc(ctx, id, title, content, language, canonicalURL, version)
+}
+
+func (m *ArticleStorage) RemoveArticle(ctx context.Context, id int) error {
+	return m.RemoveArticleFunc(ctx, id)
+}
+
+func (m *ArticleStorage) RemoveArticlesBulk(ctx context.Context, authorID int, ids []int) (removed, foreign []int, err error) {
+	return m.RemoveArticlesBulkFunc(ctx, authorID, ids)
+}
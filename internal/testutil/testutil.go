@@ -0,0 +1,3 @@
+// Package testutil has handwritten test doubles of the interfaces services and handlers depend on.
+// Each method calls the func field named after it, calling a method whose field isn't set panics
+package testutil
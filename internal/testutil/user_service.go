@@ -0,0 +1,55 @@
+package testutil
+
+import (
+	"context"
+
+	"blog-api/internal/domain/models"
+	"blog-api/internal/http-server/handlers/user"
+)
+
+// UserService is the service of the user handlers.
+// Methods without a func field panic on the nil embedded interface
+type UserService struct {
+	user.Service
+
+	GetAllFunc         func(ctx context.Context, limit, offset int) ([]models.User, error)
+	RegisterFunc       func(ctx context.Context, userName, email, password string) (int64, error)
+	LoginFunc          func(ctx context.Context, identifier, password string, rememberMe bool, ip, userAgent string) (string, error)
+	UserByIDFunc       func(ctx context.Context, id int) (models.User, error)
+	UpdateUserNameFunc func(ctx context.Context, id int, userName string) error
+	UpdateEmailFunc    func(ctx context.Context, id int, email string) error
+	UpdateStatusFunc   func(ctx context.Context, id int, status string) error
+	RemoveFunc         func(ctx context.Context, id int) error
+}
+
+func (m *UserService) GetAll(ctx context.Context, limit, offset int) ([]models.User, error) {
+	return m.GetAllFunc(ctx, limit, offset)
+}
+
+func (m *UserService) Register(ctx context.Context, userName, email, password string) (int64, error) {
+	return m.RegisterFunc(ctx, userName, email, password)
+}
+
+func (m *UserService) Login(ctx context.Context, identifier, password string, rememberMe bool, ip, userAgent string) (string, error) {
+	return m.LoginFunc(ctx, identifier, password, rememberMe, ip, userAgent)
+}
+
+func (m *UserService) UserByID(ctx context.Context, id int) (models.User, error) {
+	return m.UserByIDFunc(ctx, id)
+}
+
+func (m *UserService) UpdateUserName(ctx context.Context, id int, userName string) error {
+	return m.UpdateUserNameFunc(ctx, id, userName)
+}
+
+func (m *UserService) UpdateEmail(ctx context.Context, id int, email string) error {
+	return m.UpdateEmailFunc(ctx, id, email)
+}
+
+func (m *UserService) UpdateStatus(ctx context.Context, id int, status string) error {
+	return m.UpdateStatusFunc(ctx, id, status)
+}
+
+func (m *UserService) Remove(ctx context.Context, id int) error {
+	return m.RemoveFunc(ctx, id)
+}
@@ -0,0 +1,35 @@
+package testutil
+
+import (
+	"context"
+
+	"blog-api/internal/domain/models"
+	userservice "blog-api/internal/service/user"
+)
+
+// UserStorage is the storage of the user service.
+// Methods without a func field panic on the nil embedded interface
+type UserStorage struct {
+	userservice.Storage
+
+	UserByIdentifierFunc       func(ctx context.Context, identifier string) (models.User, error)
+	UpdateUserNameFunc         func(ctx context.Context, id int, userName string) error
+	UserByIDFunc               func(ctx context.Context, id int) (models.User, error)
+	GetTotalLikesForAuthorFunc func(ctx context.Context, authorID int) (int, error)
+}
+
+func (m *UserStorage) UserByIdentifier(ctx context.Context, identifier string) (models.User, error) {
+	return m.UserByIdentifierFunc(ctx, identifier)
+}
+
+func (m *UserStorage) UpdateUserName(ctx context.Context, id int, userName string) error {
+	return m.UpdateUserNameFunc(ctx, id, userName)
+}
+
+func (m *UserStorage) UserByID(ctx context.Context, id int) (models.User, error) {
+	return m.UserByIDFunc(ctx, id)
+}
+
+func (m *UserStorage) GetTotalLikesForAuthor(ctx context.Context, authorID int) (int, error) {
+	return m.GetTotalLikesForAuthorFunc(ctx, authorID)
+}
@@ -0,0 +1,143 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"blog-api/internal/lib/logger/sl"
+)
+
+// Task is a job run every Interval until the scheduler is stopped
+type Task struct {
+	Name     string
+	Interval time.Duration
+	Run      func(ctx context.Context) error
+}
+
+// Clock tells the time and makes the tickers tasks are scheduled with,
+// tests replace it to run iterations without waiting
+type Clock interface {
+	Now() time.Time
+	// NewTicker returns the channel ticks are sent on every d and the func stopping them
+	NewTicker(d time.Duration) (<-chan time.Time, func())
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) NewTicker(d time.Duration) (<-chan time.Time, func()) {
+	t := time.NewTicker(d)
+	return t.C, t.Stop
+}
+
+type Scheduler struct {
+	log   *slog.Logger
+	clock Clock
+	tasks []Task
+	// stop is closed by Stop, iterations keep the context they were started with
+	stop     chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+}
+
+func New(log *slog.Logger) *Scheduler {
+	return NewWithClock(log, systemClock{})
+}
+
+// NewWithClock is New with the time taken from clock
+func NewWithClock(log *slog.Logger, clock Clock) *Scheduler {
+	return &Scheduler{
+		log:   log,
+		clock: clock,
+		stop:  make(chan struct{}),
+	}
+}
+
+// Add registers a task, it must be called before Start
+func (s *Scheduler) Add(task Task) {
+	s.tasks = append(s.tasks, task)
+}
+
+// Start runs every registered task in its own goroutine, until Stop or until ctx is done
+func (s *Scheduler) Start(ctx context.Context) {
+	for _, task := range s.tasks {
+		s.wg.Add(1)
+		go s.loop(ctx, task)
+	}
+}
+
+// Stop stops scheduling new iterations and waits for running ones to finish
+// or for ctx to expire, whichever comes first. Running iterations aren't cancelled
+func (s *Scheduler) Stop(ctx context.Context) error {
+	const op = "worker.Stop"
+
+	s.stopOnce.Do(func() { close(s.stop) })
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("%s: %w", op, ctx.Err())
+	}
+}
+
+func (s *Scheduler) loop(ctx context.Context, task Task) {
+	defer s.wg.Done()
+
+	ticks, stop := s.clock.NewTicker(task.Interval)
+	defer stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.stop:
+			return
+		case <-ticks:
+			// select picks randomly when several are ready, don't start a new iteration after Stop
+			select {
+			case <-s.stop:
+				return
+			default:
+			}
+			if ctx.Err() != nil {
+				return
+			}
+			s.run(ctx, task)
+		}
+	}
+}
+
+// run executes one iteration, a panic is logged and doesn't stop the other tasks
+func (s *Scheduler) run(ctx context.Context, task Task) {
+	const op = "worker.run"
+
+	log := s.log.With(slog.String("op", op), slog.String("task", task.Name))
+
+	start := s.clock.Now()
+
+	defer func() {
+		if rec := recover(); rec != nil {
+			log.Error("task panicked", slog.Any("panic", rec), slog.Duration("duration", s.clock.Now().Sub(start)))
+		}
+	}()
+
+	if err := task.Run(ctx); err != nil {
+		log.Error("task failed", sl.Error(err), slog.Duration("duration", s.clock.Now().Sub(start)))
+		return
+	}
+
+	log.Debug("task finished", slog.Duration("duration", s.clock.Now().Sub(start)))
+}
@@ -0,0 +1,234 @@
+package worker_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"blog-api/internal/worker"
+)
+
+// fakeClock hands out tickers that only tick when the test says so
+type fakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	tickers chan *fakeTicker
+}
+
+type fakeTicker struct {
+	interval time.Duration
+	c        chan time.Time
+	stopped  chan struct{}
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{
+		now:     time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		tickers: make(chan *fakeTicker, 8),
+	}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+func (c *fakeClock) NewTicker(d time.Duration) (<-chan time.Time, func()) {
+	t := &fakeTicker{
+		interval: d,
+		c:        make(chan time.Time),
+		stopped:  make(chan struct{}),
+	}
+	c.tickers <- t
+
+	return t.c, func() { close(t.stopped) }
+}
+
+// ticker waits for the scheduler to create the next ticker
+func (c *fakeClock) ticker(t *testing.T) *fakeTicker {
+	t.Helper()
+
+	select {
+	case tk := <-c.tickers:
+		return tk
+	case <-time.After(time.Second):
+		t.Fatal("no ticker was created")
+		return nil
+	}
+}
+
+// tick moves the time one interval forward and delivers the tick, it returns once
+// the scheduler received it
+func (c *fakeClock) tick(t *testing.T, tk *fakeTicker) {
+	t.Helper()
+
+	c.mu.Lock()
+	c.now = c.now.Add(tk.interval)
+	now := c.now
+	c.mu.Unlock()
+
+	select {
+	case tk.c <- now:
+	case <-time.After(time.Second):
+		t.Fatal("the tick wasn't received")
+	}
+}
+
+func newTestScheduler(clock worker.Clock) *worker.Scheduler {
+	return worker.NewWithClock(slog.New(slog.NewTextHandler(io.Discard, nil)), clock)
+}
+
+// wait fails the test unless ch receives within a second
+func wait(t *testing.T, ch <-chan struct{}, what string) {
+	t.Helper()
+
+	select {
+	case <-ch:
+	case <-time.After(time.Second):
+		t.Fatalf("timed out waiting for %s", what)
+	}
+}
+
+func TestSchedulerRunsOnEveryTick(t *testing.T) {
+	clock := newFakeClock()
+	s := newTestScheduler(clock)
+
+	ran := make(chan struct{}, 1)
+	s.Add(worker.Task{
+		Name:     "count",
+		Interval: time.Hour,
+		Run: func(ctx context.Context) error {
+			ran <- struct{}{}
+			return nil
+		},
+	})
+	s.Start(context.Background())
+
+	tk := clock.ticker(t)
+	if tk.interval != time.Hour {
+		t.Errorf("ticker interval = %s, want %s", tk.interval, time.Hour)
+	}
+
+	select {
+	case <-ran:
+		t.Fatal("task ran before the first tick")
+	default:
+	}
+
+	for i := 0; i < 3; i++ {
+		clock.tick(t, tk)
+		wait(t, ran, "the task to run")
+	}
+
+	if err := s.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+	wait(t, tk.stopped, "the ticker to be stopped")
+}
+
+func TestSchedulerSurvivesPanic(t *testing.T) {
+	clock := newFakeClock()
+	s := newTestScheduler(clock)
+
+	ran := make(chan struct{}, 1)
+	calls := 0
+	s.Add(worker.Task{
+		Name:     "panics once",
+		Interval: time.Minute,
+		Run: func(ctx context.Context) error {
+			calls++
+			if calls == 1 {
+				defer func() { ran <- struct{}{} }()
+				panic("boom")
+			}
+			ran <- struct{}{}
+			return nil
+		},
+	})
+	s.Start(context.Background())
+	defer s.Stop(context.Background())
+
+	tk := clock.ticker(t)
+	clock.tick(t, tk)
+	wait(t, ran, "the panicking iteration")
+	clock.tick(t, tk)
+	wait(t, ran, "the iteration after the panic")
+}
+
+func TestStopLetsRunningIterationFinish(t *testing.T) {
+	clock := newFakeClock()
+	s := newTestScheduler(clock)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	finished := make(chan error, 1)
+	s.Add(worker.Task{
+		Name:     "slow",
+		Interval: time.Minute,
+		Run: func(ctx context.Context) error {
+			close(started)
+			<-release
+			finished <- ctx.Err()
+			return nil
+		},
+	})
+	s.Start(context.Background())
+
+	tk := clock.ticker(t)
+	clock.tick(t, tk)
+	wait(t, started, "the iteration to start")
+
+	stopped := make(chan error, 1)
+	go func() { stopped <- s.Stop(context.Background()) }()
+
+	select {
+	case err := <-stopped:
+		t.Fatalf("Stop() returned %v before the iteration finished", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(release)
+	if err := <-finished; err != nil {
+		t.Errorf("iteration context error = %v, want it not cancelled", err)
+	}
+	if err := <-stopped; err != nil {
+		t.Errorf("Stop() error = %v", err)
+	}
+	// No iteration is scheduled after Stop
+	wait(t, tk.stopped, "the ticker to be stopped")
+}
+
+func TestStopDeadline(t *testing.T) {
+	clock := newFakeClock()
+	s := newTestScheduler(clock)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	s.Add(worker.Task{
+		Name:     "stuck",
+		Interval: time.Minute,
+		Run: func(ctx context.Context) error {
+			close(started)
+			<-release
+			return nil
+		},
+	})
+	s.Start(context.Background())
+
+	tk := clock.ticker(t)
+	clock.tick(t, tk)
+	wait(t, started, "the iteration to start")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := s.Stop(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Stop() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+}